@@ -0,0 +1,30 @@
+// Command vpauthorizer-cedarschema prints a schema file (YAML, JSON or .cedarschema) in the Cedar
+// natural schema syntax, e.g. to review schema changes as readable diffs:
+//
+//	go run ./cmd/vpauthorizer-cedarschema ./infra/authorizer/schema.yaml > schema.cedarschema
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s <schema file>\n", os.Args[0])
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	out, err := sharedavp.FormatSchemaFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(out)
+}
@@ -39,3 +39,6 @@ output "policy_store_id" {
 output "lambda_authorizer_arn" {
   value = vpauthorizer_authorizer.main.lambda_authorizer_arn
 }
+output "merged_schema_hash" {
+  value = vpauthorizer_authorizer.main.merged_schema_hash
+}
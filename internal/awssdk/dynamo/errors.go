@@ -7,3 +7,39 @@ package dynamo
 // Project logging standard: emit logs as message + structured context
 // (logging.Fields). Avoid printf-style formatting and prefer JSON-friendly
 // key/value context so logs compose cleanly across call stacks.
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by repository reads, updates and deletes when the target item does not exist.
+var ErrNotFound = errors.New("dynamo: item not found")
+
+// Domain errors matched (via errors.Is) by the UniqueConflictError returned when a unique value is taken.
+var (
+	ErrEmailTaken             = errors.New("dynamo: email is already in use")
+	ErrPhoneTaken             = errors.New("dynamo: phone is already in use")
+	ErrPreferredUsernameTaken = errors.New("dynamo: preferred username is already in use")
+	ErrRoleNameTaken          = errors.New("dynamo: role name is already in use in this scope")
+)
+
+// UniqueConflictError reports that a value that must be unique (a user's email, phone or preferred
+// username, or a role name within its scope) already belongs to another item. It matches the
+// corresponding Err*Taken via errors.Is and wraps the underlying conflict.
+type UniqueConflictError struct {
+	// Attribute is the colliding attribute name, e.g. "email".
+	Attribute string
+	Value     string
+	Cause     error
+	domain    error
+}
+
+func (e *UniqueConflictError) Error() string {
+	return fmt.Sprintf("dynamo: %s %q is already in use", e.Attribute, e.Value)
+}
+
+func (e *UniqueConflictError) Unwrap() error { return e.Cause }
+
+// Is matches the domain error for the colliding attribute.
+func (e *UniqueConflictError) Is(target error) bool { return target == e.domain }
@@ -0,0 +1,73 @@
+package dynamo
+
+import "context"
+
+// Policy is the metadata tracked for a Verified Permissions static policy.
+type Policy struct {
+	// PolicyId is assigned by Verified Permissions.
+	PolicyId string
+	Name     string
+}
+
+// Item renders the ADR-0002 Policy item: GLOBAL/name primary key, policy-id GSI1 keys, Type and attributes.
+func (p Policy) Item() Item {
+	item := mergeItems(PolicyPrimaryKey(p.Name), PolicyIdGSIKeys(p.PolicyId))
+	item["Type"] = StringAttribute(TypePolicy)
+	item["policyId"] = StringAttribute(p.PolicyId)
+	item["name"] = StringAttribute(p.Name)
+	return item
+}
+
+// PolicyFromItem decodes a Policy item.
+func PolicyFromItem(item Item) (Policy, error) {
+	if err := checkType(item, TypePolicy); err != nil {
+		return Policy{}, err
+	}
+	return Policy{PolicyId: stringValue(item, "policyId"), Name: stringValue(item, "name")}, nil
+}
+
+// CreatePolicy stores metadata for a new policy; names are unique.
+func (r *Repository) CreatePolicy(ctx context.Context, p Policy) (Policy, error) {
+	if err := requireFields(TypePolicy, "policyId", p.PolicyId, "name", p.Name); err != nil {
+		return Policy{}, err
+	}
+	if err := r.table.putItem(ctx, p.Item(), condNotExists, nil, false); err != nil {
+		return Policy{}, err
+	}
+	return p, nil
+}
+
+// GetPolicy reads policy metadata by name.
+func (r *Repository) GetPolicy(ctx context.Context, name string) (Policy, error) {
+	item, err := r.table.getItem(ctx, PolicyPrimaryKey(name), TypePolicy)
+	if err != nil {
+		return Policy{}, err
+	}
+	return PolicyFromItem(item)
+}
+
+// UpdatePolicy replaces existing policy metadata, e.g. after the policy was recreated with a new id.
+func (r *Repository) UpdatePolicy(ctx context.Context, p Policy) error {
+	if err := requireFields(TypePolicy, "policyId", p.PolicyId, "name", p.Name); err != nil {
+		return err
+	}
+	return r.table.putItem(ctx, p.Item(), condExists, nil, true)
+}
+
+// DeletePolicy removes policy metadata by name.
+func (r *Repository) DeletePolicy(ctx context.Context, name string) error {
+	return r.table.deleteItem(ctx, PolicyPrimaryKey(name), TypePolicy)
+}
+
+// ListPolicies returns policy metadata whose name starts with prefix (all policies for ""), ordered by name.
+func (r *Repository) ListPolicies(ctx context.Context, prefix string) ([]Policy, error) {
+	policies := []Policy{}
+	err := r.EachPolicyByPrefix(ctx, prefix, QueryOptions{}, func(p Policy) bool {
+		policies = append(policies, p)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
@@ -0,0 +1,157 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// QueryOptions tune the ADR-0002 access-pattern queries below.
+type QueryOptions struct {
+	// ConsistentRead requests a strongly consistent read. Only base-table queries (policies by name
+	// prefix) support it; GSI queries reject it.
+	ConsistentRead bool
+	// PageSize caps the items evaluated per Query call; zero leaves paging to DynamoDB (1 MB pages).
+	PageSize int32
+}
+
+// Attributes projected by the access-pattern queries: exactly what the entity decoders read, so key
+// and GSI attributes are not transferred.
+var (
+	tenantAttributes      = []string{"Type", "tenantId", "name"}
+	roleAttributes        = []string{"Type", "roleId", "name", "scope"}
+	tenantGrantAttributes = []string{"Type", "tenantGrantId", "tenantId", "userId", "roles"}
+	policyAttributes      = []string{"Type", "policyId", "name"}
+)
+
+// apply sets the read consistency, page size and projection of attributes on in.
+func (o QueryOptions) apply(in *dynamodb.QueryInput, attributes []string) error {
+	if o.ConsistentRead && in.IndexName != nil {
+		return fmt.Errorf("dynamo: consistent reads are not supported on index %s", *in.IndexName)
+	}
+	if o.PageSize < 0 {
+		return fmt.Errorf("dynamo: page size must not be negative (got %d)", o.PageSize)
+	}
+	if o.ConsistentRead {
+		in.ConsistentRead = aws.Bool(true)
+	}
+	if o.PageSize > 0 {
+		in.Limit = aws.Int32(o.PageSize)
+	}
+	if in.ExpressionAttributeNames == nil {
+		in.ExpressionAttributeNames = map[string]string{}
+	}
+	projection := ""
+	for i, a := range attributes {
+		// Placeholders sidestep reserved words such as name and Type.
+		placeholder := fmt.Sprintf("#p%d", i)
+		in.ExpressionAttributeNames[placeholder] = a
+		if i > 0 {
+			projection += ", "
+		}
+		projection += placeholder
+	}
+	in.ProjectionExpression = aws.String(projection)
+	return nil
+}
+
+// queryEach runs in with opts and passes each decoded item to fn until fn returns false, the last
+// page has been read or an item fails to decode.
+func queryEach[T any](ctx context.Context, t *Table, in *dynamodb.QueryInput, opts QueryOptions, attributes []string, decode func(Item) (T, error), fn func(T) bool) error {
+	if err := opts.apply(in, attributes); err != nil {
+		return err
+	}
+	var decodeErr error
+	err := t.queryPages(ctx, in, func(page []Item) bool {
+		for _, item := range page {
+			v, err := decode(item)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			if !fn(v) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// queryOne returns the first item matched by in, or ErrNotFound when there is none. It is used for
+// exact-match GSI lookups, where the key holds at most one item.
+func queryOne[T any](ctx context.Context, t *Table, in *dynamodb.QueryInput, opts QueryOptions, attributes []string, decode func(Item) (T, error)) (T, error) {
+	var (
+		out   T
+		found bool
+	)
+	err := queryEach(ctx, t, in, opts, attributes, decode, func(v T) bool {
+		out, found = v, true
+		return false
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if !found {
+		return out, ErrNotFound
+	}
+	return out, nil
+}
+
+// gsiQuery returns a query for items whose <index>PK equals pk.
+func gsiQuery(index string, pk string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		IndexName:                 aws.String(index),
+		KeyConditionExpression:    aws.String(index + "PK = :pk"),
+		ExpressionAttributeValues: Item{":pk": StringAttribute(pk)},
+	}
+}
+
+// EachTenantGrantByUser passes every grant held by userId to fn (GSI1 reverse lookup), page by page,
+// until fn returns false.
+func (r *Repository) EachTenantGrantByUser(ctx context.Context, userId string, opts QueryOptions, fn func(TenantGrant) bool) error {
+	return queryEach(ctx, r.table, gsiQuery("GSI1", TenantGrantGSI1PK(userId)), opts, tenantGrantAttributes, TenantGrantFromItem, fn)
+}
+
+// FindTenantGrantById resolves a grant by its id (GSI2).
+func (r *Repository) FindTenantGrantById(ctx context.Context, tenantGrantId string, opts QueryOptions) (TenantGrant, error) {
+	pk, _ := TenantGrantIdGSI(tenantGrantId)
+	return queryOne(ctx, r.table, gsiQuery("GSI2", pk), opts, tenantGrantAttributes, TenantGrantFromItem)
+}
+
+// FindRoleById resolves a role definition by its id (GSI1).
+func (r *Repository) FindRoleById(ctx context.Context, roleId string, opts QueryOptions) (Role, error) {
+	pk, _ := RoleIdGSI(roleId)
+	return queryOne(ctx, r.table, gsiQuery("GSI1", pk), opts, roleAttributes, RoleFromItem)
+}
+
+// FindTenantByName resolves a tenant by name (GSI1).
+func (r *Repository) FindTenantByName(ctx context.Context, name string, opts QueryOptions) (Tenant, error) {
+	pk, _ := TenantNameGSI(name)
+	return queryOne(ctx, r.table, gsiQuery("GSI1", pk), opts, tenantAttributes, TenantFromItem)
+}
+
+// FindPolicyById resolves policy metadata by Verified Permissions policy id (GSI1).
+func (r *Repository) FindPolicyById(ctx context.Context, policyId string, opts QueryOptions) (Policy, error) {
+	pk, _ := PolicyIdGSI(policyId)
+	return queryOne(ctx, r.table, gsiQuery("GSI1", pk), opts, policyAttributes, PolicyFromItem)
+}
+
+// EachPolicyByPrefix passes policy metadata whose name starts with prefix (all policies for "") to fn
+// in name order, page by page, until fn returns false.
+func (r *Repository) EachPolicyByPrefix(ctx context.Context, prefix string, opts QueryOptions, fn func(Policy) bool) error {
+	in := &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: Item{
+			":pk": StringAttribute(PolicyPK()),
+			":sk": StringAttribute(PolicyNameSK(prefix)),
+		},
+	}
+	return queryEach(ctx, r.table, in, opts, policyAttributes, PolicyFromItem, fn)
+}
@@ -0,0 +1,122 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/internal/testutil"
+)
+
+func TestEachTenantGrantByUserStopsEarly(t *testing.T) {
+	g1 := TenantGrant{TenantGrantId: "G1", TenantId: "T1", UserId: "U1"}
+	g2 := TenantGrant{TenantGrantId: "G2", TenantId: "T2", UserId: "U1"}
+	c := &recordingClient{pages: []*dynamodb.QueryOutput{
+		{Items: []Item{g1.Item()}, LastEvaluatedKey: TenantGrantPrimaryKey("T1", "U1")},
+		{Items: []Item{g2.Item()}},
+	}}
+	var got []TenantGrant
+	err := newTestRepository(c).EachTenantGrantByUser(context.Background(), "U1", QueryOptions{PageSize: 1}, func(g TenantGrant) bool {
+		got = append(got, g)
+		return false
+	})
+	if err != nil || len(got) != 1 || got[0].TenantGrantId != "G1" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if len(c.queries) != 1 || *c.queries[0].Limit != 1 || *c.queries[0].KeyConditionExpression != "GSI1PK = :pk" {
+		t.Fatalf("unexpected queries: %+v", c.queries)
+	}
+}
+
+func TestQueryOptionsConsistentRead(t *testing.T) {
+	r := newTestRepository(&recordingClient{})
+	if _, err := r.FindRoleById(context.Background(), "R1", QueryOptions{ConsistentRead: true}); err == nil {
+		t.Fatalf("expected consistent read on a GSI to be rejected")
+	}
+	p := Policy{PolicyId: "p-1", Name: "tenant-enforce"}
+	c := &recordingClient{pages: []*dynamodb.QueryOutput{{Items: []Item{p.Item()}}}}
+	var got []Policy
+	err := newTestRepository(c).EachPolicyByPrefix(context.Background(), "tenant-", QueryOptions{ConsistentRead: true}, func(p Policy) bool {
+		got = append(got, p)
+		return true
+	})
+	if err != nil || len(got) != 1 || got[0] != p {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	q := c.queries[0]
+	if q.ConsistentRead == nil || !*q.ConsistentRead || q.IndexName != nil {
+		t.Fatalf("expected a consistent base-table query: %+v", q)
+	}
+	if *q.ProjectionExpression != "#p0, #p1, #p2" || q.ExpressionAttributeNames["#p2"] != "name" {
+		t.Fatalf("unexpected projection: %s %v", *q.ProjectionExpression, q.ExpressionAttributeNames)
+	}
+}
+
+func TestFindByIdQueries(t *testing.T) {
+	g := TenantGrant{TenantGrantId: "G1", TenantId: "T1", UserId: "U1", Roles: []string{"R1"}}
+	c := &recordingClient{pages: []*dynamodb.QueryOutput{{Items: []Item{g.Item()}}, {}}}
+	r := newTestRepository(c)
+	got, err := r.FindTenantGrantById(context.Background(), "G1", QueryOptions{})
+	if err != nil || got.UserId != "U1" || len(got.Roles) != 1 {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if q := c.queries[0]; *q.IndexName != "GSI2" || stringValue(q.ExpressionAttributeValues, ":pk") != "TENANT_GRANT#G1" {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+	if _, err := r.FindTenantByName(context.Background(), "acme", QueryOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if q := c.queries[1]; *q.IndexName != "GSI1" || stringValue(q.ExpressionAttributeValues, ":pk") != "TENANT_NAME#acme" {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+}
+
+func TestQueryDecodeError(t *testing.T) {
+	role := Role{RoleId: "R1", Name: "admin", Scope: RoleScopeTenant}
+	c := &recordingClient{pages: []*dynamodb.QueryOutput{{Items: []Item{role.Item()}}}}
+	if _, err := newTestRepository(c).FindPolicyById(context.Background(), "R1", QueryOptions{}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a type mismatch error, got %v", err)
+	}
+}
+
+func TestQueriesAgainstFakeTable(t *testing.T) {
+	ctx := context.Background()
+	f := testutil.NewFakeDynamoDB("auth")
+	r := newTestRepository(f)
+	f.Seed(
+		Tenant{TenantId: "T1", Name: "acme"}.Item(),
+		Role{RoleId: "R1", Name: "admin", Scope: RoleScopeTenant}.Item(),
+		TenantGrant{TenantGrantId: "G1", TenantId: "T1", UserId: "U1", Roles: []string{"R1"}}.Item(),
+		TenantGrant{TenantGrantId: "G2", TenantId: "T2", UserId: "U1"}.Item(),
+		TenantGrant{TenantGrantId: "G3", TenantId: "T1", UserId: "U2"}.Item(),
+		Policy{PolicyId: "p-1", Name: "tenant-enforce"}.Item(),
+		Policy{PolicyId: "p-2", Name: "tenant-read"}.Item(),
+		Policy{PolicyId: "p-3", Name: "global-deny"}.Item(),
+	)
+	grants, err := r.ListTenantGrantsByUser(ctx, "U1")
+	if err != nil || len(grants) != 2 || grants[0].TenantGrantId != "G1" || grants[0].Roles[0] != "R1" {
+		t.Fatalf("got %+v, %v", grants, err)
+	}
+	var names []string
+	err = r.EachPolicyByPrefix(ctx, "tenant-", QueryOptions{ConsistentRead: true, PageSize: 1}, func(p Policy) bool {
+		names = append(names, p.Name)
+		return true
+	})
+	if err != nil || len(names) != 2 || names[1] != "tenant-read" {
+		t.Fatalf("got %v, %v", names, err)
+	}
+	if g, err := r.FindTenantGrantById(ctx, "G3", QueryOptions{}); err != nil || g.UserId != "U2" {
+		t.Fatalf("got %+v, %v", g, err)
+	}
+	if role, err := r.FindRoleById(ctx, "R1", QueryOptions{}); err != nil || role.Name != "admin" {
+		t.Fatalf("got %+v, %v", role, err)
+	}
+	if tenant, err := r.FindTenantByName(ctx, "acme", QueryOptions{}); err != nil || tenant.TenantId != "T1" {
+		t.Fatalf("got %+v, %v", tenant, err)
+	}
+	if _, err := r.FindPolicyById(ctx, "p-9", QueryOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item type names written to the Type attribute (ADR-0002).
+const (
+	TypeTenant      = "Tenant"
+	TypeUser        = "User"
+	TypeRole        = "Role"
+	TypeTenantGrant = "TenantGrant"
+	TypePolicy      = "Policy"
+
+	TypeUserEmail             = "UserEmail"
+	TypeUserPhone             = "UserPhone"
+	TypeUserPreferredUsername = "UserPreferredUsername"
+)
+
+const (
+	condNotExists = "attribute_not_exists(PK) AND attribute_not_exists(SK)"
+	condExists    = "attribute_exists(PK) AND attribute_exists(SK)"
+)
+
+// Repository reads and writes the ADR-0002 entities in the auth table. Writes render the exact item
+// layouts (keys, GSI keys and Type) so callers never assemble items by hand.
+type Repository struct {
+	table *Table
+	// newID generates entity ids; NewULID unless overridden in tests.
+	newID func() string
+}
+
+// NewRepository returns a repository over table.
+func NewRepository(table *Table) *Repository {
+	return &Repository{table: table, newID: NewULID}
+}
+
+// transact writes tx atomically. When an operation whose key is listed in unique (see keyString) fails
+// its condition, that UniqueConflictError is returned wrapping the TransactionCanceledError.
+func (r *Repository) transact(ctx context.Context, tx Tx, unique map[string]*UniqueConflictError) error {
+	err := r.table.WriteTx(ctx, tx)
+	var canceled *TransactionCanceledError
+	if errors.As(err, &canceled) {
+		for _, c := range canceled.ConditionFailures() {
+			if u, ok := unique[keyString(c.Key)]; ok {
+				u.Cause = err
+				return u
+			}
+		}
+	}
+	return err
+}
+
+// keyString identifies an item by its PK and SK.
+func keyString(key Item) string {
+	return stringValue(key, "PK") + "|" + stringValue(key, "SK")
+}
+
+// decodeAll decodes items with decode, stopping at the first error.
+func decodeAll[T any](items []Item, decode func(Item) (T, error)) ([]T, error) {
+	out := make([]T, 0, len(items))
+	for _, it := range items {
+		v, err := decode(it)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func checkType(item Item, want string) error {
+	if got := stringValue(item, "Type"); got != want {
+		return fmt.Errorf("dynamo: expected item of type %s, got %q", want, got)
+	}
+	return nil
+}
+
+// requireFields returns an error naming the first empty field; fields alternate name, value.
+func requireFields(typ string, fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			return fmt.Errorf("dynamo: %s.%s is required", typ, fields[i])
+		}
+	}
+	return nil
+}
+
+// mergeItems returns a new item holding the attributes of all parts; later parts win.
+func mergeItems(parts ...Item) Item {
+	out := Item{}
+	for _, p := range parts {
+		for k, v := range p {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// setString sets name on item unless value is empty; optional attributes are omitted rather than empty.
+func setString(item Item, name string, value string) {
+	if value != "" {
+		item[name] = StringAttribute(value)
+	}
+}
+
+// StringListAttribute renders a list of strings as an L AttributeValue (an empty list for nil).
+func StringListAttribute(values []string) types.AttributeValue {
+	l := make([]types.AttributeValue, 0, len(values))
+	for _, v := range values {
+		l = append(l, StringAttribute(v))
+	}
+	return &types.AttributeValueMemberL{Value: l}
+}
+
+func stringValue(item Item, name string) string {
+	if s, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func stringListValue(item Item, name string) []string {
+	l, ok := item[name].(*types.AttributeValueMemberL)
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(l.Value))
+	for _, v := range l.Value {
+		if s, ok := v.(*types.AttributeValueMemberS); ok {
+			out = append(out, s.Value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+)
+
+// recordingClient records the last request of each kind and replays canned responses.
+type recordingClient struct {
+	put      *dynamodb.PutItemInput
+	get      *dynamodb.GetItemInput
+	del      *dynamodb.DeleteItemInput
+	queries  []dynamodb.QueryInput
+	tx       *dynamodb.TransactWriteItemsInput
+	getItem  Item
+	pages    []*dynamodb.QueryOutput
+	writeErr error
+}
+
+func (c *recordingClient) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	c.get = in
+	return &dynamodb.GetItemOutput{Item: c.getItem}, nil
+}
+
+func (c *recordingClient) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.put = in
+	return &dynamodb.PutItemOutput{}, c.writeErr
+}
+
+func (c *recordingClient) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	c.del = in
+	return &dynamodb.DeleteItemOutput{}, c.writeErr
+}
+
+func (c *recordingClient) Query(_ context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.queries = append(c.queries, *in)
+	out := c.pages[0]
+	c.pages = c.pages[1:]
+	return out, nil
+}
+
+func (c *recordingClient) Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return &dynamodb.ScanOutput{}, nil
+}
+
+func (c *recordingClient) TransactWriteItems(_ context.Context, in *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	c.tx = in
+	return &dynamodb.TransactWriteItemsOutput{}, c.writeErr
+}
+
+func newTestRepository(c Client) *Repository {
+	table := NewTable(c, "auth", nil)
+	table.Retry = awssdk.RetryPolicy{}
+	r := NewRepository(table)
+	r.newID = func() string { return "01J8Z0E2Z8D2A3J7A7Y2H9GQ9C" }
+	return r
+}
+
+// attributes flattens an item into name -> string (S) or []string (L of S) for comparisons.
+func attributes(item Item) map[string]any {
+	out := map[string]any{}
+	for k := range item {
+		if _, ok := item[k].(*types.AttributeValueMemberL); ok {
+			out[k] = stringListValue(item, k)
+		} else {
+			out[k] = stringValue(item, k)
+		}
+	}
+	return out
+}
+
+func TestEntityItemLayouts(t *testing.T) {
+	tenant := attributes(Tenant{TenantId: "T1", Name: "acme"}.Item())
+	wantTenant := map[string]any{"PK": "TENANT#T1", "SK": "TENANT#T1", "GSI1PK": "TENANT_NAME#acme", "GSI1SK": "TENANT_NAME#acme", "Type": "Tenant", "tenantId": "T1", "name": "acme"}
+	if !reflect.DeepEqual(tenant, wantTenant) {
+		t.Fatalf("tenant item: %v", tenant)
+	}
+	grant := attributes(TenantGrant{TenantGrantId: "G1", TenantId: "T1", UserId: "U1", Roles: []string{"R1", "R2"}}.Item())
+	wantGrant := map[string]any{
+		"PK": "TENANT#T1", "SK": "USER#U1", "GSI1PK": "USER#U1", "GSI1SK": "TENANT#T1",
+		"GSI2PK": "TENANT_GRANT#G1", "GSI2SK": "TENANT_GRANT#G1", "Type": "TenantGrant",
+		"tenantGrantId": "G1", "tenantId": "T1", "userId": "U1", "roles": []string{"R1", "R2"},
+	}
+	if !reflect.DeepEqual(grant, wantGrant) {
+		t.Fatalf("tenant grant item: %v", grant)
+	}
+	role := attributes(Role{RoleId: "R1", Name: "admin", Scope: "tenant"}.Item())
+	if role["PK"] != "ROLE_SCOPE#tenant" || role["SK"] != "ROLE_NAME#admin" || role["GSI1PK"] != "ROLE#R1" || role["Type"] != "Role" {
+		t.Fatalf("role item: %v", role)
+	}
+	policy := attributes(Policy{PolicyId: "p-1", Name: "n"}.Item())
+	if policy["PK"] != "GLOBAL" || policy["SK"] != "POLICY_NAME#n" || policy["GSI1SK"] != "POLICY#p-1" || policy["Type"] != "Policy" {
+		t.Fatalf("policy item: %v", policy)
+	}
+	user := attributes(User{UserId: "U1", Email: "a@example.com"}.Item())
+	if _, ok := user["phone"]; ok || user["email"] != "a@example.com" || user["Type"] != "User" {
+		t.Fatalf("user item: %v", user)
+	}
+}
+
+func TestCreateTenantAssignsULIDAndCondition(t *testing.T) {
+	c := &recordingClient{}
+	got, err := newTestRepository(c).CreateTenant(context.Background(), Tenant{Name: "acme"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got.TenantId != "01J8Z0E2Z8D2A3J7A7Y2H9GQ9C" || *c.put.TableName != "auth" || *c.put.ConditionExpression != condNotExists {
+		t.Fatalf("unexpected create: %+v %+v", got, c.put)
+	}
+	c.writeErr = &types.ConditionalCheckFailedException{}
+	var conflict *awserrors.ConflictError
+	if _, err := newTestRepository(c).CreateTenant(context.Background(), Tenant{Name: "acme"}); !errors.As(err, &conflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
+
+func TestUpdateAndDeleteReportNotFound(t *testing.T) {
+	c := &recordingClient{writeErr: &types.ConditionalCheckFailedException{}}
+	r := newTestRepository(c)
+	if err := r.UpdateUser(context.Background(), User{UserId: "U1"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("update: expected ErrNotFound, got %v", err)
+	}
+	if err := r.DeletePolicy(context.Background(), "n"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete: expected ErrNotFound, got %v", err)
+	}
+	if _, err := r.GetUser(context.Background(), "U1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetUserDecodes(t *testing.T) {
+	want := User{UserId: "U1", Email: "a@example.com", GivenName: "Ada", Roles: []string{"R1"}}
+	c := &recordingClient{getItem: want.Item()}
+	got, err := newTestRepository(c).GetUser(context.Background(), "U1")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if !*c.get.ConsistentRead {
+		t.Fatalf("expected consistent read")
+	}
+}
+
+func TestListTenantGrantsByUserPaginates(t *testing.T) {
+	g1 := TenantGrant{TenantGrantId: "G1", TenantId: "T1", UserId: "U1"}
+	g2 := TenantGrant{TenantGrantId: "G2", TenantId: "T2", UserId: "U1"}
+	c := &recordingClient{pages: []*dynamodb.QueryOutput{
+		{Items: []Item{g1.Item()}, LastEvaluatedKey: TenantGrantPrimaryKey("T1", "U1")},
+		{Items: []Item{g2.Item()}},
+	}}
+	got, err := newTestRepository(c).ListTenantGrantsByUser(context.Background(), "U1")
+	if err != nil || len(got) != 2 || got[1].TenantGrantId != "G2" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if len(c.queries) != 2 || *c.queries[0].IndexName != "GSI1" || c.queries[1].ExclusiveStartKey == nil {
+		t.Fatalf("unexpected queries: %+v", c.queries)
+	}
+}
+
+func TestUpdateRoleRenamesAtomically(t *testing.T) {
+	taken := &recordingClient{getItem: Role{RoleId: "R1", Name: "admin", Scope: "tenant"}.Item(), writeErr: canceled(ReasonConditionalCheckFailed, "None")}
+	if _, err := newTestRepository(taken).UpdateRole(context.Background(), "tenant", "admin", "owner"); !errors.Is(err, ErrRoleNameTaken) {
+		t.Fatalf("expected ErrRoleNameTaken, got %v", err)
+	}
+	c := &recordingClient{getItem: Role{RoleId: "R1", Name: "admin", Scope: "tenant"}.Item()}
+	got, err := newTestRepository(c).UpdateRole(context.Background(), "tenant", "admin", "owner")
+	if err != nil || got.Name != "owner" || got.RoleId != "R1" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	items := c.tx.TransactItems
+	if len(items) != 2 || items[0].Put == nil || items[1].Delete == nil {
+		t.Fatalf("unexpected transaction: %+v", items)
+	}
+	if stringValue(items[0].Put.Item, "SK") != "ROLE_NAME#owner" {
+		t.Fatalf("unexpected renamed item: %v", attributes(items[0].Put.Item))
+	}
+}
+
+func TestCreateRoleValidatesScope(t *testing.T) {
+	if _, err := newTestRepository(&recordingClient{}).CreateRole(context.Background(), Role{Name: "admin", Scope: "org"}); err == nil {
+		t.Fatalf("expected scope error")
+	}
+	c := &recordingClient{writeErr: &types.ConditionalCheckFailedException{}}
+	if _, err := newTestRepository(c).CreateRole(context.Background(), Role{Name: "admin", Scope: "tenant"}); !errors.Is(err, ErrRoleNameTaken) {
+		t.Fatalf("expected ErrRoleNameTaken, got %v", err)
+	}
+}
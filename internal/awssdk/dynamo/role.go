@@ -0,0 +1,130 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+)
+
+// Role scopes.
+const (
+	RoleScopeTenant = "tenant"
+	RoleScopeGlobal = "global"
+)
+
+// Role is a role definition; names are unique per scope.
+type Role struct {
+	RoleId string
+	Name   string
+	Scope  string
+}
+
+// Item renders the ADR-0002 Role item: scope/name primary key, role-id GSI1 keys, Type and attributes.
+func (r Role) Item() Item {
+	item := mergeItems(RolePrimaryKey(r.Scope, r.Name), RoleIdGSIKeys(r.RoleId))
+	item["Type"] = StringAttribute(TypeRole)
+	item["roleId"] = StringAttribute(r.RoleId)
+	item["name"] = StringAttribute(r.Name)
+	item["scope"] = StringAttribute(r.Scope)
+	return item
+}
+
+// RoleFromItem decodes a Role item.
+func RoleFromItem(item Item) (Role, error) {
+	if err := checkType(item, TypeRole); err != nil {
+		return Role{}, err
+	}
+	return Role{RoleId: stringValue(item, "roleId"), Name: stringValue(item, "name"), Scope: stringValue(item, "scope")}, nil
+}
+
+func roleNameConflict(name string, cause error) *UniqueConflictError {
+	return &UniqueConflictError{Attribute: "name", Value: name, Cause: cause, domain: ErrRoleNameTaken}
+}
+
+func validateRole(role Role) error {
+	if err := requireFields(TypeRole, "name", role.Name); err != nil {
+		return err
+	}
+	if role.Scope != RoleScopeTenant && role.Scope != RoleScopeGlobal {
+		return fmt.Errorf("dynamo: Role.scope must be %q or %q (got %q)", RoleScopeTenant, RoleScopeGlobal, role.Scope)
+	}
+	return nil
+}
+
+// CreateRole stores a new role, assigning a ULID when RoleId is empty. The name must be unique in its
+// scope; a taken name yields ErrRoleNameTaken.
+func (r *Repository) CreateRole(ctx context.Context, role Role) (Role, error) {
+	if role.RoleId == "" {
+		role.RoleId = r.newID()
+	}
+	if err := validateRole(role); err != nil {
+		return Role{}, err
+	}
+	if err := r.table.putItem(ctx, role.Item(), condNotExists, nil, false); err != nil {
+		var conflict *awserrors.ConflictError
+		if errors.As(err, &conflict) {
+			return Role{}, roleNameConflict(role.Name, err)
+		}
+		return Role{}, err
+	}
+	return role, nil
+}
+
+// GetRole reads a role by scope and name.
+func (r *Repository) GetRole(ctx context.Context, scope string, name string) (Role, error) {
+	item, err := r.table.getItem(ctx, RolePrimaryKey(scope, name), TypeRole)
+	if err != nil {
+		return Role{}, err
+	}
+	return RoleFromItem(item)
+}
+
+// UpdateRole renames a role within its scope. The name is part of the primary key, so the old item is
+// deleted and the new one created in a single transaction that fails with ErrRoleNameTaken if newName
+// is already taken.
+func (r *Repository) UpdateRole(ctx context.Context, scope string, name string, newName string) (Role, error) {
+	role, err := r.GetRole(ctx, scope, name)
+	if err != nil || newName == name {
+		return role, err
+	}
+	renamed := role
+	renamed.Name = newName
+	if err := validateRole(renamed); err != nil {
+		return Role{}, err
+	}
+	tx := Tx{
+		Puts: []TxPut{{Item: renamed.Item()}},
+		Deletes: []TxDelete{{
+			Key:                       RolePrimaryKey(scope, name),
+			ConditionExpression:       "roleId = :roleId",
+			ExpressionAttributeValues: Item{":roleId": StringAttribute(role.RoleId)},
+		}},
+	}
+	unique := map[string]*UniqueConflictError{keyString(RolePrimaryKey(scope, newName)): roleNameConflict(newName, nil)}
+	if err := r.transact(ctx, tx, unique); err != nil {
+		return Role{}, err
+	}
+	return renamed, nil
+}
+
+// DeleteRole removes a role by scope and name.
+func (r *Repository) DeleteRole(ctx context.Context, scope string, name string) error {
+	return r.table.deleteItem(ctx, RolePrimaryKey(scope, name), TypeRole)
+}
+
+// ListRoles returns every role in scope, ordered by name.
+func (r *Repository) ListRoles(ctx context.Context, scope string) ([]Role, error) {
+	items, err := r.table.queryAll(ctx, &dynamodb.QueryInput{
+		KeyConditionExpression:    aws.String("PK = :pk"),
+		ExpressionAttributeValues: Item{":pk": StringAttribute(RoleScopePK(scope))},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll(items, RoleFromItem)
+}
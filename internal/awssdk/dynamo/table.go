@@ -0,0 +1,180 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/utils/logging"
+)
+
+// Client is the subset of the DynamoDB API used by Table; *dynamodb.Client satisfies it.
+type Client interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// Table is a handle on the auth table: its name, the client used to reach it and the logger. All dynamo
+// operations (transactions, repository reads and writes) go through a Table, so the table name is
+// supplied once and tests can swap in a fake client.
+type Table struct {
+	Name string
+	// Retry governs retries of transactions that fail with a retryable error.
+	Retry  awssdk.RetryPolicy
+	client Client
+	logger logging.Logger
+}
+
+// NewTable returns a handle on the named table using awssdk.DefaultRetryPolicy. A nil logger discards logs.
+func NewTable(client Client, name string, logger logging.Logger) *Table {
+	if logger == nil {
+		logger = logging.NopLogger{}
+	}
+	return &Table{Name: name, Retry: awssdk.DefaultRetryPolicy, client: client, logger: logger}
+}
+
+// NewTableFromArn returns a handle on the table identified by tableArn, e.g. the provider's
+// dynamo.authTableArn output.
+func NewTableFromArn(client Client, tableArn string, logger logging.Logger) (*Table, error) {
+	name, err := TableNameFromArn(tableArn)
+	if err != nil {
+		return nil, err
+	}
+	return NewTable(client, name, logger), nil
+}
+
+// TableNameFromArn extracts the table name from a DynamoDB table ARN
+// (arn:<partition>:dynamodb:<region>:<account>:table/<name>).
+func TableNameFromArn(tableArn string) (string, error) {
+	a, err := arn.Parse(tableArn)
+	if err != nil || a.Service != "dynamodb" || !strings.HasPrefix(a.Resource, "table/") {
+		return "", fmt.Errorf("dynamo: %q is not a DynamoDB table ARN", tableArn)
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(a.Resource, "table/"), "/")
+	if name == "" {
+		return "", fmt.Errorf("dynamo: %q is not a DynamoDB table ARN", tableArn)
+	}
+	return name, nil
+}
+
+func (t *Table) name() *string { return aws.String(t.Name) }
+
+// putItem writes item under condition. With notFound set, a failed condition is reported as ErrNotFound.
+func (t *Table) putItem(ctx context.Context, item Item, condition string, values Item, notFound bool) error {
+	_, err := t.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 t.name(),
+		Item:                      item,
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		return t.writeError(err, notFound)
+	}
+	t.logger.Debug("dynamo.put", logging.Fields{"type": stringValue(item, "Type")})
+	return nil
+}
+
+// getItem reads an item with a strongly consistent read, returning ErrNotFound when it is missing.
+func (t *Table) getItem(ctx context.Context, key Item, typ string) (Item, error) {
+	out, err := t.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      t.name(),
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, awserrors.Classify(err)
+	}
+	if len(out.Item) == 0 {
+		return nil, ErrNotFound
+	}
+	if err := checkType(out.Item, typ); err != nil {
+		return nil, err
+	}
+	return out.Item, nil
+}
+
+// deleteItem removes an existing item, returning ErrNotFound when it is missing.
+func (t *Table) deleteItem(ctx context.Context, key Item, typ string) error {
+	_, err := t.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           t.name(),
+		Key:                 key,
+		ConditionExpression: aws.String(condExists),
+	})
+	if err != nil {
+		return t.writeError(err, true)
+	}
+	t.logger.Debug("dynamo.delete", logging.Fields{"type": typ})
+	return nil
+}
+
+// queryAll runs in and follows LastEvaluatedKey until every page has been read.
+func (t *Table) queryAll(ctx context.Context, in *dynamodb.QueryInput) ([]Item, error) {
+	var items []Item
+	err := t.queryPages(ctx, in, func(page []Item) bool {
+		items = append(items, page...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// queryPages runs in and passes each page of items to fn, following LastEvaluatedKey until the last
+// page has been read or fn returns false.
+func (t *Table) queryPages(ctx context.Context, in *dynamodb.QueryInput, fn func([]Item) bool) error {
+	in.TableName = t.name()
+	for {
+		out, err := t.client.Query(ctx, in)
+		if err != nil {
+			return awserrors.Classify(err)
+		}
+		if !fn(out.Items) || len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// scanType reads every item of the given Type. ADR-0002 has no partition listing tenants or users, so
+// this is a full table scan intended for administrative tooling rather than request paths.
+func (t *Table) scanType(ctx context.Context, typ string) ([]Item, error) {
+	in := &dynamodb.ScanInput{
+		TableName:                 t.name(),
+		FilterExpression:          aws.String("#type = :type"),
+		ExpressionAttributeNames:  map[string]string{"#type": "Type"},
+		ExpressionAttributeValues: Item{":type": StringAttribute(typ)},
+	}
+	var items []Item
+	for {
+		out, err := t.client.Scan(ctx, in)
+		if err != nil {
+			return nil, awserrors.Classify(err)
+		}
+		items = append(items, out.Items...)
+		if len(out.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+func (t *Table) writeError(err error, notFound bool) error {
+	var ccf *types.ConditionalCheckFailedException
+	if notFound && errors.As(err, &ccf) {
+		return ErrNotFound
+	}
+	return awserrors.Classify(err)
+}
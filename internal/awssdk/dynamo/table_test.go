@@ -0,0 +1,22 @@
+package dynamo
+
+import "testing"
+
+func TestTableNameFromArn(t *testing.T) {
+	name, err := TableNameFromArn("arn:aws:dynamodb:us-east-1:123456789012:table/vpa-auth")
+	if err != nil || name != "vpa-auth" {
+		t.Fatalf("got %q, %v", name, err)
+	}
+	if name, _ := TableNameFromArn("arn:aws-us-gov:dynamodb:us-gov-west-1:123456789012:table/auth/stream/2025"); name != "auth" {
+		t.Fatalf("stream ARN: got %q", name)
+	}
+	for _, bad := range []string{"vpa-auth", "arn:aws:s3:::bucket", "arn:aws:dynamodb:us-east-1:123456789012:table/"} {
+		if _, err := TableNameFromArn(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	tbl, err := NewTableFromArn(&recordingClient{}, "arn:aws:dynamodb:us-east-1:123456789012:table/vpa-auth", nil)
+	if err != nil || tbl.Name != "vpa-auth" || tbl.logger == nil {
+		t.Fatalf("NewTableFromArn: %+v, %v", tbl, err)
+	}
+}
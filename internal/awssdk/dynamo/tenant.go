@@ -0,0 +1,71 @@
+package dynamo
+
+import "context"
+
+// Tenant is a tenant record.
+type Tenant struct {
+	TenantId string
+	Name     string
+}
+
+// Item renders the ADR-0002 Tenant item: primary key, tenant-name GSI1 keys, Type and attributes.
+func (t Tenant) Item() Item {
+	item := mergeItems(TenantPrimaryKey(t.TenantId), TenantNameGSIKeys(t.Name))
+	item["Type"] = StringAttribute(TypeTenant)
+	item["tenantId"] = StringAttribute(t.TenantId)
+	item["name"] = StringAttribute(t.Name)
+	return item
+}
+
+// TenantFromItem decodes a Tenant item.
+func TenantFromItem(item Item) (Tenant, error) {
+	if err := checkType(item, TypeTenant); err != nil {
+		return Tenant{}, err
+	}
+	return Tenant{TenantId: stringValue(item, "tenantId"), Name: stringValue(item, "name")}, nil
+}
+
+// CreateTenant stores a new tenant, assigning a ULID when TenantId is empty.
+func (r *Repository) CreateTenant(ctx context.Context, t Tenant) (Tenant, error) {
+	if t.TenantId == "" {
+		t.TenantId = r.newID()
+	}
+	if err := requireFields(TypeTenant, "name", t.Name); err != nil {
+		return Tenant{}, err
+	}
+	if err := r.table.putItem(ctx, t.Item(), condNotExists, nil, false); err != nil {
+		return Tenant{}, err
+	}
+	return t, nil
+}
+
+// GetTenant reads a tenant by id.
+func (r *Repository) GetTenant(ctx context.Context, tenantId string) (Tenant, error) {
+	item, err := r.table.getItem(ctx, TenantPrimaryKey(tenantId), TypeTenant)
+	if err != nil {
+		return Tenant{}, err
+	}
+	return TenantFromItem(item)
+}
+
+// UpdateTenant replaces an existing tenant; renaming also moves its GSI1 name keys.
+func (r *Repository) UpdateTenant(ctx context.Context, t Tenant) error {
+	if err := requireFields(TypeTenant, "tenantId", t.TenantId, "name", t.Name); err != nil {
+		return err
+	}
+	return r.table.putItem(ctx, t.Item(), condExists, nil, true)
+}
+
+// DeleteTenant removes a tenant by id.
+func (r *Repository) DeleteTenant(ctx context.Context, tenantId string) error {
+	return r.table.deleteItem(ctx, TenantPrimaryKey(tenantId), TypeTenant)
+}
+
+// ListTenants returns every tenant (full table scan; see scanType).
+func (r *Repository) ListTenants(ctx context.Context) ([]Tenant, error) {
+	items, err := r.table.scanType(ctx, TypeTenant)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll(items, TenantFromItem)
+}
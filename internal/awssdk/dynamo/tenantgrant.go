@@ -0,0 +1,111 @@
+package dynamo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// TenantGrant is a user's membership in a tenant together with the tenant-scoped role ids granted.
+type TenantGrant struct {
+	TenantGrantId string
+	TenantId      string
+	UserId        string
+	Roles         []string
+}
+
+// Item renders the ADR-0002 TenantGrant item: tenant/user primary key, user->tenant GSI1 keys,
+// grant-id GSI2 keys, Type and attributes.
+func (g TenantGrant) Item() Item {
+	item := mergeItems(
+		TenantGrantPrimaryKey(g.TenantId, g.UserId),
+		TenantGrantGSI1Keys(g.UserId, g.TenantId),
+		TenantGrantIdGSIKeys(g.TenantGrantId),
+	)
+	item["Type"] = StringAttribute(TypeTenantGrant)
+	item["tenantGrantId"] = StringAttribute(g.TenantGrantId)
+	item["tenantId"] = StringAttribute(g.TenantId)
+	item["userId"] = StringAttribute(g.UserId)
+	item["roles"] = StringListAttribute(g.Roles)
+	return item
+}
+
+// TenantGrantFromItem decodes a TenantGrant item.
+func TenantGrantFromItem(item Item) (TenantGrant, error) {
+	if err := checkType(item, TypeTenantGrant); err != nil {
+		return TenantGrant{}, err
+	}
+	return TenantGrant{
+		TenantGrantId: stringValue(item, "tenantGrantId"),
+		TenantId:      stringValue(item, "tenantId"),
+		UserId:        stringValue(item, "userId"),
+		Roles:         stringListValue(item, "roles"),
+	}, nil
+}
+
+// CreateTenantGrant stores a new grant, assigning a ULID when TenantGrantId is empty. A user has at
+// most one grant per tenant.
+func (r *Repository) CreateTenantGrant(ctx context.Context, g TenantGrant) (TenantGrant, error) {
+	if g.TenantGrantId == "" {
+		g.TenantGrantId = r.newID()
+	}
+	if err := requireFields(TypeTenantGrant, "tenantId", g.TenantId, "userId", g.UserId); err != nil {
+		return TenantGrant{}, err
+	}
+	if err := r.table.putItem(ctx, g.Item(), condNotExists, nil, false); err != nil {
+		return TenantGrant{}, err
+	}
+	return g, nil
+}
+
+// GetTenantGrant reads the grant for userId in tenantId.
+func (r *Repository) GetTenantGrant(ctx context.Context, tenantId string, userId string) (TenantGrant, error) {
+	item, err := r.table.getItem(ctx, TenantGrantPrimaryKey(tenantId, userId), TypeTenantGrant)
+	if err != nil {
+		return TenantGrant{}, err
+	}
+	return TenantGrantFromItem(item)
+}
+
+// UpdateTenantGrant replaces an existing grant (typically its roles). The stored grant must have the
+// same TenantGrantId, otherwise ErrNotFound is returned.
+func (r *Repository) UpdateTenantGrant(ctx context.Context, g TenantGrant) error {
+	if err := requireFields(TypeTenantGrant, "tenantGrantId", g.TenantGrantId, "tenantId", g.TenantId, "userId", g.UserId); err != nil {
+		return err
+	}
+	return r.table.putItem(ctx, g.Item(), condExists+" AND tenantGrantId = :id", Item{":id": StringAttribute(g.TenantGrantId)}, true)
+}
+
+// DeleteTenantGrant removes the grant for userId in tenantId.
+func (r *Repository) DeleteTenantGrant(ctx context.Context, tenantId string, userId string) error {
+	return r.table.deleteItem(ctx, TenantGrantPrimaryKey(tenantId, userId), TypeTenantGrant)
+}
+
+// ListTenantGrantsByTenant returns every grant in tenantId.
+func (r *Repository) ListTenantGrantsByTenant(ctx context.Context, tenantId string) ([]TenantGrant, error) {
+	items, err := r.table.queryAll(ctx, &dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: Item{
+			":pk": StringAttribute(TenantGrantPK(tenantId)),
+			":sk": StringAttribute(UserSK("")),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll(items, TenantGrantFromItem)
+}
+
+// ListTenantGrantsByUser returns every grant held by userId (GSI1 reverse lookup).
+func (r *Repository) ListTenantGrantsByUser(ctx context.Context, userId string) ([]TenantGrant, error) {
+	grants := []TenantGrant{}
+	err := r.EachTenantGrantByUser(ctx, userId, QueryOptions{}, func(g TenantGrant) bool {
+		grants = append(grants, g)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
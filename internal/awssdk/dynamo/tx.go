@@ -3,58 +3,253 @@ package dynamo
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
-	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
 	"github.com/mikecbrant/verified-permissions-authorizer/internal/utils/logging"
 )
 
-// TxPut defines a put with a standard not-exists condition for (PK, SK).
+// MaxTransactionItems is DynamoDB's limit on operations in one TransactWriteItems call.
+const MaxTransactionItems = 100
+
+// TxPut defines a put. By default it carries the standard not-exists condition for (PK, SK); set
+// ConditionExpression to use a different condition, or Overwrite to replace any existing item.
 type TxPut struct {
-	Item Item
+	Item                      Item
+	ConditionExpression       string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues Item
+	Overwrite                 bool
+}
+
+// TxUpdate defines an update of an existing or new item by key.
+type TxUpdate struct {
+	Key                       Item
+	UpdateExpression          string
+	ConditionExpression       string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues Item
+}
+
+// TxDelete defines a delete by key with an optional condition.
+type TxDelete struct {
+	Key                       Item
+	ConditionExpression       string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues Item
 }
 
 // TxCheck defines a condition check (rare in our flows but supported).
 type TxCheck struct {
-	Key                 Item
-	ConditionExpression string
+	Key                       Item
+	ConditionExpression       string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues Item
+}
+
+// Tx is the set of operations written by one transaction, executed as puts, updates, deletes, checks.
+type Tx struct {
+	Puts    []TxPut
+	Updates []TxUpdate
+	Deletes []TxDelete
+	Checks  []TxCheck
 }
 
-// WriteTransaction composes a TransactWriteItems call using the provided client.
-// It applies not-exists conditions for each TxPut and classifies errors.
-func WriteTransaction(ctx context.Context, client interface {
-	TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
-}, puts []TxPut, checks []TxCheck, logger logging.Logger) error {
-	if logger == nil {
-		logger = logging.NopLogger{}
+// Len returns the number of operations in the transaction.
+func (tx Tx) Len() int { return len(tx.Puts) + len(tx.Updates) + len(tx.Deletes) + len(tx.Checks) }
+
+// Validate checks the transaction against DynamoDB's rules: at least one and at most
+// MaxTransactionItems operations, an update expression on every update, and no two operations on the
+// same item.
+func (tx Tx) Validate() error {
+	if tx.Len() == 0 {
+		return errors.New("dynamo: transaction requires at least one operation")
+	}
+	if tx.Len() > MaxTransactionItems {
+		return fmt.Errorf("dynamo: transaction has %d operations; DynamoDB allows at most %d", tx.Len(), MaxTransactionItems)
+	}
+	return tx.validateOps()
+}
+
+// validateOps checks that every update has an update expression and that no two operations target the
+// same item.
+func (tx Tx) validateOps() error {
+	for i, u := range tx.Updates {
+		if u.UpdateExpression == "" {
+			return fmt.Errorf("dynamo: update %d requires an UpdateExpression", i)
+		}
 	}
-	if len(puts) == 0 && len(checks) == 0 {
-		return errors.New("dynamo: WriteTransaction requires at least one put or check")
+	seen := map[string]string{}
+	for _, op := range tx.ops() {
+		k := stringValue(op.key, "PK") + "|" + stringValue(op.key, "SK")
+		if prev, ok := seen[k]; ok {
+			return fmt.Errorf("dynamo: %s[%d] and %s target the same item (PK=%s, SK=%s)", op.op, op.index, prev, stringValue(op.key, "PK"), stringValue(op.key, "SK"))
+		}
+		seen[k] = fmt.Sprintf("%s[%d]", op.op, op.index)
 	}
-	var actions []types.TransactWriteItem
-	// Puts with uniqueness condition on PK and SK
-	for i, p := range puts {
-		cond := "attribute_not_exists(PK) AND attribute_not_exists(SK)"
+	return nil
+}
+
+// ops describes the transaction's operations in execution order.
+func (tx Tx) ops() []txOp {
+	ops := make([]txOp, 0, tx.Len())
+	for i, p := range tx.Puts {
+		ops = append(ops, txOp{op: "put", index: i, key: primaryKey(p.Item)})
+	}
+	for i, u := range tx.Updates {
+		ops = append(ops, txOp{op: "update", index: i, key: u.Key})
+	}
+	for i, d := range tx.Deletes {
+		ops = append(ops, txOp{op: "delete", index: i, key: d.Key})
+	}
+	for i, c := range tx.Checks {
+		ops = append(ops, txOp{op: "check", index: i, key: c.Key})
+	}
+	return ops
+}
+
+// actions renders the TransactWriteItems for the transaction against tableName, in the same order as ops.
+func (tx Tx) actions(tableName string) []types.TransactWriteItem {
+	table := &tableName
+	actions := make([]types.TransactWriteItem, 0, tx.Len())
+	for _, p := range tx.Puts {
+		cond := p.ConditionExpression
+		if cond == "" && !p.Overwrite {
+			cond = condNotExists
+		}
 		actions = append(actions, types.TransactWriteItem{Put: &types.Put{
-			TableName:           nil, // caller must set on client via middleware or pass via options
-			Item:                p.Item,
-			ConditionExpression: &cond,
+			TableName:                 table,
+			Item:                      p.Item,
+			ConditionExpression:       optionalString(cond),
+			ExpressionAttributeNames:  p.ExpressionAttributeNames,
+			ExpressionAttributeValues: p.ExpressionAttributeValues,
+		}})
+	}
+	for _, u := range tx.Updates {
+		actions = append(actions, types.TransactWriteItem{Update: &types.Update{
+			TableName:                 table,
+			Key:                       u.Key,
+			UpdateExpression:          optionalString(u.UpdateExpression),
+			ConditionExpression:       optionalString(u.ConditionExpression),
+			ExpressionAttributeNames:  u.ExpressionAttributeNames,
+			ExpressionAttributeValues: u.ExpressionAttributeValues,
+		}})
+	}
+	for _, d := range tx.Deletes {
+		actions = append(actions, types.TransactWriteItem{Delete: &types.Delete{
+			TableName:                 table,
+			Key:                       d.Key,
+			ConditionExpression:       optionalString(d.ConditionExpression),
+			ExpressionAttributeNames:  d.ExpressionAttributeNames,
+			ExpressionAttributeValues: d.ExpressionAttributeValues,
 		}})
-		logger.Debug("dynamo.tx.put", logging.Fields{"index": i})
 	}
-	for i, c := range checks {
+	for _, c := range tx.Checks {
 		actions = append(actions, types.TransactWriteItem{ConditionCheck: &types.ConditionCheck{
-			Key:                 c.Key,
-			ConditionExpression: &c.ConditionExpression,
+			TableName:                 table,
+			Key:                       c.Key,
+			ConditionExpression:       optionalString(c.ConditionExpression),
+			ExpressionAttributeNames:  c.ExpressionAttributeNames,
+			ExpressionAttributeValues: c.ExpressionAttributeValues,
 		}})
-		logger.Debug("dynamo.tx.check", logging.Fields{"index": i})
 	}
-	_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: actions})
+	return actions
+}
+
+// WriteTransaction composes a TransactWriteItems call against the table.
+// It applies not-exists conditions for each TxPut and classifies errors; a canceled transaction is
+// returned as a TransactionCanceledError whose cancellations refer to the TxPut/TxCheck indexes.
+func (t *Table) WriteTransaction(ctx context.Context, puts []TxPut, checks []TxCheck) error {
+	return t.WriteTx(ctx, Tx{Puts: puts, Checks: checks})
+}
+
+// WriteTx validates tx (see Tx.Validate) and writes it atomically, retrying per t.Retry while it fails
+// only for transient reasons. A canceled transaction is returned as a TransactionCanceledError whose
+// cancellations refer to the per-kind operation indexes.
+func (t *Table) WriteTx(ctx context.Context, tx Tx) error {
+	if err := tx.Validate(); err != nil {
+		return err
+	}
+	ops := tx.ops()
+	for _, op := range ops {
+		t.logger.Debug("dynamo.tx."+op.op, logging.Fields{"index": op.index})
+	}
+	in := &dynamodb.TransactWriteItemsInput{TransactItems: tx.actions(t.Name)}
+	err := awssdk.Retry(ctx, t.Retry, t.logger, "dynamo.TransactWriteItems", func(ctx context.Context) error {
+		if _, err := t.client.TransactWriteItems(ctx, in); err != nil {
+			return transactionError(err, ops)
+		}
+		return nil
+	})
 	if err != nil {
-		return awserrors.Classify(err)
+		return err
+	}
+	t.logger.Info("dynamo.tx.ok", logging.Fields{"puts": len(tx.Puts), "updates": len(tx.Updates), "deletes": len(tx.Deletes), "checks": len(tx.Checks)})
+	return nil
+}
+
+// WriteTxBatches writes a transaction that may exceed MaxTransactionItems by splitting its writes
+// (puts, updates, deletes, in that order) into consecutive transactions. Every batch repeats all checks,
+// so each remains guarded by them. Batches are atomic individually but not together: on failure the
+// returned error reports how many batches were committed, and earlier batches are not rolled back.
+// A check must not target an item the transaction writes: once a batch wrote it, the check would fail
+// every later batch, so such transactions are rejected before anything is written.
+func (t *Table) WriteTxBatches(ctx context.Context, tx Tx) error {
+	if tx.Len() <= MaxTransactionItems {
+		return t.WriteTx(ctx, tx)
+	}
+	if err := tx.validateOps(); err != nil {
+		return err
+	}
+	size := MaxTransactionItems - len(tx.Checks)
+	if size < 1 {
+		return fmt.Errorf("dynamo: %d checks leave no room for writes in a transaction of at most %d operations", len(tx.Checks), MaxTransactionItems)
+	}
+	batches := splitTx(tx, size)
+	for i, b := range batches {
+		if err := t.WriteTx(ctx, b); err != nil {
+			return fmt.Errorf("dynamo: transaction batch %d of %d failed after %d committed: %w", i+1, len(batches), i, err)
+		}
 	}
-	logger.Info("dynamo.tx.ok", logging.Fields{"puts": len(puts), "checks": len(checks)})
 	return nil
 }
+
+// splitTx splits the writes of tx into transactions of at most size writes, each carrying all checks.
+func splitTx(tx Tx, size int) []Tx {
+	var out []Tx
+	cur := Tx{}
+	flush := func() {
+		cur.Checks = tx.Checks
+		out = append(out, cur)
+		cur = Tx{}
+	}
+	add := func(f func()) {
+		if cur.Len() == size {
+			flush()
+		}
+		f()
+	}
+	for _, p := range tx.Puts {
+		add(func() { cur.Puts = append(cur.Puts, p) })
+	}
+	for _, u := range tx.Updates {
+		add(func() { cur.Updates = append(cur.Updates, u) })
+	}
+	for _, d := range tx.Deletes {
+		add(func() { cur.Deletes = append(cur.Deletes, d) })
+	}
+	if cur.Len() > 0 {
+		flush()
+	}
+	return out
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -5,22 +5,44 @@ import (
 	sterrors "errors"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/smithy-go"
 
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
 	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
 	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/internal/testutil"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/utils/logging"
 )
 
+// txOnlyClient routes transactions to a FakeDynamoTxnClient; other calls are not expected.
+type txOnlyClient struct {
+	Client
+	fake *testutil.FakeDynamoTxnClient
+}
+
+func (c txOnlyClient) TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	return c.fake.TransactWriteItems(ctx, in, opts...)
+}
+
+func txTable(fake *testutil.FakeDynamoTxnClient, logger logging.Logger) *Table {
+	t := NewTable(txOnlyClient{fake: fake}, "auth", logger)
+	t.Retry = awssdk.RetryPolicy{MaxAttempts: 2}
+	return t
+}
+
 func TestWriteTransaction_BuildsActions(t *testing.T) {
 	c := &testutil.FakeDynamoTxnClient{}
 	l := &testutil.BufferLogger{}
 	item := Item{"PK": StringAttribute("A"), "SK": StringAttribute("B")}
-	if err := WriteTransaction(context.Background(), c, []TxPut{{Item: item}}, nil, l); err != nil {
+	if err := txTable(c, l).WriteTransaction(context.Background(), []TxPut{{Item: item}}, nil); err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
 	if c.In == nil || len(c.In.TransactItems) != 1 || c.In.TransactItems[0].Put == nil {
 		t.Fatalf("missing put in transact items: %#v", c.In)
 	}
+	if name := c.In.TransactItems[0].Put.TableName; name == nil || *name != "auth" {
+		t.Fatalf("expected table name on put, got: %v", name)
+	}
 	if cond := c.In.TransactItems[0].Put.ConditionExpression; cond == nil || *cond == "" {
 		t.Fatalf("expected not-exists condition on put, got: %v", cond)
 	}
@@ -66,7 +88,101 @@ func TestClassifyErrors(t *testing.T) {
 func TestWriteTransaction_EmptyInputError(t *testing.T) {
 	c := &testutil.FakeDynamoTxnClient{}
 	l := &testutil.BufferLogger{}
-	if err := WriteTransaction(context.Background(), c, nil, nil, l); err == nil {
+	if err := txTable(c, l).WriteTransaction(context.Background(), nil, nil); err == nil {
 		t.Fatalf("expected error on empty input")
 	}
 }
+
+func TestWriteTx_BuildsAllOperationKinds(t *testing.T) {
+	c := &testutil.FakeDynamoTxnClient{}
+	tx := Tx{
+		Puts: []TxPut{
+			{Item: Tenant{TenantId: "t", Name: "new"}.Item(), ConditionExpression: "#name = :old", ExpressionAttributeNames: map[string]string{"#name": "name"}, ExpressionAttributeValues: Item{":old": StringAttribute("old")}},
+			{Item: Policy{PolicyId: "p", Name: "n"}.Item(), Overwrite: true},
+		},
+		Updates: []TxUpdate{{Key: TenantGrantPrimaryKey("t", "u"), UpdateExpression: "SET #roles = :roles", ExpressionAttributeNames: map[string]string{"#roles": "roles"}, ExpressionAttributeValues: Item{":roles": StringListAttribute([]string{"r"})}}},
+		Deletes: []TxDelete{{Key: UserPrimaryKey("u"), ConditionExpression: "attribute_exists(PK)"}},
+	}
+	if err := txTable(c, nil).WriteTx(context.Background(), tx); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	items := c.In.TransactItems
+	if len(items) != 4 || items[0].Put == nil || items[2].Update == nil || items[3].Delete == nil {
+		t.Fatalf("unexpected transact items: %#v", items)
+	}
+	if *items[0].Put.ConditionExpression != "#name = :old" || items[1].Put.ConditionExpression != nil {
+		t.Fatalf("unexpected put conditions: %v %v", items[0].Put.ConditionExpression, items[1].Put.ConditionExpression)
+	}
+	if *items[2].Update.UpdateExpression != "SET #roles = :roles" || items[2].Update.ConditionExpression != nil {
+		t.Fatalf("unexpected update: %#v", items[2].Update)
+	}
+}
+
+func TestTxValidate(t *testing.T) {
+	key := UserPrimaryKey("u")
+	tooMany := Tx{}
+	for i := 0; i <= MaxTransactionItems; i++ {
+		tooMany.Puts = append(tooMany.Puts, TxPut{Item: UserPrimaryKey(string(rune('a' + i)))})
+	}
+	for name, tx := range map[string]Tx{
+		"too many":       tooMany,
+		"duplicate":      {Puts: []TxPut{{Item: key}}, Checks: []TxCheck{{Key: key, ConditionExpression: "attribute_exists(PK)"}}},
+		"missing update": {Updates: []TxUpdate{{Key: key}}},
+	} {
+		if err := tx.Validate(); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestWriteTxBatches_SplitsWritesAndRepeatsChecks(t *testing.T) {
+	c := &testutil.FakeDynamoTxnClient{}
+	tx := Tx{Checks: []TxCheck{{Key: TenantPrimaryKey("t"), ConditionExpression: "attribute_exists(PK)"}}}
+	for i := 0; i < 150; i++ {
+		tx.Deletes = append(tx.Deletes, TxDelete{Key: TenantGrantPrimaryKey("t", NewULID())})
+	}
+	if err := txTable(c, nil).WriteTxBatches(context.Background(), tx); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(c.Inputs) != 2 || len(c.Inputs[0].TransactItems) != MaxTransactionItems || len(c.Inputs[1].TransactItems) != 52 {
+		t.Fatalf("unexpected batches: %d", len(c.Inputs))
+	}
+	if c.Inputs[1].TransactItems[51].ConditionCheck == nil {
+		t.Fatalf("expected checks repeated in every batch")
+	}
+	c.Err = canceled(ReasonThrottlingError)
+	if err := txTable(c, nil).WriteTxBatches(context.Background(), tx); err == nil || !testutil.Contains(err.Error(), "after 0 committed") {
+		t.Fatalf("expected batch failure, got %v", err)
+	}
+}
+
+func TestWriteTxBatches_RejectsChecksOnWrittenItems(t *testing.T) {
+	c := &testutil.FakeDynamoTxnClient{}
+	guarded := TenantGrantPrimaryKey("t", NewULID())
+	tx := Tx{Checks: []TxCheck{{Key: guarded, ConditionExpression: "attribute_not_exists(PK)"}}}
+	for i := 0; i < 150; i++ {
+		tx.Puts = append(tx.Puts, TxPut{Item: TenantGrantPrimaryKey("t", NewULID())})
+	}
+	// The guarded item is written in the second batch, after the first would have committed.
+	tx.Puts[120] = TxPut{Item: guarded}
+	err := txTable(c, nil).WriteTxBatches(context.Background(), tx)
+	if err == nil || !testutil.Contains(err.Error(), "target the same item") {
+		t.Fatalf("expected overlapping check to be rejected, got %v", err)
+	}
+	if len(c.Inputs) != 0 {
+		t.Fatalf("no batch may be written, got %d", len(c.Inputs))
+	}
+}
+
+func TestWriteTx_RetriesTransientCancellations(t *testing.T) {
+	c := &testutil.FakeDynamoTxnClient{Err: canceled(ReasonTransactionConflict)}
+	err := txTable(c, nil).WriteTx(context.Background(), Tx{Puts: []TxPut{{Item: UserPrimaryKey("u")}}})
+	var txErr *TransactionCanceledError
+	if !sterrors.As(err, &txErr) || !txErr.Retryable() || len(c.Inputs) != 2 {
+		t.Fatalf("expected 2 attempts ending in a retryable error, got %v after %d", err, len(c.Inputs))
+	}
+	c = &testutil.FakeDynamoTxnClient{Err: canceled(ReasonConditionalCheckFailed)}
+	if err := txTable(c, nil).WriteTx(context.Background(), Tx{Puts: []TxPut{{Item: UserPrimaryKey("u")}}}); err == nil || len(c.Inputs) != 1 {
+		t.Fatalf("conflicts must not be retried: %v after %d", err, len(c.Inputs))
+	}
+}
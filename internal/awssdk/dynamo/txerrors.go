@@ -0,0 +1,126 @@
+package dynamo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+)
+
+// Cancellation reason codes reported by TransactWriteItems.
+const (
+	ReasonConditionalCheckFailed = "ConditionalCheckFailed"
+	ReasonThrottlingError        = "ThrottlingError"
+	ReasonTransactionConflict    = "TransactionConflict"
+	ReasonThroughputExceeded     = "ProvisionedThroughputExceeded"
+	ReasonRequestLimitExceeded   = "RequestLimitExceeded"
+)
+
+// TxCancellation is one cancellation reason of a canceled transaction, mapped back to the operation
+// that caused it.
+type TxCancellation struct {
+	// Op is the operation kind: "put", "update", "delete" or "check".
+	Op string
+	// Index is the position of the operation within its kind, e.g. the index into Tx.Puts.
+	Index int
+	// Key is the PK/SK of the item the operation targeted.
+	Key     Item
+	Code    string
+	Message string
+}
+
+// ConditionFailed reports whether the operation's condition expression evaluated to false.
+func (c TxCancellation) ConditionFailed() bool { return c.Code == ReasonConditionalCheckFailed }
+
+// Retryable reports whether the reason is transient (throttling, capacity or a conflicting transaction).
+func (c TxCancellation) Retryable() bool {
+	switch c.Code {
+	case ReasonThrottlingError, ReasonTransactionConflict, ReasonThroughputExceeded, ReasonRequestLimitExceeded:
+		return true
+	}
+	return false
+}
+
+// TransactionCanceledError is a decoded TransactionCanceledException. Cause is a ConflictError when any
+// condition failed, a RetryableError when every reason is transient, and an OpError otherwise, so
+// errors.As on the awssdk/errors categories keeps working.
+type TransactionCanceledError struct {
+	// Cancellations lists the reasons other than None, in transaction order.
+	Cancellations []TxCancellation
+	Cause         error
+}
+
+func (e *TransactionCanceledError) Error() string {
+	parts := make([]string, 0, len(e.Cancellations))
+	for _, c := range e.Cancellations {
+		parts = append(parts, fmt.Sprintf("%s[%d] %s/%s: %s", c.Op, c.Index, stringValue(c.Key, "PK"), stringValue(c.Key, "SK"), c.Code))
+	}
+	return fmt.Sprintf("dynamo: transaction canceled (%s): %v", strings.Join(parts, "; "), e.Cause)
+}
+
+func (e *TransactionCanceledError) Unwrap() error { return e.Cause }
+
+// Retryable reports whether the whole transaction may succeed on retry.
+func (e *TransactionCanceledError) Retryable() bool {
+	var r *awserrors.RetryableError
+	return errors.As(e.Cause, &r)
+}
+
+// ConditionFailures returns the cancellations caused by failed conditions.
+func (e *TransactionCanceledError) ConditionFailures() []TxCancellation {
+	var out []TxCancellation
+	for _, c := range e.Cancellations {
+		if c.ConditionFailed() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// txOp identifies a transaction item for mapping cancellation reasons back to it.
+type txOp struct {
+	op    string
+	index int
+	key   Item
+}
+
+// transactionError decodes a TransactionCanceledException into a TransactionCanceledError using ops
+// (one per transaction item, in order). Other errors are classified with awserrors.Classify.
+func transactionError(err error, ops []txOp) error {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return awserrors.Classify(err)
+	}
+	out := &TransactionCanceledError{}
+	conflict, retryable := false, true
+	for i, r := range canceled.CancellationReasons {
+		code := aws.ToString(r.Code)
+		if code == "" || code == "None" {
+			continue
+		}
+		c := TxCancellation{Index: i, Code: code, Message: aws.ToString(r.Message)}
+		if i < len(ops) {
+			c.Op, c.Index, c.Key = ops[i].op, ops[i].index, ops[i].key
+		}
+		conflict = conflict || c.ConditionFailed()
+		retryable = retryable && c.Retryable()
+		out.Cancellations = append(out.Cancellations, c)
+	}
+	switch {
+	case conflict:
+		out.Cause = &awserrors.ConflictError{Cause: err}
+	case retryable && len(out.Cancellations) > 0:
+		out.Cause = &awserrors.RetryableError{Cause: err}
+	default:
+		out.Cause = &awserrors.OpError{Cause: err}
+	}
+	return out
+}
+
+func primaryKey(item Item) Item {
+	return Item{"PK": item["PK"], "SK": item["SK"]}
+}
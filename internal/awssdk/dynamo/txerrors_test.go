@@ -0,0 +1,48 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/internal/testutil"
+)
+
+func TestWriteTransaction_DecodesCancellationReasons(t *testing.T) {
+	c := &testutil.FakeDynamoTxnClient{Err: canceled("None", ReasonConditionalCheckFailed, ReasonThrottlingError)}
+	puts := []TxPut{
+		{Item: Item{"PK": StringAttribute("USER#u"), "SK": StringAttribute("USER#u")}},
+		{Item: Item{"PK": StringAttribute("USER_EMAIL#e"), "SK": StringAttribute("USER_EMAIL#e")}},
+	}
+	checks := []TxCheck{{Key: TenantPrimaryKey("t"), ConditionExpression: "attribute_exists(PK)"}}
+	err := txTable(c, nil).WriteTransaction(context.Background(), puts, checks)
+	var txErr *TransactionCanceledError
+	if !errors.As(err, &txErr) || len(txErr.Cancellations) != 2 {
+		t.Fatalf("expected decoded cancellation, got %v", err)
+	}
+	first, second := txErr.Cancellations[0], txErr.Cancellations[1]
+	if first.Op != "put" || first.Index != 1 || stringValue(first.Key, "PK") != "USER_EMAIL#e" || !first.ConditionFailed() {
+		t.Fatalf("unexpected put cancellation: %+v", first)
+	}
+	if second.Op != "check" || second.Index != 0 || !second.Retryable() {
+		t.Fatalf("unexpected check cancellation: %+v", second)
+	}
+	var conflict *awserrors.ConflictError
+	if !errors.As(err, &conflict) || txErr.Retryable() || len(txErr.ConditionFailures()) != 1 {
+		t.Fatalf("expected a non-retryable conflict: %v", err)
+	}
+}
+
+func TestTransactionError_RetryableReasons(t *testing.T) {
+	err := transactionError(canceled(ReasonTransactionConflict, "None", ReasonThroughputExceeded), nil)
+	var retryable *awserrors.RetryableError
+	if !errors.As(err, &retryable) {
+		t.Fatalf("expected retryable, got %v", err)
+	}
+	err = transactionError(canceled("ValidationError"), nil)
+	var op *awserrors.OpError
+	if !errors.As(err, &op) {
+		t.Fatalf("expected op error, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package dynamo
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// crockfordAlphabet is the Crockford base32 alphabet used by ULIDs.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a new ULID (48-bit millisecond timestamp + 80 random bits) in its canonical
+// 26-character Crockford base32 form. ULIDs sort lexicographically by creation time.
+func NewULID() string {
+	var entropy [10]byte
+	_, _ = rand.Read(entropy[:]) // crypto/rand.Read never returns an error
+	return encodeULID(time.Now(), entropy)
+}
+
+func encodeULID(t time.Time, entropy [10]byte) string {
+	ms := uint64(t.UnixMilli())
+	// 128 bits as hi (timestamp + first 2 entropy bytes) and lo (remaining 8 entropy bytes).
+	hi := ms<<16 | uint64(entropy[0])<<8 | uint64(entropy[1])
+	var lo uint64
+	for _, b := range entropy[2:] {
+		lo = lo<<8 | uint64(b)
+	}
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,23 @@
+package dynamo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeULID(t *testing.T) {
+	if got := encodeULID(time.UnixMilli(0), [10]byte{}); got != "00000000000000000000000000" {
+		t.Fatalf("zero ULID: %s", got)
+	}
+	// Timestamp from the ULID spec example (01ARYZ6S41...).
+	if got := encodeULID(time.UnixMilli(1469918176385), [10]byte{}); got[:10] != "01ARYZ6S41" {
+		t.Fatalf("timestamp encoding: %s", got)
+	}
+	max := [10]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if got := encodeULID(time.UnixMilli(1<<48-1), max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Fatalf("max ULID: %s", got)
+	}
+	if a, b := NewULID(), NewULID(); len(a) != 26 || a == b {
+		t.Fatalf("NewULID: %s %s", a, b)
+	}
+}
@@ -0,0 +1,279 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+)
+
+// User is a user record. Roles holds global role ids; tenant roles live on TenantGrant items.
+type User struct {
+	UserId            string
+	Email             string
+	Phone             string
+	PreferredUsername string
+	GivenName         string
+	FamilyName        string
+	Roles             []string
+}
+
+// Item renders the ADR-0002 User item. Empty optional attributes are omitted; roles is always a list.
+func (u User) Item() Item {
+	item := UserPrimaryKey(u.UserId)
+	item["Type"] = StringAttribute(TypeUser)
+	item["userId"] = StringAttribute(u.UserId)
+	setString(item, "email", u.Email)
+	setString(item, "phone", u.Phone)
+	setString(item, "preferredUsername", u.PreferredUsername)
+	setString(item, "givenName", u.GivenName)
+	setString(item, "familyName", u.FamilyName)
+	item["roles"] = StringListAttribute(u.Roles)
+	return item
+}
+
+// UserFromItem decodes a User item.
+func UserFromItem(item Item) (User, error) {
+	if err := checkType(item, TypeUser); err != nil {
+		return User{}, err
+	}
+	return User{
+		UserId:            stringValue(item, "userId"),
+		Email:             stringValue(item, "email"),
+		Phone:             stringValue(item, "phone"),
+		PreferredUsername: stringValue(item, "preferredUsername"),
+		GivenName:         stringValue(item, "givenName"),
+		FamilyName:        stringValue(item, "familyName"),
+		Roles:             stringListValue(item, "roles"),
+	}, nil
+}
+
+// userGuardedAttributes are the User attributes kept unique by guard rows, in transaction order.
+var userGuardedAttributes = []string{"email", "phone", "preferredUsername"}
+
+// userGuard is the uniqueness guard row for one guarded User attribute value.
+type userGuard struct {
+	attribute string
+	value     string
+}
+
+// conflict returns the error reported when the guarded value is taken.
+func (g userGuard) conflict() *UniqueConflictError {
+	domain := map[string]error{"email": ErrEmailTaken, "phone": ErrPhoneTaken, "preferredUsername": ErrPreferredUsernameTaken}[g.attribute]
+	return &UniqueConflictError{Attribute: g.attribute, Value: g.value, domain: domain}
+}
+
+func (g userGuard) key() Item {
+	var pk string
+	switch g.attribute {
+	case "email":
+		pk = UserEmailPK(g.value)
+	case "phone":
+		pk = UserPhonePK(g.value)
+	default:
+		pk = UserPreferredUsernamePK(g.value)
+	}
+	return Item{"PK": StringAttribute(pk), "SK": StringAttribute(pk)}
+}
+
+// item renders the ADR-0002 guard item (UserEmail, UserPhone or UserPreferredUsername) owned by userId.
+func (g userGuard) item(userId string) Item {
+	typ := map[string]string{"email": TypeUserEmail, "phone": TypeUserPhone, "preferredUsername": TypeUserPreferredUsername}[g.attribute]
+	item := g.key()
+	item["Type"] = StringAttribute(typ)
+	item[g.attribute] = StringAttribute(g.value)
+	item["userId"] = StringAttribute(userId)
+	return item
+}
+
+func (g userGuard) put(userId string) TxPut { return TxPut{Item: g.item(userId)} }
+
+// delete removes the guard, provided it still belongs to userId.
+func (g userGuard) delete(userId string) TxDelete {
+	return TxDelete{
+		Key:                       g.key(),
+		ConditionExpression:       "userId = :userId",
+		ExpressionAttributeValues: Item{":userId": StringAttribute(userId)},
+	}
+}
+
+func (u User) guarded(attribute string) string {
+	switch attribute {
+	case "email":
+		return u.Email
+	case "phone":
+		return u.Phone
+	default:
+		return u.PreferredUsername
+	}
+}
+
+func (u *User) setGuarded(attribute string, value string) {
+	switch attribute {
+	case "email":
+		u.Email = value
+	case "phone":
+		u.Phone = value
+	default:
+		u.PreferredUsername = value
+	}
+}
+
+// guards returns the guard rows for the user's non-empty guarded attributes.
+func (u User) guards() []userGuard {
+	var out []userGuard
+	for _, a := range userGuardedAttributes {
+		if v := u.guarded(a); v != "" {
+			out = append(out, userGuard{attribute: a, value: v})
+		}
+	}
+	return out
+}
+
+// guardedCondition requires the stored user to exist with exactly u's guarded attribute values, so a
+// write cannot orphan or bypass guard rows changed concurrently.
+func (u User) guardedCondition() (string, Item) {
+	cond := condExists
+	values := Item{}
+	for _, a := range userGuardedAttributes {
+		c, v := attributeMatches(a, u.guarded(a))
+		cond += " AND " + c
+		for k, av := range v {
+			values[k] = av
+		}
+	}
+	if len(values) == 0 {
+		values = nil
+	}
+	return cond, values
+}
+
+// attributeMatches renders a condition that attribute equals value, or is absent when value is empty.
+func attributeMatches(attribute string, value string) (string, Item) {
+	if value == "" {
+		return "attribute_not_exists(" + attribute + ")", nil
+	}
+	return attribute + " = :" + attribute, Item{":" + attribute: StringAttribute(value)}
+}
+
+// CreateUser stores a new user, assigning a ULID when UserId is empty. The User item and the guard rows
+// for email, phone and preferredUsername are written in one transaction; if a guarded value is taken
+// the result is a UniqueConflictError naming the attribute (errors.Is ErrEmailTaken, ErrPhoneTaken or
+// ErrPreferredUsernameTaken).
+func (r *Repository) CreateUser(ctx context.Context, u User) (User, error) {
+	if u.UserId == "" {
+		u.UserId = r.newID()
+	}
+	tx := Tx{Puts: []TxPut{{Item: u.Item()}}}
+	unique := map[string]*UniqueConflictError{}
+	for _, g := range u.guards() {
+		unique[keyString(g.key())] = g.conflict()
+		tx.Puts = append(tx.Puts, g.put(u.UserId))
+	}
+	if err := r.transact(ctx, tx, unique); err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+// GetUser reads a user by id.
+func (r *Repository) GetUser(ctx context.Context, userId string) (User, error) {
+	item, err := r.table.getItem(ctx, UserPrimaryKey(userId), TypeUser)
+	if err != nil {
+		return User{}, err
+	}
+	return UserFromItem(item)
+}
+
+// UpdateUser replaces an existing user's profile and global roles. Guarded attributes must be unchanged;
+// use ChangeUserEmail and ChangeUserPhone to change them.
+func (r *Repository) UpdateUser(ctx context.Context, u User) error {
+	if err := requireFields(TypeUser, "userId", u.UserId); err != nil {
+		return err
+	}
+	current, err := r.GetUser(ctx, u.UserId)
+	if err != nil {
+		return err
+	}
+	for _, a := range userGuardedAttributes {
+		if u.guarded(a) != current.guarded(a) {
+			return fmt.Errorf("dynamo: User.%s is guarded for uniqueness and cannot be changed by UpdateUser", a)
+		}
+	}
+	cond, values := current.guardedCondition()
+	return r.table.putItem(ctx, u.Item(), cond, values, false)
+}
+
+// ChangeUserEmail sets (or, with "", removes) a user's email, swapping the UserEmail guard row in the
+// same transaction. A taken email yields ErrEmailTaken.
+func (r *Repository) ChangeUserEmail(ctx context.Context, userId string, email string) (User, error) {
+	return r.changeGuarded(ctx, userId, "email", email)
+}
+
+// ChangeUserPhone sets (or, with "", removes) a user's phone, swapping the UserPhone guard row in the
+// same transaction. A taken phone yields ErrPhoneTaken.
+func (r *Repository) ChangeUserPhone(ctx context.Context, userId string, phone string) (User, error) {
+	return r.changeGuarded(ctx, userId, "phone", phone)
+}
+
+func (r *Repository) changeGuarded(ctx context.Context, userId string, attribute string, value string) (User, error) {
+	u, err := r.GetUser(ctx, userId)
+	if err != nil {
+		return User{}, err
+	}
+	old := u.guarded(attribute)
+	if old == value {
+		return u, nil
+	}
+	cond, values := attributeMatches(attribute, old)
+	update := TxUpdate{
+		Key:                       UserPrimaryKey(userId),
+		ConditionExpression:       condExists + " AND " + cond,
+		UpdateExpression:          "REMOVE " + attribute,
+		ExpressionAttributeValues: values,
+	}
+	if value != "" {
+		update.UpdateExpression = "SET " + attribute + " = :new"
+		update.ExpressionAttributeValues = mergeItems(values, Item{":new": StringAttribute(value)})
+	}
+	tx := Tx{Updates: []TxUpdate{update}}
+	if old != "" {
+		tx.Deletes = append(tx.Deletes, userGuard{attribute: attribute, value: old}.delete(userId))
+	}
+	unique := map[string]*UniqueConflictError{}
+	if value != "" {
+		g := userGuard{attribute: attribute, value: value}
+		unique[keyString(g.key())] = g.conflict()
+		tx.Puts = append(tx.Puts, g.put(userId))
+	}
+	if err := r.transact(ctx, tx, unique); err != nil {
+		return User{}, err
+	}
+	u.setGuarded(attribute, value)
+	return u, nil
+}
+
+// DeleteUser removes a user by id together with its guard rows, in one transaction.
+func (r *Repository) DeleteUser(ctx context.Context, userId string) error {
+	u, err := r.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	cond, values := u.guardedCondition()
+	tx := Tx{Deletes: []TxDelete{{
+		Key:                       UserPrimaryKey(userId),
+		ConditionExpression:       cond,
+		ExpressionAttributeValues: values,
+	}}}
+	for _, g := range u.guards() {
+		tx.Deletes = append(tx.Deletes, g.delete(userId))
+	}
+	return r.transact(ctx, tx, nil)
+}
+
+// ListUsers returns every user (full table scan; see scanType).
+func (r *Repository) ListUsers(ctx context.Context) ([]User, error) {
+	items, err := r.table.scanType(ctx, TypeUser)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll(items, UserFromItem)
+}
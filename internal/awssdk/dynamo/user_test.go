@@ -0,0 +1,146 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/internal/testutil"
+)
+
+func canceled(codes ...string) error {
+	reasons := make([]types.CancellationReason, 0, len(codes))
+	for _, c := range codes {
+		reasons = append(reasons, types.CancellationReason{Code: aws.String(c)})
+	}
+	return &types.TransactionCanceledException{Message: aws.String("canceled"), CancellationReasons: reasons}
+}
+
+func TestCreateUserWritesGuardRows(t *testing.T) {
+	c := &recordingClient{}
+	u, err := newTestRepository(c).CreateUser(context.Background(), User{Email: "a@example.com", Phone: "+15550100"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	items := c.tx.TransactItems
+	if len(items) != 3 || stringValue(items[0].Put.Item, "Type") != TypeUser {
+		t.Fatalf("expected user put plus two guards, got %+v", items)
+	}
+	guard := attributes(items[1].Put.Item)
+	if guard["PK"] != "USER_EMAIL#a@example.com" || guard["SK"] != guard["PK"] || guard["Type"] != "UserEmail" || guard["userId"] != u.UserId {
+		t.Fatalf("unexpected email guard: %v", guard)
+	}
+	if *items[2].Put.TableName != "auth" || *items[2].Put.ConditionExpression != condNotExists {
+		t.Fatalf("unexpected phone guard put: %+v", items[2].Put)
+	}
+}
+
+func TestCreateUserReportsCollidingAttribute(t *testing.T) {
+	c := &recordingClient{writeErr: canceled("None", "None", "ConditionalCheckFailed")}
+	_, err := newTestRepository(c).CreateUser(context.Background(), User{Email: "a@example.com", Phone: "+15550100"})
+	var unique *UniqueConflictError
+	if !errors.As(err, &unique) || unique.Attribute != "phone" || unique.Value != "+15550100" || !errors.Is(err, ErrPhoneTaken) {
+		t.Fatalf("expected phone conflict, got %v", err)
+	}
+	var conflict *awserrors.ConflictError
+	var txErr *TransactionCanceledError
+	if !errors.As(err, &conflict) || !errors.As(err, &txErr) || errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected UniqueConflictError to wrap the decoded conflict, got %v", err)
+	}
+	c.writeErr = canceled("ConditionalCheckFailed", "None")
+	if _, err := newTestRepository(c).CreateUser(context.Background(), User{Email: "a@example.com"}); errors.As(err, &unique) || !errors.As(err, &conflict) {
+		t.Fatalf("expected plain conflict for existing user id, got %v", err)
+	}
+}
+
+func TestChangeUserEmailSwapsGuards(t *testing.T) {
+	c := &recordingClient{getItem: User{UserId: "U1", Email: "old@example.com"}.Item()}
+	u, err := newTestRepository(c).ChangeUserEmail(context.Background(), "U1", "new@example.com")
+	if err != nil || u.Email != "new@example.com" {
+		t.Fatalf("got %+v, %v", u, err)
+	}
+	items := c.tx.TransactItems
+	if len(items) != 3 || items[0].Put == nil || items[1].Update == nil || items[2].Delete == nil {
+		t.Fatalf("unexpected transaction: %+v", items)
+	}
+	if *items[1].Update.ConditionExpression != condExists+" AND email = :email" || stringValue(items[2].Delete.Key, "PK") != "USER_EMAIL#old@example.com" {
+		t.Fatalf("unexpected update/delete: %+v %+v", items[1].Update, items[2].Delete)
+	}
+	c.writeErr = canceled("ConditionalCheckFailed", "None", "None")
+	if _, err := newTestRepository(c).ChangeUserEmail(context.Background(), "U1", "taken@example.com"); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected email conflict, got %v", err)
+	}
+}
+
+func TestChangeUserPhoneAddsGuard(t *testing.T) {
+	c := &recordingClient{getItem: User{UserId: "U1"}.Item()}
+	if _, err := newTestRepository(c).ChangeUserPhone(context.Background(), "U1", "+15550100"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	items := c.tx.TransactItems
+	if len(items) != 2 || items[0].Put == nil || *items[1].Update.ConditionExpression != condExists+" AND attribute_not_exists(phone)" {
+		t.Fatalf("unexpected transaction: %+v", items)
+	}
+}
+
+func TestDeleteUserRemovesGuards(t *testing.T) {
+	c := &recordingClient{getItem: User{UserId: "U1", Email: "a@example.com", PreferredUsername: "ada"}.Item()}
+	if err := newTestRepository(c).DeleteUser(context.Background(), "U1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	items := c.tx.TransactItems
+	if len(items) != 3 || stringValue(items[2].Delete.Key, "PK") != "USER_PREFERREDUSERNAME#ada" {
+		t.Fatalf("unexpected transaction: %+v", items)
+	}
+}
+
+func TestUpdateUserRejectsGuardedChanges(t *testing.T) {
+	c := &recordingClient{getItem: User{UserId: "U1", Email: "a@example.com"}.Item()}
+	r := newTestRepository(c)
+	if err := r.UpdateUser(context.Background(), User{UserId: "U1", Email: "b@example.com"}); err == nil {
+		t.Fatalf("expected guarded attribute error")
+	}
+	if err := r.UpdateUser(context.Background(), User{UserId: "U1", Email: "a@example.com", GivenName: "Ada"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if c.put == nil || c.put.ExpressionAttributeValues[":email"] == nil {
+		t.Fatalf("expected guarded condition on put: %+v", c.put)
+	}
+}
+
+func TestUserGuardsAgainstFakeTable(t *testing.T) {
+	ctx := context.Background()
+	f := testutil.NewFakeDynamoDB("auth")
+	r := newTestRepository(f)
+	if _, err := r.CreateUser(ctx, User{UserId: "U1", Email: "a@example.com", PreferredUsername: "ada"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, err := r.CreateUser(ctx, User{UserId: "U2", Email: "b@example.com", PreferredUsername: "ada"}); !errors.Is(err, ErrPreferredUsernameTaken) {
+		t.Fatalf("expected preferred username conflict, got %v", err)
+	}
+	if f.Item(UserEmailPK("b@example.com"), UserEmailPK("b@example.com")) != nil {
+		t.Fatalf("expected the failed create to leave no guard rows")
+	}
+	if _, err := r.ChangeUserEmail(ctx, "U1", "c@example.com"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, err := r.CreateUser(ctx, User{UserId: "U2", Email: "a@example.com"}); err != nil {
+		t.Fatalf("expected the released email to be reusable, got %v", err)
+	}
+	if _, err := r.ChangeUserEmail(ctx, "U2", "c@example.com"); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected email conflict, got %v", err)
+	}
+	if err := r.DeleteUser(ctx, "U1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if f.Len() != 2 {
+		t.Fatalf("expected only U2 and its email guard to remain, got %d items", f.Len())
+	}
+	if _, err := r.GetUser(ctx, "U1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -3,7 +3,10 @@ package errors
 import (
 	goerrors "errors"
 	"fmt"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	vptypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
 	"github.com/aws/smithy-go"
 )
 
@@ -19,13 +22,67 @@ type RetryableError struct{ Cause error }
 func (e *RetryableError) Error() string { return fmt.Sprintf("retryable: %v", e.Cause) }
 func (e *RetryableError) Unwrap() error { return e.Cause }
 
+// NotFoundError indicates the target resource does not exist; callers may recreate it or drop it from state.
+type NotFoundError struct {
+	// ResourceType and ResourceId are set when the service reports them (e.g. Verified Permissions).
+	ResourceType string
+	ResourceId   string
+	Cause        error
+}
+
+func (e *NotFoundError) Error() string { return fmt.Sprintf("not found: %v", e.Cause) }
+func (e *NotFoundError) Unwrap() error { return e.Cause }
+
+// FieldViolation is a field-level validation failure reported by the service.
+type FieldViolation struct {
+	Path    string
+	Message string
+}
+
+// ValidationError indicates the request was rejected as invalid; retrying the same request will fail again.
+type ValidationError struct {
+	// Fields carries Verified Permissions' field-level details (e.g. the policy or schema element at fault).
+	Fields []FieldViolation
+	Cause  error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("validation: %v", e.Cause)
+	}
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Path+": "+f.Message)
+	}
+	return fmt.Sprintf("validation: %v [%s]", e.Cause, strings.Join(parts, "; "))
+}
+func (e *ValidationError) Unwrap() error { return e.Cause }
+
+// QuotaExceededError indicates a service quota was reached; a quota increase is needed before retrying.
+type QuotaExceededError struct {
+	ResourceType string
+	QuotaCode    string
+	ServiceCode  string
+	Cause        error
+}
+
+func (e *QuotaExceededError) Error() string { return fmt.Sprintf("quota exceeded: %v", e.Cause) }
+func (e *QuotaExceededError) Unwrap() error { return e.Cause }
+
+// AccessDeniedError indicates the caller's credentials lack a required permission.
+type AccessDeniedError struct{ Cause error }
+
+func (e *AccessDeniedError) Error() string { return fmt.Sprintf("access denied: %v", e.Cause) }
+func (e *AccessDeniedError) Unwrap() error { return e.Cause }
+
 // OpError is a generic wrapper for unexpected failures.
 type OpError struct{ Cause error }
 
 func (e *OpError) Error() string { return fmt.Sprintf("op error: %v", e.Cause) }
 func (e *OpError) Unwrap() error { return e.Cause }
 
-// Classify maps smithy errors to provider-wide categories.
+// Classify maps smithy errors to provider-wide categories (DynamoDB, Verified Permissions and other
+// AWS services using the standard error codes).
 // Service-specific packages may add further handling, but should prefer using this
 // function for standard throttling/throughput/transaction cases.
 func Classify(err error) error {
@@ -35,11 +92,94 @@ func Classify(err error) error {
 	var api smithy.APIError
 	if goerrors.As(err, &api) {
 		switch api.ErrorCode() {
-		case "ConditionalCheckFailedException", "TransactionCanceledException":
+		case "ConditionalCheckFailedException", "TransactionCanceledException", "ConflictException":
 			return &ConflictError{Cause: err}
-		case "ProvisionedThroughputExceededException", "ThrottlingException", "RequestLimitExceeded", "TransactionInProgressException":
+		case "ProvisionedThroughputExceededException", "ThrottlingException", "RequestLimitExceeded", "TransactionInProgressException", "InternalServerException":
 			return &RetryableError{Cause: err}
+		case "ResourceNotFoundException", "NotFoundException", "NoSuchEntity":
+			return notFound(err)
+		case "ValidationException":
+			return validation(err)
+		case "ServiceQuotaExceededException":
+			return quotaExceeded(err)
+		case "AccessDeniedException", "AccessDenied":
+			return &AccessDeniedError{Cause: err}
 		}
 	}
 	return &OpError{Cause: err}
 }
+
+func notFound(err error) error {
+	out := &NotFoundError{Cause: err}
+	var nf *vptypes.ResourceNotFoundException
+	if goerrors.As(err, &nf) {
+		out.ResourceType, out.ResourceId = string(nf.ResourceType), aws.ToString(nf.ResourceId)
+	}
+	return out
+}
+
+func validation(err error) error {
+	out := &ValidationError{Cause: err}
+	var ve *vptypes.ValidationException
+	if goerrors.As(err, &ve) {
+		for _, f := range ve.FieldList {
+			out.Fields = append(out.Fields, FieldViolation{Path: aws.ToString(f.Path), Message: aws.ToString(f.Message)})
+		}
+	}
+	return out
+}
+
+func quotaExceeded(err error) error {
+	out := &QuotaExceededError{Cause: err}
+	var qe *vptypes.ServiceQuotaExceededException
+	if goerrors.As(err, &qe) {
+		out.ResourceType, out.QuotaCode, out.ServiceCode = string(qe.ResourceType), aws.ToString(qe.QuotaCode), aws.ToString(qe.ServiceCode)
+	}
+	return out
+}
+
+// Describe returns a short summary and an actionable detail for err, suitable for provider
+// diagnostics. Errors that have not been classified yet are classified first.
+func Describe(err error) (summary string, detail string) {
+	if summary, detail, ok := describe(err); ok {
+		return summary, detail
+	}
+	if summary, detail, ok := describe(Classify(err)); ok {
+		return summary, detail
+	}
+	return "AWS request failed", err.Error()
+}
+
+func describe(err error) (string, string, bool) {
+	var (
+		nf *NotFoundError
+		ve *ValidationError
+		qe *QuotaExceededError
+		ad *AccessDeniedError
+		ce *ConflictError
+		re *RetryableError
+	)
+	switch {
+	case goerrors.As(err, &ve):
+		// ValidationError.Error already lists the offending fields.
+		return "Request rejected as invalid", err.Error(), true
+	case goerrors.As(err, &nf):
+		if nf.ResourceType != "" {
+			return "Resource not found", fmt.Sprintf("%s %q does not exist: %v", nf.ResourceType, nf.ResourceId, err), true
+		}
+		return "Resource not found", err.Error(), true
+	case goerrors.As(err, &qe):
+		detail := err.Error()
+		if qe.QuotaCode != "" {
+			detail += fmt.Sprintf(" (service %s, quota %s)", qe.ServiceCode, qe.QuotaCode)
+		}
+		return "Service quota exceeded", detail + "; request a quota increase or remove unused resources", true
+	case goerrors.As(err, &ad):
+		return "Access denied", err.Error() + "; check the IAM permissions of the credentials used by the provider", true
+	case goerrors.As(err, &ce):
+		return "Conflicting change", err.Error(), true
+	case goerrors.As(err, &re):
+		return "Transient AWS error", err.Error() + "; retrying the operation may succeed", true
+	}
+	return "", "", false
+}
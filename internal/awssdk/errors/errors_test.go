@@ -0,0 +1,62 @@
+package errors
+
+import (
+	goerrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	vptypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestClassifyVerifiedPermissionsErrors(t *testing.T) {
+	validation := &vptypes.ValidationException{Message: aws.String("bad policy"), FieldList: []vptypes.ValidationExceptionField{
+		{Path: aws.String("definition.static.statement"), Message: aws.String("unexpected token")},
+	}}
+	var ve *ValidationError
+	if err := Classify(fmt.Errorf("create policy: %w", validation)); !goerrors.As(err, &ve) || len(ve.Fields) != 1 || ve.Fields[0].Path != "definition.static.statement" {
+		t.Fatalf("expected validation error with fields, got %v", err)
+	}
+	var nf *NotFoundError
+	if err := Classify(&vptypes.ResourceNotFoundException{ResourceType: vptypes.ResourceTypePolicyStore, ResourceId: aws.String("ps-1")}); !goerrors.As(err, &nf) || nf.ResourceId != "ps-1" || nf.ResourceType != "POLICY_STORE" {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	var qe *QuotaExceededError
+	if err := Classify(&vptypes.ServiceQuotaExceededException{QuotaCode: aws.String("L-1"), ServiceCode: aws.String("verifiedpermissions")}); !goerrors.As(err, &qe) || qe.QuotaCode != "L-1" {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"AccessDeniedException", "access denied"},
+		{"ConflictException", "conflict"},
+		{"ThrottlingException", "retryable"},
+		{"InternalServerException", "retryable"},
+		{"NoSuchEntity", "not found"},
+		{"SomethingElse", "op error"},
+	}
+	for _, tt := range tests {
+		if got := Classify(&smithy.GenericAPIError{Code: tt.code}); !strings.HasPrefix(got.Error(), tt.want) {
+			t.Fatalf("Classify(%s) = %v; want prefix %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	err := fmt.Errorf("put schema failed: %w", &vptypes.ValidationException{Message: aws.String("invalid schema"), FieldList: []vptypes.ValidationExceptionField{
+		{Path: aws.String("ns.entityTypes.User"), Message: aws.String("unknown type")},
+	}})
+	summary, detail := Describe(err)
+	if summary != "Request rejected as invalid" || !strings.Contains(detail, "ns.entityTypes.User: unknown type") {
+		t.Fatalf("unexpected description: %q / %q", summary, detail)
+	}
+	if summary, _ := Describe(&AccessDeniedError{Cause: goerrors.New("denied")}); summary != "Access denied" {
+		t.Fatalf("unexpected summary for classified error: %q", summary)
+	}
+	if summary, _ := Describe(goerrors.New("boom")); summary != "AWS request failed" {
+		t.Fatalf("unexpected summary for plain error: %q", summary)
+	}
+}
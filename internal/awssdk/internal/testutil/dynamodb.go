@@ -0,0 +1,567 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// keySchema names an index and its partition (hash) and sort (range) key attributes.
+type keySchema struct {
+	hash, rng string
+}
+
+// fakeIndexes is the ADR-0002 layout; "" is the base table.
+var fakeIndexes = map[string]keySchema{
+	"":     {hash: "PK", rng: "SK"},
+	"GSI1": {hash: "GSI1PK", rng: "GSI1SK"},
+	"GSI2": {hash: "GSI2PK", rng: "GSI2SK"},
+}
+
+// FakeDynamoDB is an in-memory auth table with the ADR-0002 key layout (PK/SK plus the GSI1 and GSI2
+// indexes). It implements GetItem, PutItem, UpdateItem, DeleteItem, Query, Scan and TransactWriteItems,
+// evaluates the expressions the dynamo package writes (see expression.go) and fails the way DynamoDB
+// does: typed exceptions such as ConditionalCheckFailedException and TransactionCanceledException (with
+// per-item cancellation reasons), or a ValidationException, wrapped in a smithy.OperationError.
+// Query and Scan honour Limit, ExclusiveStartKey, ScanIndexForward, FilterExpression and
+// ProjectionExpression; indexes are sparse, as in DynamoDB.
+type FakeDynamoDB struct {
+	TableName string
+
+	mu    sync.Mutex
+	items map[string]item
+}
+
+// NewFakeDynamoDB returns an empty fake table named tableName.
+func NewFakeDynamoDB(tableName string) *FakeDynamoDB {
+	return &FakeDynamoDB{TableName: tableName, items: map[string]item{}}
+}
+
+// Seed stores items as-is, without conditions, replacing items with the same key.
+func (f *FakeDynamoDB) Seed(items ...map[string]types.AttributeValue) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, it := range items {
+		f.items[storageKey(it)] = copyItem(it)
+	}
+}
+
+// Item returns a copy of the stored item with the given PK and SK, or nil.
+func (f *FakeDynamoDB) Item(pk, sk string) map[string]types.AttributeValue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	it, ok := f.items[storageKey(primaryKey(pk, sk))]
+	if !ok {
+		return nil
+	}
+	return copyItem(it)
+}
+
+// Len returns the number of stored items.
+func (f *FakeDynamoDB) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.items)
+}
+
+// GetItem returns the item with the given key, if any.
+func (f *FakeDynamoDB) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	const op = "GetItem"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkTable(op, in.TableName); err != nil {
+		return nil, err
+	}
+	if err := checkKey(op, in.Key); err != nil {
+		return nil, err
+	}
+	it, ok := f.items[storageKey(in.Key)]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	out, err := project(op, it, in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: out}, nil
+}
+
+// PutItem stores an item if its condition holds.
+func (f *FakeDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	const op = "PutItem"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkTable(op, in.TableName); err != nil {
+		return nil, err
+	}
+	if err := checkItemKeys(op, in.Item); err != nil {
+		return nil, err
+	}
+	k := storageKey(in.Item)
+	old := f.items[k]
+	if err := checkCondition(op, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+	f.items[k] = copyItem(in.Item)
+	out := &dynamodb.PutItemOutput{}
+	if in.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = old
+	}
+	return out, nil
+}
+
+// UpdateItem applies a SET/REMOVE update expression if its condition holds, creating the item when it
+// does not exist.
+func (f *FakeDynamoDB) UpdateItem(_ context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	const op = "UpdateItem"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkTable(op, in.TableName); err != nil {
+		return nil, err
+	}
+	updated, err := f.prepareUpdate(op, in.Key, in.UpdateExpression, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	k := storageKey(in.Key)
+	old := f.items[k]
+	f.items[k] = updated
+	out := &dynamodb.UpdateItemOutput{}
+	switch in.ReturnValues {
+	case types.ReturnValueAllOld:
+		out.Attributes = old
+	case types.ReturnValueAllNew:
+		out.Attributes = copyItem(updated)
+	}
+	return out, nil
+}
+
+// DeleteItem removes an item if its condition holds; deleting a missing item is not an error.
+func (f *FakeDynamoDB) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	const op = "DeleteItem"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkTable(op, in.TableName); err != nil {
+		return nil, err
+	}
+	if err := checkKey(op, in.Key); err != nil {
+		return nil, err
+	}
+	k := storageKey(in.Key)
+	old := f.items[k]
+	if err := checkCondition(op, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+	delete(f.items, k)
+	out := &dynamodb.DeleteItemOutput{}
+	if in.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = old
+	}
+	return out, nil
+}
+
+// Query reads the items of the table or a GSI that match the key condition, in sort-key order.
+func (f *FakeDynamoDB) Query(_ context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	const op = "Query"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkTable(op, in.TableName); err != nil {
+		return nil, err
+	}
+	schema, err := readSchema(op, in.IndexName, in.ConsistentRead)
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToString(in.KeyConditionExpression) == "" {
+		return nil, validationError(op, "Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	keyCond, err := parseCondition(aws.ToString(in.KeyConditionExpression), in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, validationError(op, "Invalid KeyConditionExpression: %v", err)
+	}
+	var matched []item
+	for _, it := range f.indexItems(schema, in.ScanIndexForward == nil || *in.ScanIndexForward) {
+		if keyCond.eval(it) {
+			matched = append(matched, it)
+		}
+	}
+	p, err := f.page(op, schema, matched, pageRequest{
+		start:      in.ExclusiveStartKey,
+		limit:      aws.ToInt32(in.Limit),
+		filter:     in.FilterExpression,
+		projection: in.ProjectionExpression,
+		names:      in.ExpressionAttributeNames,
+		values:     in.ExpressionAttributeValues,
+		forward:    in.ScanIndexForward == nil || *in.ScanIndexForward,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{Items: p.items, Count: int32(len(p.items)), ScannedCount: p.scanned, LastEvaluatedKey: p.last}, nil
+}
+
+// Scan reads every item of the table or a GSI.
+func (f *FakeDynamoDB) Scan(_ context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	const op = "Scan"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkTable(op, in.TableName); err != nil {
+		return nil, err
+	}
+	schema, err := readSchema(op, in.IndexName, in.ConsistentRead)
+	if err != nil {
+		return nil, err
+	}
+	p, err := f.page(op, schema, f.indexItems(schema, true), pageRequest{
+		start:      in.ExclusiveStartKey,
+		limit:      aws.ToInt32(in.Limit),
+		filter:     in.FilterExpression,
+		projection: in.ProjectionExpression,
+		names:      in.ExpressionAttributeNames,
+		values:     in.ExpressionAttributeValues,
+		forward:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.ScanOutput{Items: p.items, Count: int32(len(p.items)), ScannedCount: p.scanned, LastEvaluatedKey: p.last}, nil
+}
+
+// transactOp is one element of a TransactWriteItems request.
+type transactOp struct {
+	table  *string
+	key    item
+	cond   *string
+	names  map[string]string
+	values item
+	put    item    // Put: the new item
+	update *string // Update: the update expression
+	delete bool
+}
+
+func newTransactOp(op string, i int, ti types.TransactWriteItem) (transactOp, error) {
+	switch {
+	case ti.Put != nil:
+		p := ti.Put
+		return transactOp{table: p.TableName, key: p.Item, cond: p.ConditionExpression, names: p.ExpressionAttributeNames, values: p.ExpressionAttributeValues, put: p.Item}, nil
+	case ti.Update != nil:
+		u := ti.Update
+		return transactOp{table: u.TableName, key: u.Key, cond: u.ConditionExpression, names: u.ExpressionAttributeNames, values: u.ExpressionAttributeValues, update: u.UpdateExpression}, nil
+	case ti.Delete != nil:
+		d := ti.Delete
+		return transactOp{table: d.TableName, key: d.Key, cond: d.ConditionExpression, names: d.ExpressionAttributeNames, values: d.ExpressionAttributeValues, delete: true}, nil
+	case ti.ConditionCheck != nil:
+		c := ti.ConditionCheck
+		if aws.ToString(c.ConditionExpression) == "" {
+			return transactOp{}, validationError(op, "TransactItems[%d]: ConditionCheck requires a ConditionExpression", i)
+		}
+		return transactOp{table: c.TableName, key: c.Key, cond: c.ConditionExpression, names: c.ExpressionAttributeNames, values: c.ExpressionAttributeValues}, nil
+	}
+	return transactOp{}, validationError(op, "TransactItems[%d] must set exactly one of Put, Update, Delete or ConditionCheck", i)
+}
+
+// TransactWriteItems applies every Put, Update, Delete and ConditionCheck atomically. If any condition
+// fails nothing is written and a TransactionCanceledException lists one reason per item.
+func (f *FakeDynamoDB) TransactWriteItems(_ context.Context, in *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	const op = "TransactWriteItems"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(in.TransactItems) == 0 || len(in.TransactItems) > 100 {
+		return nil, validationError(op, "1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length between 1 and 100")
+	}
+	// after holds the resulting item per storage key; nil deletes it. Condition checks write nothing.
+	after := map[string]item{}
+	reasons := make([]types.CancellationReason, len(in.TransactItems))
+	failed := false
+	seen := map[string]bool{}
+	for i, ti := range in.TransactItems {
+		t, err := newTransactOp(op, i, ti)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.checkTable(op, t.table); err != nil {
+			return nil, err
+		}
+		if err := checkItemKeys(op, t.key); err != nil {
+			return nil, err
+		}
+		k := storageKey(t.key)
+		if seen[k] {
+			return nil, validationError(op, "Transaction request cannot include multiple operations on one item")
+		}
+		seen[k] = true
+		var next item
+		if t.update != nil {
+			next, err = f.prepareUpdate(op, t.key, t.update, t.cond, t.names, t.values)
+		} else {
+			err = checkCondition(op, t.cond, t.names, t.values, f.items[k])
+		}
+		var ccf *types.ConditionalCheckFailedException
+		switch {
+		case errors.As(err, &ccf):
+			failed = true
+			reasons[i] = conditionFailedReason()
+			continue
+		case err != nil:
+			return nil, err
+		}
+		reasons[i] = types.CancellationReason{Code: aws.String("None")}
+		switch {
+		case t.put != nil:
+			after[k] = copyItem(t.put)
+		case t.update != nil:
+			after[k] = next
+		case t.delete:
+			after[k] = nil
+		}
+	}
+	if failed {
+		codes := make([]string, len(reasons))
+		for i, r := range reasons {
+			codes[i] = aws.ToString(r.Code)
+		}
+		return nil, opError(op, &types.TransactionCanceledException{
+			Message:             aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons [" + strings.Join(codes, ", ") + "]"),
+			CancellationReasons: reasons,
+		})
+	}
+	for k, it := range after {
+		if it == nil {
+			delete(f.items, k)
+		} else {
+			f.items[k] = it
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// prepareUpdate returns the item that results from applying updateExpr to the item at key when cond
+// holds. Key attributes cannot be updated.
+func (f *FakeDynamoDB) prepareUpdate(op string, key item, updateExpr *string, cond *string, names map[string]string, values item) (item, error) {
+	if err := checkKey(op, key); err != nil {
+		return nil, err
+	}
+	u, err := parseUpdate(aws.ToString(updateExpr), names, values)
+	if err != nil {
+		return nil, validationError(op, "Invalid UpdateExpression: %v", err)
+	}
+	for _, a := range []string{"PK", "SK"} {
+		if _, ok := u.set[a]; ok || slices.Contains(u.remove, a) {
+			return nil, validationError(op, "One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", a)
+		}
+	}
+	current := f.items[storageKey(key)]
+	if err := checkCondition(op, cond, names, values, current); err != nil {
+		return nil, err
+	}
+	base := current
+	if base == nil {
+		base = copyItem(key)
+	}
+	return u.apply(base), nil
+}
+
+func (f *FakeDynamoDB) checkTable(op string, name *string) error {
+	if aws.ToString(name) != f.TableName {
+		return opError(op, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")})
+	}
+	return nil
+}
+
+// indexItems returns the items present in the index (those with both index key attributes), ordered by
+// the index keys and then the table keys.
+func (f *FakeDynamoDB) indexItems(schema keySchema, forward bool) []item {
+	var out []item
+	for _, it := range f.items {
+		if _, ok := it[schema.hash]; !ok {
+			continue
+		}
+		if _, ok := it[schema.rng]; !ok {
+			continue
+		}
+		out = append(out, it)
+	}
+	slices.SortFunc(out, func(a, b item) int {
+		c := slices.Compare(sortTuple(schema, a), sortTuple(schema, b))
+		if !forward {
+			return -c
+		}
+		return c
+	})
+	return out
+}
+
+type pageRequest struct {
+	start      item
+	limit      int32
+	filter     *string
+	projection *string
+	names      map[string]string
+	values     item
+	forward    bool
+}
+
+type page struct {
+	items   []item
+	scanned int32
+	last    item
+}
+
+// page applies ExclusiveStartKey, Limit (counted before the filter, as in DynamoDB), the filter and the
+// projection to ordered items.
+func (f *FakeDynamoDB) page(op string, schema keySchema, ordered []item, req pageRequest) (page, error) {
+	filter, err := parseCondition(aws.ToString(req.filter), req.names, req.values)
+	if err != nil {
+		return page{}, validationError(op, "Invalid FilterExpression: %v", err)
+	}
+	if req.limit < 0 {
+		return page{}, validationError(op, "Limit must be greater than or equal to 1")
+	}
+	var p page
+	if len(req.start) > 0 {
+		start := sortTuple(schema, req.start)
+		ordered = slices.DeleteFunc(slices.Clone(ordered), func(it item) bool {
+			c := slices.Compare(sortTuple(schema, it), start)
+			return (req.forward && c <= 0) || (!req.forward && c >= 0)
+		})
+	}
+	for _, it := range ordered {
+		if req.limit > 0 && p.scanned == req.limit {
+			break
+		}
+		p.scanned++
+		if req.limit > 0 && p.scanned == req.limit {
+			p.last = lastEvaluatedKey(schema, it)
+		}
+		if filter != nil && !filter.eval(it) {
+			continue
+		}
+		out, err := project(op, it, req.projection, req.names)
+		if err != nil {
+			return page{}, err
+		}
+		p.items = append(p.items, out)
+	}
+	return p, nil
+}
+
+// readSchema resolves an index name; GSIs only support eventually consistent reads.
+func readSchema(op string, indexName *string, consistent *bool) (keySchema, error) {
+	name := aws.ToString(indexName)
+	schema, ok := fakeIndexes[name]
+	if !ok {
+		return keySchema{}, validationError(op, "The table does not have the specified index: %s", name)
+	}
+	if name != "" && aws.ToBool(consistent) {
+		return keySchema{}, validationError(op, "Consistent reads are not supported on global secondary indexes")
+	}
+	return schema, nil
+}
+
+// checkCondition evaluates a condition expression against the current item (nil when missing).
+func checkCondition(op string, cond *string, names map[string]string, values item, current item) error {
+	c, err := parseCondition(aws.ToString(cond), names, values)
+	if err != nil {
+		return validationError(op, "Invalid ConditionExpression: %v", err)
+	}
+	if c != nil && !c.eval(current) {
+		return opError(op, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")})
+	}
+	return nil
+}
+
+// checkKey requires exactly the table's PK and SK string attributes.
+func checkKey(op string, key item) error {
+	if len(key) != 2 {
+		return validationError(op, "The provided key element does not match the schema")
+	}
+	return checkItemKeys(op, key)
+}
+
+// checkItemKeys requires the PK and SK string attributes.
+func checkItemKeys(op string, it item) error {
+	for _, a := range []string{"PK", "SK"} {
+		if v, ok := it[a].(*types.AttributeValueMemberS); !ok || v.Value == "" {
+			return validationError(op, "One or more parameter values were invalid: Missing the key %s in the item", a)
+		}
+	}
+	return nil
+}
+
+func project(op string, it item, projection *string, names map[string]string) (item, error) {
+	if aws.ToString(projection) == "" {
+		return copyItem(it), nil
+	}
+	attrs, err := parseProjection(*projection, names)
+	if err != nil {
+		return nil, validationError(op, "Invalid ProjectionExpression: %v", err)
+	}
+	out := item{}
+	for _, a := range attrs {
+		if v, ok := it[a]; ok {
+			out[a] = v
+		}
+	}
+	return out, nil
+}
+
+// sortTuple orders items within an index: index keys first, then the table keys.
+func sortTuple(schema keySchema, it item) []string {
+	return []string{stringAttr(it, schema.hash), stringAttr(it, schema.rng), stringAttr(it, "PK"), stringAttr(it, "SK")}
+}
+
+// lastEvaluatedKey holds the table keys plus, for a GSI, the index keys.
+func lastEvaluatedKey(schema keySchema, it item) item {
+	out := item{}
+	for _, a := range []string{"PK", "SK", schema.hash, schema.rng} {
+		out[a] = it[a]
+	}
+	return out
+}
+
+func primaryKey(pk, sk string) item {
+	return item{"PK": &types.AttributeValueMemberS{Value: pk}, "SK": &types.AttributeValueMemberS{Value: sk}}
+}
+
+func storageKey(it item) string {
+	return stringAttr(it, "PK") + "\x00" + stringAttr(it, "SK")
+}
+
+func stringAttr(it item, name string) string {
+	if s, ok := it[name].(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	out := make(item, len(it))
+	for k, v := range it {
+		out[k] = v
+	}
+	return out
+}
+
+func conditionFailedReason() types.CancellationReason {
+	return types.CancellationReason{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed")}
+}
+
+// opError wraps a service error the way the SDK reports it to callers.
+func opError(op string, err error) error {
+	return &smithy.OperationError{ServiceID: "DynamoDB", OperationName: op, Err: err}
+}
+
+func validationError(op string, format string, args ...any) error {
+	return opError(op, &smithy.GenericAPIError{Code: "ValidationException", Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient})
+}
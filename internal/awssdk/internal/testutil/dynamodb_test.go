@@ -0,0 +1,125 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+func s(v string) types.AttributeValue { return &types.AttributeValueMemberS{Value: v} }
+
+func grant(tenant, user string) map[string]types.AttributeValue {
+	return item{"PK": s("TENANT#" + tenant), "SK": s("USER#" + user), "GSI1PK": s("USER#" + user), "GSI1SK": s("TENANT#" + tenant), "Type": s("TenantGrant")}
+}
+
+func TestFakeDynamoDBConditionalPut(t *testing.T) {
+	f := NewFakeDynamoDB("auth")
+	in := &dynamodb.PutItemInput{TableName: aws.String("auth"), Item: grant("T1", "U1"), ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)")}
+	if _, err := f.PutItem(context.Background(), in); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	_, err := f.PutItem(context.Background(), in)
+	var ccf *types.ConditionalCheckFailedException
+	var opErr *smithy.OperationError
+	if !errors.As(err, &ccf) || !errors.As(err, &opErr) || opErr.OperationName != "PutItem" {
+		t.Fatalf("expected a wrapped ConditionalCheckFailedException, got %v", err)
+	}
+	in.TableName = aws.String("other")
+	var missing *types.ResourceNotFoundException
+	if _, err := f.PutItem(context.Background(), in); !errors.As(err, &missing) {
+		t.Fatalf("expected ResourceNotFoundException for another table, got %v", err)
+	}
+}
+
+func TestFakeDynamoDBQueryPaginatesGSI(t *testing.T) {
+	f := NewFakeDynamoDB("auth")
+	f.Seed(grant("T2", "U1"), grant("T1", "U1"), grant("T3", "U2"), item{"PK": s("USER#U1"), "SK": s("USER#U1")})
+	in := &dynamodb.QueryInput{
+		TableName:                 aws.String("auth"),
+		IndexName:                 aws.String("GSI1"),
+		KeyConditionExpression:    aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :sk)"),
+		ExpressionAttributeValues: item{":pk": s("USER#U1"), ":sk": s("TENANT#")},
+		ProjectionExpression:      aws.String("#t, PK"),
+		ExpressionAttributeNames:  map[string]string{"#t": "Type"},
+		Limit:                     aws.Int32(1),
+	}
+	var got []string
+	for {
+		out, err := f.Query(context.Background(), in)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		for _, it := range out.Items {
+			if len(it) != 2 {
+				t.Fatalf("expected projected item, got %v", it)
+			}
+			got = append(got, stringAttr(it, "PK"))
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+	if len(got) != 2 || got[0] != "TENANT#T1" || got[1] != "TENANT#T2" {
+		t.Fatalf("unexpected query results: %v", got)
+	}
+	in.ConsistentRead = aws.Bool(true)
+	var api smithy.APIError
+	if _, err := f.Query(context.Background(), in); !errors.As(err, &api) || api.ErrorCode() != "ValidationException" {
+		t.Fatalf("expected ValidationException for a consistent GSI read, got %v", err)
+	}
+}
+
+func TestFakeDynamoDBTransactionIsAtomic(t *testing.T) {
+	f := NewFakeDynamoDB("auth")
+	f.Seed(item{"PK": s("USER_EMAIL#a@example.com"), "SK": s("USER_EMAIL#a@example.com"), "userId": s("U0")})
+	notExists := aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)")
+	in := &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+		{Put: &types.Put{TableName: aws.String("auth"), Item: item{"PK": s("USER#U1"), "SK": s("USER#U1")}, ConditionExpression: notExists}},
+		{Put: &types.Put{TableName: aws.String("auth"), Item: item{"PK": s("USER_EMAIL#a@example.com"), "SK": s("USER_EMAIL#a@example.com"), "userId": s("U1")}, ConditionExpression: notExists}},
+	}}
+	_, err := f.TransactWriteItems(context.Background(), in)
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) != 2 ||
+		aws.ToString(canceled.CancellationReasons[0].Code) != "None" || aws.ToString(canceled.CancellationReasons[1].Code) != "ConditionalCheckFailed" {
+		t.Fatalf("expected per-item cancellation reasons, got %v", err)
+	}
+	if f.Item("USER#U1", "USER#U1") != nil || f.Len() != 1 {
+		t.Fatalf("expected no writes from a canceled transaction")
+	}
+	in.TransactItems[1] = types.TransactWriteItem{Update: &types.Update{
+		TableName:                 aws.String("auth"),
+		Key:                       primaryKey("USER_EMAIL#a@example.com", "USER_EMAIL#a@example.com"),
+		UpdateExpression:          aws.String("SET userId = :new REMOVE legacy"),
+		ConditionExpression:       aws.String("userId = :old"),
+		ExpressionAttributeValues: item{":old": s("U0"), ":new": s("U1")},
+	}}
+	if _, err := f.TransactWriteItems(context.Background(), in); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if guard := f.Item("USER_EMAIL#a@example.com", "USER_EMAIL#a@example.com"); stringAttr(guard, "userId") != "U1" || f.Len() != 2 {
+		t.Fatalf("expected both writes applied, got %v", guard)
+	}
+	in.TransactItems[1] = types.TransactWriteItem{Delete: &types.Delete{TableName: aws.String("auth"), Key: primaryKey("USER#U1", "USER#U1")}}
+	var api smithy.APIError
+	if _, err := f.TransactWriteItems(context.Background(), in); !errors.As(err, &api) || api.ErrorCode() != "ValidationException" {
+		t.Fatalf("expected ValidationException for two operations on one item, got %v", err)
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	for _, expr := range []string{"PK = :missing", "size(PK) > :v", "PK =", "(PK = :v", "#n = :v"} {
+		if _, err := parseCondition(expr, nil, item{":v": s("x")}); err == nil {
+			t.Fatalf("expected an error for %q", expr)
+		}
+	}
+	c, err := parseCondition("NOT (a = :v OR b <> :v) AND attribute_exists(c)", nil, item{":v": s("x")})
+	if err != nil || !c.eval(item{"a": s("y"), "b": s("x"), "c": s("z")}) || c.eval(item{"a": s("x"), "c": s("z")}) {
+		t.Fatalf("unexpected evaluation: %v", err)
+	}
+}
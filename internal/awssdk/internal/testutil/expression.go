@@ -0,0 +1,445 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// This file evaluates the subset of the DynamoDB expression language used by the dynamo package:
+// conditions built from AND, OR, NOT, parentheses, comparisons (= <> < <= > >=), attribute_exists,
+// attribute_not_exists and begins_with; SET/REMOVE update expressions; and projection lists. Paths are
+// top-level attribute names, given directly or through #name placeholders.
+
+type item = map[string]types.AttributeValue
+
+// condition is a parsed condition, key-condition or filter expression.
+type condition interface {
+	eval(it item) bool
+}
+
+// operand is an attribute path or a :value placeholder.
+type operand struct {
+	path  string
+	value types.AttributeValue
+}
+
+func (o operand) resolve(it item) (types.AttributeValue, bool) {
+	if o.value != nil {
+		return o.value, true
+	}
+	v, ok := it[o.path]
+	return v, ok
+}
+
+type andCond struct{ left, right condition }
+
+func (c andCond) eval(it item) bool { return c.left.eval(it) && c.right.eval(it) }
+
+type orCond struct{ left, right condition }
+
+func (c orCond) eval(it item) bool { return c.left.eval(it) || c.right.eval(it) }
+
+type notCond struct{ inner condition }
+
+func (c notCond) eval(it item) bool { return !c.inner.eval(it) }
+
+type existsCond struct {
+	path   string
+	exists bool
+}
+
+func (c existsCond) eval(it item) bool {
+	_, ok := it[c.path]
+	return ok == c.exists
+}
+
+type beginsWithCond struct{ subject, prefix operand }
+
+func (c beginsWithCond) eval(it item) bool {
+	s, ok1 := c.subject.resolve(it)
+	p, ok2 := c.prefix.resolve(it)
+	if !ok1 || !ok2 {
+		return false
+	}
+	ss, ok1 := s.(*types.AttributeValueMemberS)
+	ps, ok2 := p.(*types.AttributeValueMemberS)
+	return ok1 && ok2 && strings.HasPrefix(ss.Value, ps.Value)
+}
+
+type compareCond struct {
+	op          string
+	left, right operand
+}
+
+func (c compareCond) eval(it item) bool {
+	l, ok1 := c.left.resolve(it)
+	r, ok2 := c.right.resolve(it)
+	if !ok1 || !ok2 {
+		return false
+	}
+	switch c.op {
+	case "=":
+		return equalValues(l, r)
+	case "<>":
+		return !equalValues(l, r)
+	}
+	cmp, ok := compareValues(l, r)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func equalValues(a, b types.AttributeValue) bool {
+	if cmp, ok := compareValues(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders two scalar values of the same type (S, N or B).
+func compareValues(a, b types.AttributeValue) (int, bool) {
+	switch av := a.(type) {
+	case *types.AttributeValueMemberS:
+		if bv, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(av.Value, bv.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if bv, ok := b.(*types.AttributeValueMemberN); ok {
+			x, err1 := strconv.ParseFloat(av.Value, 64)
+			y, err2 := strconv.ParseFloat(bv.Value, 64)
+			if err1 == nil && err2 == nil {
+				switch {
+				case x < y:
+					return -1, true
+				case x > y:
+					return 1, true
+				}
+				return 0, true
+			}
+		}
+	case *types.AttributeValueMemberB:
+		if bv, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(av.Value, bv.Value), true
+		}
+	}
+	return 0, false
+}
+
+// exprParser is a recursive-descent parser over the tokens of one expression.
+type exprParser struct {
+	tokens []string
+	pos    int
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+func newExprParser(expr string, names map[string]string, values map[string]types.AttributeValue) (*exprParser, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens, names: names, values: values}, nil
+}
+
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("(),=", c):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '<' || c == '>':
+			if i+1 < len(expr) && (expr[i+1] == '=' || (c == '<' && expr[i+1] == '>')) {
+				tokens = append(tokens, expr[i:i+2])
+				i += 2
+			} else {
+				tokens = append(tokens, string(c))
+				i++
+			}
+		case isWordChar(c):
+			j := i
+			for j < len(expr) && isWordChar(rune(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isWordChar(c rune) bool {
+	return c == '_' || c == '#' || c == ':' || c == '-' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *exprParser) keyword(k string) bool {
+	if strings.EqualFold(p.peek(), k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected %q, got %q", t, got)
+	}
+	return nil
+}
+
+func (p *exprParser) done() error {
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("unexpected token %q", p.peek())
+	}
+	return nil
+}
+
+// path resolves an attribute name token, substituting #name placeholders.
+func (p *exprParser) path() (string, error) {
+	t := p.next()
+	switch {
+	case t == "" || strings.HasPrefix(t, ":") || strings.ContainsAny(t, "(),=<>"):
+		return "", fmt.Errorf("expected an attribute name, got %q", t)
+	case strings.HasPrefix(t, "#"):
+		name, ok := p.names[t]
+		if !ok {
+			return "", fmt.Errorf("an expression attribute name used in the document path is not defined; attribute name: %s", t)
+		}
+		return name, nil
+	}
+	return t, nil
+}
+
+func (p *exprParser) operand() (operand, error) {
+	if t := p.peek(); strings.HasPrefix(t, ":") {
+		p.pos++
+		v, ok := p.values[t]
+		if !ok {
+			return operand{}, fmt.Errorf("an expression attribute value used in expression is not defined; attribute value: %s", t)
+		}
+		return operand{value: v}, nil
+	}
+	path, err := p.path()
+	return operand{path: path}, err
+}
+
+func (p *exprParser) or() (condition, error) {
+	left, err := p.and()
+	for err == nil && p.keyword("OR") {
+		var right condition
+		right, err = p.and()
+		left = orCond{left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) and() (condition, error) {
+	left, err := p.not()
+	for err == nil && p.keyword("AND") {
+		var right condition
+		right, err = p.not()
+		left = andCond{left, right}
+	}
+	return left, err
+}
+
+func (p *exprParser) not() (condition, error) {
+	if p.keyword("NOT") {
+		inner, err := p.not()
+		return notCond{inner}, err
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (condition, error) {
+	if p.peek() == "(" {
+		p.pos++
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return c, p.expect(")")
+	}
+	if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1] == "(" {
+		return p.function()
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	switch op {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("expected a comparator, got %q", op)
+	}
+	right, err := p.operand()
+	return compareCond{op: op, left: left, right: right}, err
+}
+
+func (p *exprParser) function() (condition, error) {
+	name := p.next()
+	p.pos++ // (
+	switch name {
+	case "attribute_exists", "attribute_not_exists":
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return existsCond{path: path, exists: name == "attribute_exists"}, p.expect(")")
+	case "begins_with":
+		subject, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		prefix, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return beginsWithCond{subject: subject, prefix: prefix}, p.expect(")")
+	}
+	return nil, fmt.Errorf("invalid function name; function: %s", name)
+}
+
+// parseCondition parses a condition, key-condition or filter expression; an empty expression matches
+// every item.
+func parseCondition(expr string, names map[string]string, values map[string]types.AttributeValue) (condition, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	p, err := newExprParser(expr, names, values)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	return c, p.done()
+}
+
+// update is a parsed update expression: SET assignments and REMOVE paths.
+type update struct {
+	set    map[string]operand
+	remove []string
+}
+
+func parseUpdate(expr string, names map[string]string, values map[string]types.AttributeValue) (update, error) {
+	u := update{set: map[string]operand{}}
+	p, err := newExprParser(expr, names, values)
+	if err != nil {
+		return u, err
+	}
+	if len(p.tokens) == 0 {
+		return u, fmt.Errorf("the update expression is empty")
+	}
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.keyword("SET"):
+			for {
+				path, err := p.path()
+				if err != nil {
+					return u, err
+				}
+				if err := p.expect("="); err != nil {
+					return u, err
+				}
+				v, err := p.operand()
+				if err != nil {
+					return u, err
+				}
+				u.set[path] = v
+				if p.peek() != "," {
+					break
+				}
+				p.pos++
+			}
+		case p.keyword("REMOVE"):
+			for {
+				path, err := p.path()
+				if err != nil {
+					return u, err
+				}
+				u.remove = append(u.remove, path)
+				if p.peek() != "," {
+					break
+				}
+				p.pos++
+			}
+		default:
+			return u, fmt.Errorf("unsupported update clause %q (only SET and REMOVE)", p.peek())
+		}
+	}
+	return u, nil
+}
+
+// apply returns a copy of it with the update applied; SET operands resolve against the original item.
+func (u update) apply(it item) item {
+	out := copyItem(it)
+	for path, v := range u.set {
+		if av, ok := v.resolve(it); ok {
+			out[path] = av
+		}
+	}
+	for _, path := range u.remove {
+		delete(out, path)
+	}
+	return out
+}
+
+// parseProjection resolves a comma-separated projection expression into attribute names.
+func parseProjection(expr string, names map[string]string) ([]string, error) {
+	p, err := newExprParser(expr, names, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, path)
+		if p.peek() != "," {
+			return out, p.done()
+		}
+		p.pos++
+	}
+}
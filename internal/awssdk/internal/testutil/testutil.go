@@ -12,13 +12,16 @@ import (
 
 // FakeDynamoTxnClient is a minimal fake for TransactWriteItems used in tests.
 type FakeDynamoTxnClient struct {
-	In  *dynamodb.TransactWriteItemsInput
-	Err error
+	// In is the most recent input; Inputs holds every input in call order.
+	In     *dynamodb.TransactWriteItemsInput
+	Inputs []*dynamodb.TransactWriteItemsInput
+	Err    error
 }
 
 // TransactWriteItems records the input and returns the configured error.
 func (f *FakeDynamoTxnClient) TransactWriteItems(_ context.Context, in *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
 	f.In = in
+	f.Inputs = append(f.Inputs, in)
 	return &dynamodb.TransactWriteItemsOutput{}, f.Err
 }
 
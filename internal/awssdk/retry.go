@@ -0,0 +1,73 @@
+package awssdk
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/utils/logging"
+)
+
+// RetryPolicy configures Retry. The zero value makes a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// BaseDelay is the backoff before the second attempt; it doubles for each further attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between attempts (no cap when zero).
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used for AWS calls that may be throttled.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// backoff returns the capped exponential delay after the given (1-based) attempt with full jitter,
+// i.e. a uniformly random duration in [0, min(MaxDelay, BaseDelay*2^(attempt-1))].
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// Retry calls op until it succeeds, returns an error that is not an awserrors.RetryableError, or
+// MaxAttempts is reached, sleeping with capped exponential backoff and jitter between attempts. It does
+// not sleep past the context deadline: when ctx ends (or would end before the next attempt) the last
+// error is returned joined with the context error. name identifies the operation in logs.
+func Retry(ctx context.Context, policy RetryPolicy, logger logging.Logger, name string, op func(context.Context) error) error {
+	if logger == nil {
+		logger = logging.NopLogger{}
+	}
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		var retryable *awserrors.RetryableError
+		if err == nil || !errors.As(err, &retryable) || attempt >= policy.MaxAttempts {
+			if err != nil && attempt > 1 {
+				logger.Warn("awssdk.retry.giveup", logging.Fields{"op": name, "attempts": attempt})
+			}
+			return err
+		}
+		delay := policy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			logger.Warn("awssdk.retry.deadline", logging.Fields{"op": name, "attempts": attempt})
+			return errors.Join(err, context.DeadlineExceeded)
+		}
+		logger.Info("awssdk.retry.attempt", logging.Fields{"op": name, "attempt": attempt, "delayMs": delay.Milliseconds()})
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
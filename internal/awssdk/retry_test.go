@@ -0,0 +1,72 @@
+package awssdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/internal/testutil"
+)
+
+func TestRetry_RetriesRetryableErrors(t *testing.T) {
+	l := &testutil.BufferLogger{}
+	calls := 0
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, l, "op", func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return &awserrors.RetryableError{Cause: errors.New("throttled")}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || len(l.Calls) != 2 {
+		t.Fatalf("err=%v calls=%d logs=%v", err, calls, l.Entries)
+	}
+}
+
+func TestRetry_StopsOnOtherErrorsAndExhaustion(t *testing.T) {
+	calls := 0
+	conflict := &awserrors.ConflictError{Cause: errors.New("taken")}
+	if err := Retry(context.Background(), DefaultRetryPolicy, nil, "op", func(context.Context) error { calls++; return conflict }); err != conflict || calls != 1 {
+		t.Fatalf("expected a single attempt, got err=%v calls=%d", err, calls)
+	}
+	calls = 0
+	var retryable *awserrors.RetryableError
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 2}, nil, "op", func(context.Context) error {
+		calls++
+		return &awserrors.RetryableError{Cause: errors.New("throttled")}
+	})
+	if !errors.As(err, &retryable) || calls != 2 {
+		t.Fatalf("expected exhaustion after 2 attempts, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestRetry_RespectsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	calls := 0
+	err := Retry(ctx, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}, nil, "op", func(context.Context) error {
+		calls++
+		return &awserrors.RetryableError{Cause: errors.New("throttled")}
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || calls != 1 {
+		t.Fatalf("expected deadline error after one attempt, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for attempt := 1; attempt <= 6; attempt++ {
+		max := p.BaseDelay << (attempt - 1)
+		if max > p.MaxDelay {
+			max = p.MaxDelay
+		}
+		if d := p.backoff(attempt); d < 0 || d > max {
+			t.Fatalf("attempt %d: backoff %v outside [0, %v]", attempt, d, max)
+		}
+	}
+	if d := (RetryPolicy{}).backoff(3); d != 0 {
+		t.Fatalf("zero policy should not delay, got %v", d)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
 )
 
 //go:embed assets/canaries/*.yaml
@@ -48,28 +49,113 @@ func toCanaryCase(c yamlCase) canaryCase {
 	}
 }
 
+// CanaryAPI is the subset of the Verified Permissions client used to execute canaries.
+type CanaryAPI interface {
+	IsAuthorized(context.Context, *vpapi.IsAuthorizedInput, ...func(*vpapi.Options)) (*vpapi.IsAuthorizedOutput, error)
+}
+
+// CanaryFailure describes one canary case that failed to execute or returned an unexpected decision.
+type CanaryFailure struct {
+	// Index is the 1-based position of the case in the combined canary list.
+	Index         int
+	PrincipalType string
+	PrincipalId   string
+	Action        string
+	ResourceType  string
+	ResourceId    string
+	Want          string
+	// Got is the decision returned by IsAuthorized; empty when Err is set.
+	Got string
+	Err error
+}
+
+func (f CanaryFailure) Error() string {
+	if f.Err != nil {
+		return fmt.Sprintf("canary #%d failed to execute: %v", f.Index, f.Err)
+	}
+	return fmt.Sprintf("canary #%d unexpected decision: got %s, want %s (principal=%s:%s, action=%s, resource=%s:%s)", f.Index, f.Got, f.Want, f.PrincipalType, f.PrincipalId, f.Action, f.ResourceType, f.ResourceId)
+}
+
+func (f CanaryFailure) Unwrap() error { return f.Err }
+
+// retryingCanaryAPI retries throttled IsAuthorized calls with awssdk.DefaultRetryPolicy.
+type retryingCanaryAPI struct {
+	api CanaryAPI
+}
+
+func (r retryingCanaryAPI) IsAuthorized(ctx context.Context, in *vpapi.IsAuthorizedInput, opts ...func(*vpapi.Options)) (*vpapi.IsAuthorizedOutput, error) {
+	var out *vpapi.IsAuthorizedOutput
+	err := awssdk.Retry(ctx, awssdk.DefaultRetryPolicy, nil, "verifiedpermissions.IsAuthorized", func(ctx context.Context) error {
+		var err error
+		out, err = r.api.IsAuthorized(ctx, in, opts...)
+		return awserrors.Classify(err)
+	})
+	return out, err
+}
+
+// ResolveCanaryFile returns the consumer canary file to use. An explicit path wins; otherwise
+// ./authorizer/canaries.yaml, then the legacy ./authorize/canaries.yaml, are used when present.
+// The boolean is false when no canary file applies, in which case canaries are skipped.
+func ResolveCanaryFile(explicit string) (string, bool) {
+	if strings.TrimSpace(explicit) != "" {
+		return explicit, true
+	}
+
+	newDef := "./authorizer/canaries.yaml"
+	oldDef := "./authorize/canaries.yaml"
+	if _, err := os.Stat(newDef); err == nil {
+		return newDef, true
+	}
+	if _, err := os.Stat(oldDef); err == nil {
+		return oldDef, true
+	}
+	return "", false
+}
+
 // RunCombinedCanaries merges provider-resident canaries with an optional consumer canary file
-// and executes them against the policy store. If agMode is "off", the action-enforcement
-// canaries are skipped.
+// and executes them against the policy store, retrying throttled calls. If agMode is "off", the
+// action-enforcement canaries are skipped. The first failing case is returned as the error.
 func RunCombinedCanaries(ctx context.Context, region string, policyStoreId string, consumerPath string, agMode string) error {
 	cfg, err := awssdk.LoadDefault(ctx, region)
 	if err != nil {
 		return err
 	}
-	client := vpapi.NewFromConfig(cfg)
+	failures, err := RunCanaries(ctx, vpapi.NewFromConfig(cfg), policyStoreId, consumerPath, agMode)
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return failures[0]
+	}
+	return nil
+}
 
+// RunCanaries executes the combined canary cases (see RunCombinedCanaries) and reports every
+// failing case. Throttled calls are retried, so callers can pass a plain client. The error is
+// reserved for problems loading the cases.
+func RunCanaries(ctx context.Context, client CanaryAPI, policyStoreId string, consumerPath string, agMode string) ([]CanaryFailure, error) {
 	allCases, err := loadCanaryCases(consumerPath, agMode)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if len(allCases) == 0 {
-		return nil
+	if _, ok := client.(retryingCanaryAPI); !ok {
+		client = retryingCanaryAPI{api: client}
 	}
+	var failures []CanaryFailure
 	for i, c := range allCases {
 		p := vpapiTypes.EntityIdentifier{EntityType: &c.PrincipalType, EntityId: &c.PrincipalId}
 		r := vpapiTypes.EntityIdentifier{EntityType: &c.ResourceType, EntityId: &c.ResourceId}
 		act := c.Action
 		actionType := "Action"
+		failure := CanaryFailure{
+			Index:         i + 1,
+			PrincipalType: c.PrincipalType,
+			PrincipalId:   c.PrincipalId,
+			Action:        c.Action,
+			ResourceType:  c.ResourceType,
+			ResourceId:    c.ResourceId,
+			Want:          c.Expect,
+		}
 		out, err := client.IsAuthorized(ctx, &vpapi.IsAuthorizedInput{
 			PolicyStoreId: &policyStoreId,
 			Principal:     &p,
@@ -77,14 +163,16 @@ func RunCombinedCanaries(ctx context.Context, region string, policyStoreId strin
 			Action:        &vpapiTypes.ActionIdentifier{ActionType: &actionType, ActionId: &act},
 		})
 		if err != nil {
-			return fmt.Errorf("canary #%d failed to execute: %w", i+1, err)
+			failure.Err = err
+			failures = append(failures, failure)
+			continue
 		}
-		got := string(out.Decision)
-		if !strings.EqualFold(got, c.Expect) {
-			return fmt.Errorf("canary #%d unexpected decision: got %s, want %s (principal=%s:%s, action=%s, resource=%s:%s)", i+1, got, c.Expect, c.PrincipalType, c.PrincipalId, c.Action, c.ResourceType, c.ResourceId)
+		if got := string(out.Decision); !strings.EqualFold(got, c.Expect) {
+			failure.Got = got
+			failures = append(failures, failure)
 		}
 	}
-	return nil
+	return failures, nil
 }
 
 func loadCanaryCases(consumerPath string, agMode string) ([]canaryCase, error) {
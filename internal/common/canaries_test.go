@@ -0,0 +1,138 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	vpapi "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
+	vpapiTypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeCanaryAPI denies every request except actions listed in allow, and fails actions listed in fail.
+type fakeCanaryAPI struct {
+	allow map[string]bool
+	fail  map[string]bool
+	calls int
+}
+
+func (f *fakeCanaryAPI) IsAuthorized(_ context.Context, in *vpapi.IsAuthorizedInput, _ ...func(*vpapi.Options)) (*vpapi.IsAuthorizedOutput, error) {
+	f.calls++
+	act := *in.Action.ActionId
+	if f.fail[act] {
+		return nil, errors.New("boom")
+	}
+	if f.allow[act] {
+		return &vpapi.IsAuthorizedOutput{Decision: vpapiTypes.DecisionAllow}, nil
+	}
+	return &vpapi.IsAuthorizedOutput{Decision: vpapiTypes.DecisionDeny}, nil
+}
+
+func TestRunCanariesReportsEveryFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "canaries.yaml")
+	doc := `cases:
+  - principal: { entityType: "User", entityId: "u1" }
+    action: "ReadDoc"
+    resource: { entityType: "Tenant", entityId: "t1" }
+    expect: "ALLOW"
+  - principal: { entityType: "User", entityId: "u1" }
+    action: "WriteDoc"
+    resource: { entityType: "Tenant", entityId: "t1" }
+    expect: "ALLOW"
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeCanaryAPI{fail: map[string]bool{"WriteDoc": true}}
+	failures, err := RunCanaries(context.Background(), api, "ps", path, "error")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// two consumer cases plus the base and action-enforcement provider canaries
+	if api.calls != 4 {
+		t.Fatalf("expected 4 canary calls, got %d", api.calls)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("expected 2 failures, got %+v", failures)
+	}
+	if failures[0].Index != 1 || failures[0].Got != "DENY" || !strings.Contains(failures[0].Error(), "unexpected decision") {
+		t.Fatalf("unexpected first failure: %v", failures[0])
+	}
+	if failures[1].Index != 2 || failures[1].Err == nil || !strings.Contains(failures[1].Error(), "failed to execute") {
+		t.Fatalf("unexpected second failure: %v", failures[1])
+	}
+
+	api = &fakeCanaryAPI{allow: map[string]bool{"ReadDoc": true, "WriteDoc": true}}
+	failures, err = RunCanaries(context.Background(), api, "ps", path, "off")
+	if err != nil || len(failures) != 0 || api.calls != 3 {
+		t.Fatalf("expected passing run without action-enforcement canaries: failures=%v err=%v calls=%d", failures, err, api.calls)
+	}
+}
+
+// throttlingCanaryAPI throttles the first throttles calls and allows the rest.
+type throttlingCanaryAPI struct {
+	throttles int
+	calls     int
+}
+
+func (f *throttlingCanaryAPI) IsAuthorized(context.Context, *vpapi.IsAuthorizedInput, ...func(*vpapi.Options)) (*vpapi.IsAuthorizedOutput, error) {
+	f.calls++
+	if f.calls <= f.throttles {
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException"}
+	}
+	return &vpapi.IsAuthorizedOutput{Decision: vpapiTypes.DecisionAllow}, nil
+}
+
+func TestRunCanariesRetriesThrottling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "canaries.yaml")
+	doc := `cases:
+  - principal: { entityType: "User", entityId: "u1" }
+    action: "ReadDoc"
+    resource: { entityType: "Tenant", entityId: "t1" }
+    expect: "ALLOW"
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	api := &throttlingCanaryAPI{throttles: 1}
+	failures, err := RunCanaries(context.Background(), api, "ps", path, "off")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The throttled consumer case is retried once; the base provider canary expects DENY and fails.
+	if api.calls != 3 || len(failures) != 1 || failures[0].Index != 2 {
+		t.Fatalf("expected throttled canary to be retried: calls=%d failures=%v", api.calls, failures)
+	}
+}
+
+func TestResolveCanaryFile(t *testing.T) {
+	if got, ok := ResolveCanaryFile("custom.yaml"); !ok || got != "custom.yaml" {
+		t.Fatalf("explicit canary file not honored: %q %v", got, ok)
+	}
+	t.Chdir(t.TempDir())
+	if _, ok := ResolveCanaryFile(" "); ok {
+		t.Fatalf("expected no canary file without defaults present")
+	}
+	if err := os.MkdirAll("authorize", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("authorize/canaries.yaml", []byte("cases: []"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := ResolveCanaryFile(""); !ok || got != "./authorize/canaries.yaml" {
+		t.Fatalf("expected legacy default, got %q %v", got, ok)
+	}
+	if err := os.MkdirAll("authorizer", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("authorizer/canaries.yaml", []byte("cases: []"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := ResolveCanaryFile(""); !ok || got != "./authorizer/canaries.yaml" {
+		t.Fatalf("expected default, got %q %v", got, ok)
+	}
+}
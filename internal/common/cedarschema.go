@@ -0,0 +1,465 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cedar-policy/cedar-go/x/exp/schema"
+)
+
+// CedarSchemaExt is the file extension of schemas written in the Cedar natural (human-readable) syntax.
+const CedarSchemaExt = ".cedarschema"
+
+var (
+	cedarIdent = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+	cedarPath  = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*(::[_a-zA-Z][_a-zA-Z0-9]*)*$`)
+	// quotedNamespaceRe matches a namespace declared as a string, the one extension to the Cedar syntax.
+	quotedNamespaceRe = regexp.MustCompile(`(?m)^(\s*namespace\s+)"([^"\\\n]*)"`)
+)
+
+// quotedNamespacePlaceholder is the Cedar path a quoted namespace is parsed under before it is renamed.
+const quotedNamespacePlaceholder = "vpauthorizer_quoted_namespace_"
+
+// ParseCedarSchema parses a schema in the Cedar natural syntax with cedar-go's schema parser into the
+// Cedar JSON schema document (namespace → {entityTypes, actions, commonTypes}) that the YAML/JSON formats
+// decode to, along with the line of each declaration. filename prefixes syntax errors. Type references
+// are resolved to their JSON schema form. As an extension, a namespace that is not a Cedar path (e.g. the
+// kebab-case names this repo recommends) may be written as a string: namespace "my-app" { ... }.
+func ParseCedarSchema(filename string, src []byte) (map[string]any, SchemaPositions, error) {
+	var quoted []string
+	src = quotedNamespaceRe.ReplaceAllFunc(src, func(m []byte) []byte {
+		sub := quotedNamespaceRe.FindSubmatch(m)
+		quoted = append(quoted, string(sub[2]))
+		return fmt.Appendf(nil, "%s%s%d", sub[1], quotedNamespacePlaceholder, len(quoted)-1)
+	})
+
+	var s schema.Schema
+	s.SetFilename(filename)
+	if err := s.UnmarshalCedar(src); err != nil {
+		return nil, nil, err
+	}
+	raw, err := s.MarshalJSON()
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, err
+	}
+	for i, name := range quoted {
+		placeholder := fmt.Sprintf("%s%d", quotedNamespacePlaceholder, i)
+		if body, ok := doc[placeholder]; ok {
+			delete(doc, placeholder)
+			doc[name] = body
+		}
+	}
+	for ns, body := range doc {
+		if b, ok := body.(map[string]any); ok {
+			resolveCedarTypes(ns, b)
+		}
+	}
+	return doc, cedarDeclarationPositions(src), nil
+}
+
+// cedarDeclRe matches the start of an entity, action or common type declaration, after any annotations;
+// cedarDeclEnd ends its list of names.
+var (
+	cedarDeclRe  = regexp.MustCompile(`^\s*(?:@[_a-zA-Z][_a-zA-Z0-9]*(?:\("(?:[^"\\]|\\.)*"\))?\s*)*(entity|action|type)\s+(.*)$`)
+	cedarDeclEnd = regexp.MustCompile(`\s(?:in|appliesTo|enum|tags)\b|[=;{\[]`)
+)
+
+// cedarDeclarationPositions records the line of each declaration whose names start on the declaring
+// line. cedar-go's schema AST carries no positions, so problems inside a declaration are reported at it.
+func cedarDeclarationPositions(src []byte) SchemaPositions {
+	sections := map[string]string{"entity": "entityTypes", "action": "actions", "type": "commonTypes"}
+	pos := SchemaPositions{}
+	for i, line := range strings.Split(string(src), "\n") {
+		m := cedarDeclRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		names := m[2]
+		if loc := cedarDeclEnd.FindStringIndex(names); loc != nil {
+			names = names[:loc[0]]
+		}
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if name != "" {
+				pos[sections[m[1]]+"."+name] = i + 1
+			}
+		}
+	}
+	return pos
+}
+
+func quoteCedarString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u{%x}`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// resolveCedarTypes replaces the EntityOrCommon type references of namespace ns, in Cedar's lookup
+// order: common types, then entity types, then the built-in primitive and extension types. Names that
+// resolve to nothing stay EntityOrCommon so the validator reports them. Action references without a
+// type lose the empty type cedar-go writes for them.
+func resolveCedarTypes(ns string, body map[string]any) {
+	commons, _ := body["commonTypes"].(map[string]any)
+	entities, _ := body["entityTypes"].(map[string]any)
+	var walk func(v any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			if t["type"] == "EntityOrCommon" {
+				name, _ := t["name"].(string)
+				delete(t, "name")
+				for k, e := range resolveCedarTypeName(ns, name, commons, entities) {
+					t[k] = e
+				}
+			}
+			if refs, ok := t["memberOf"]; ok {
+				for _, r := range anyList(refs) {
+					if ref, ok := r.(map[string]any); ok && ref["type"] == "" {
+						delete(ref, "type")
+					}
+				}
+			}
+			for k, e := range t {
+				if k != "annotations" {
+					walk(e)
+				}
+			}
+		case []any:
+			for _, e := range t {
+				walk(e)
+			}
+		}
+	}
+	walk(body)
+}
+
+func resolveCedarTypeName(ns, name string, commons, entities map[string]any) map[string]any {
+	local := name
+	if ns != "" {
+		local = strings.TrimPrefix(name, ns+"::")
+	}
+	if _, ok := commons[local]; ok {
+		return map[string]any{"type": name}
+	}
+	if _, ok := entities[local]; ok {
+		return map[string]any{"type": "Entity", "name": name}
+	}
+	builtin := strings.TrimPrefix(name, "__cedar::")
+	switch {
+	case builtin == "String" || builtin == "Long":
+		return map[string]any{"type": builtin}
+	case builtin == "Bool":
+		return map[string]any{"type": "Boolean"}
+	case slices.Contains(extensionTypes, builtin):
+		return map[string]any{"type": "Extension", "name": builtin}
+	}
+	return map[string]any{"type": "EntityOrCommon", "name": name}
+}
+
+// FormatCedarSchema renders a Cedar JSON schema document (superset keys already pruned) in the Cedar
+// natural syntax. Declarations are sorted by name so the output is stable for review diffs. Constructs
+// the natural syntax cannot express (non-record entity shapes, additionalAttributes, annotations on
+// types and attributes, action attributes) are reported as errors. An appliesTo without principal or
+// resource types, which never applies, is replaced by a comment as the syntax cannot write it.
+func FormatCedarSchema(doc map[string]any) (string, error) {
+	var b strings.Builder
+	for i, ns := range sortedKeys(doc) {
+		body, ok := doc[ns].(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("schema namespace %q must map to an object", ns)
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		indent := ""
+		if ns != "" {
+			name := ns
+			if !cedarPath.MatchString(ns) {
+				name = quoteCedarString(ns)
+			}
+			fmt.Fprintf(&b, "namespace %s {\n", name)
+			indent = "  "
+		}
+		f := cedarFormatter{b: &b, indent: indent}
+		if err := f.namespace(body); err != nil {
+			return "", fmt.Errorf("namespace %q: %w", ns, err)
+		}
+		if ns != "" {
+			b.WriteString("}\n")
+		}
+	}
+	return b.String(), nil
+}
+
+type cedarFormatter struct {
+	b      *strings.Builder
+	indent string
+}
+
+func (f cedarFormatter) namespace(body map[string]any) error {
+	for _, k := range sortedKeys(body) {
+		if k != "commonTypes" && k != "entityTypes" && k != "actions" {
+			return fmt.Errorf("unknown section %q", k)
+		}
+	}
+	sections := []struct {
+		name string
+		decl func(name string, def map[string]any) error
+	}{
+		{"commonTypes", f.commonType},
+		{"entityTypes", f.entityType},
+		{"actions", f.action},
+	}
+	first := true
+	for _, s := range sections {
+		defs, _ := body[s.name].(map[string]any)
+		if len(defs) == 0 {
+			continue
+		}
+		if !first {
+			f.b.WriteString("\n")
+		}
+		first = false
+		for _, name := range sortedKeys(defs) {
+			def, ok := defs[name].(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.%s must be an object", s.name, name)
+			}
+			if err := s.decl(name, def); err != nil {
+				return fmt.Errorf("%s.%s: %w", s.name, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (f cedarFormatter) annotations(def map[string]any) error {
+	raw, ok := def["annotations"]
+	if !ok {
+		return nil
+	}
+	annotations, ok := raw.(map[string]any)
+	if !ok {
+		return fmt.Errorf("annotations must be an object")
+	}
+	for _, k := range sortedKeys(annotations) {
+		v, ok := annotations[k].(string)
+		if !ok || !cedarIdent.MatchString(k) {
+			return fmt.Errorf("annotation %q must have an identifier key and a string value", k)
+		}
+		fmt.Fprintf(f.b, "%s@%s(%s)\n", f.indent, k, quoteCedarString(v))
+	}
+	return nil
+}
+
+func (f cedarFormatter) commonType(name string, def map[string]any) error {
+	t, err := f.typeExpr(def, f.indent)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f.b, "%stype %s = %s;\n", f.indent, name, t)
+	return nil
+}
+
+func (f cedarFormatter) entityType(name string, def map[string]any) error {
+	if err := f.annotations(def); err != nil {
+		return err
+	}
+	fmt.Fprintf(f.b, "%sentity %s", f.indent, name)
+	if raw, ok := def["enum"]; ok {
+		values := make([]string, 0, len(anyList(raw)))
+		for _, v := range anyList(raw) {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("enum values must be strings")
+			}
+			values = append(values, quoteCedarString(s))
+		}
+		fmt.Fprintf(f.b, " enum [%s];\n", strings.Join(values, ", "))
+		return nil
+	}
+	if raw, ok := def["memberOfTypes"]; ok {
+		fmt.Fprintf(f.b, " in [%s]", strings.Join(stringList(raw), ", "))
+	}
+	if raw, ok := def["shape"]; ok {
+		shape, _ := raw.(map[string]any)
+		if shape["type"] != "Record" {
+			return fmt.Errorf("shape must be a Record type in the Cedar schema syntax")
+		}
+		t, err := f.typeExpr(shape, f.indent)
+		if err != nil {
+			return err
+		}
+		f.b.WriteString(" " + t)
+	}
+	if raw, ok := def["tags"]; ok {
+		t, err := f.typeExpr(raw, f.indent)
+		if err != nil {
+			return err
+		}
+		f.b.WriteString(" tags " + t)
+	}
+	f.b.WriteString(";\n")
+	return nil
+}
+
+func (f cedarFormatter) action(name string, def map[string]any) error {
+	if _, ok := def["attributes"]; ok {
+		return fmt.Errorf("action attributes cannot be expressed in the Cedar schema syntax")
+	}
+	applies, hasAppliesTo := def["appliesTo"].(map[string]any)
+	if _, ok := def["appliesTo"]; ok && !hasAppliesTo {
+		return fmt.Errorf("appliesTo must be an object")
+	}
+	// The Cedar syntax requires principal and resource types in appliesTo; without either the action
+	// never applies, which the syntax writes as an action without appliesTo.
+	if hasAppliesTo && (len(anyList(applies["principalTypes"])) == 0 || len(anyList(applies["resourceTypes"])) == 0) {
+		fmt.Fprintf(f.b, "%s// appliesTo omitted: without both principal and resource types the action never applies\n", f.indent)
+		hasAppliesTo = false
+	}
+	if err := f.annotations(def); err != nil {
+		return err
+	}
+	fmt.Fprintf(f.b, "%saction %s", f.indent, quoteCedarString(name))
+	if raw, ok := def["memberOf"]; ok {
+		refs := []string{}
+		for _, r := range anyList(raw) {
+			ref, _ := r.(map[string]any)
+			id, ok := ref["id"].(string)
+			if !ok {
+				return fmt.Errorf("memberOf entries must have an id")
+			}
+			if typ, ok := ref["type"].(string); ok {
+				refs = append(refs, typ+"::"+quoteCedarString(id))
+			} else {
+				refs = append(refs, quoteCedarString(id))
+			}
+		}
+		fmt.Fprintf(f.b, " in [%s]", strings.Join(refs, ", "))
+	}
+	if hasAppliesTo {
+		f.b.WriteString(" appliesTo {\n")
+		inner := f.indent + "  "
+		for _, k := range []struct{ key, kw string }{{"principalTypes", "principal"}, {"resourceTypes", "resource"}} {
+			if refs, ok := applies[k.key]; ok {
+				fmt.Fprintf(f.b, "%s%s: [%s],\n", inner, k.kw, strings.Join(stringList(refs), ", "))
+			}
+		}
+		if ctx, ok := applies["context"]; ok {
+			t, err := f.typeExpr(ctx, inner)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(f.b, "%scontext: %s,\n", inner, t)
+		}
+		f.b.WriteString(f.indent + "}")
+	}
+	f.b.WriteString(";\n")
+	return nil
+}
+
+// typeExpr renders a type; indent is the indentation of the line the type starts on.
+func (f cedarFormatter) typeExpr(raw any, indent string) (string, error) {
+	t, ok := raw.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("type must be an object")
+	}
+	if _, ok := t["annotations"]; ok {
+		return "", fmt.Errorf("type annotations cannot be expressed in the Cedar schema syntax")
+	}
+	typ, _ := t["type"].(string)
+	switch typ {
+	case "Boolean":
+		return "Bool", nil
+	case "Set":
+		elem, err := f.typeExpr(t["element"], indent)
+		if err != nil {
+			return "", err
+		}
+		return "Set<" + elem + ">", nil
+	case "Entity", "Extension", "EntityOrCommon":
+		name, _ := t["name"].(string)
+		if name == "" {
+			return "", fmt.Errorf("%s type requires a name", typ)
+		}
+		return name, nil
+	case "Record":
+		if _, ok := t["additionalAttributes"]; ok {
+			return "", fmt.Errorf("additionalAttributes cannot be expressed in the Cedar schema syntax")
+		}
+		attrs, _ := t["attributes"].(map[string]any)
+		if len(attrs) == 0 {
+			return "{}", nil
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		inner := indent + "  "
+		for _, name := range sortedKeys(attrs) {
+			attr, _ := attrs[name].(map[string]any)
+			at, err := f.typeExpr(attr, inner)
+			if err != nil {
+				return "", fmt.Errorf("attribute %s: %w", name, err)
+			}
+			key := name
+			if !cedarIdent.MatchString(key) {
+				key = quoteCedarString(key)
+			}
+			if attr["required"] == false {
+				key += "?"
+			}
+			fmt.Fprintf(&b, "%s%s: %s,\n", inner, key, at)
+		}
+		b.WriteString(indent + "}")
+		return b.String(), nil
+	case "":
+		return "", fmt.Errorf("missing type")
+	default:
+		// String, Long and common type references.
+		return typ, nil
+	}
+}
+
+// FormatSchemaFile loads a schema file (YAML, JSON or .cedarschema, including ADR-0004 partial
+// supersets) with LoadSchema and renders its Cedar JSON in the Cedar natural syntax.
+func FormatSchemaFile(schemaPath string) (string, error) {
+	s, err := LoadSchema(schemaPath)
+	if err != nil {
+		return "", err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(s.CedarJSON), &doc); err != nil {
+		return "", fmt.Errorf("failed to decode schema JSON: %w", err)
+	}
+	return FormatCedarSchema(doc)
+}
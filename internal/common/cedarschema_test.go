@@ -0,0 +1,233 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const naturalSchema = `// Example schema in the Cedar natural syntax
+namespace app {
+  type Address = { street: String, "zip code"?: String };
+
+  entity Tenant in [Tenant];
+  entity User in GlobalRole = {
+    email: String,
+    active: Bool,
+    home?: Address,
+    ip: ipaddr,
+    tenants: Set<Tenant>,
+  };
+  entity Role, GlobalRole;
+  entity TenantGrant in [Role, Tenant, User];
+  @doc("ticket resources")
+  entity Ticket in [Tenant] { owner: app::User } tags String;
+  entity Color enum ["red", "green"];
+
+  action "Get";
+  action GetTicket, "GetTicket2" in ["Get"] appliesTo {
+    principal: [User, TenantGrant],
+    resource: Ticket,
+    context: { requestId: String },
+  };
+  action FindTickets in [app::Action::"Get"] appliesTo { principal: User, resource: [Ticket] };
+  type AuditContext = { reason: __cedar::String };
+  action Audit in ["Get"] appliesTo { principal: User, resource: Tenant, context: AuditContext };
+}
+`
+
+func TestParseCedarSchema(t *testing.T) {
+	doc, pos, err := ParseCedarSchema("schema.cedarschema", []byte(naturalSchema))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var want map[string]any
+	if err := json.Unmarshal([]byte(`{"app": {
+		"commonTypes": {"Address": {"type": "Record", "attributes": {
+			"street": {"type": "String"}, "zip code": {"type": "String", "required": false}}},
+			"AuditContext": {"type": "Record", "attributes": {"reason": {"type": "String"}}}},
+		"entityTypes": {
+			"Tenant": {"memberOfTypes": ["Tenant"]},
+			"User": {"memberOfTypes": ["GlobalRole"], "shape": {"type": "Record", "attributes": {
+				"email": {"type": "String"},
+				"active": {"type": "Boolean"},
+				"home": {"type": "Address", "required": false},
+				"ip": {"type": "Extension", "name": "ipaddr"},
+				"tenants": {"type": "Set", "element": {"type": "Entity", "name": "Tenant"}}}}},
+			"Role": {}, "GlobalRole": {},
+			"TenantGrant": {"memberOfTypes": ["Role", "Tenant", "User"]},
+			"Ticket": {"annotations": {"doc": "ticket resources"}, "memberOfTypes": ["Tenant"],
+				"shape": {"type": "Record", "attributes": {"owner": {"type": "Entity", "name": "app::User"}}},
+				"tags": {"type": "String"}},
+			"Color": {"enum": ["red", "green"]}},
+		"actions": {
+			"Get": {},
+			"GetTicket": {"memberOf": [{"id": "Get"}], "appliesTo": {"principalTypes": ["User", "TenantGrant"],
+				"resourceTypes": ["Ticket"], "context": {"type": "Record", "attributes": {"requestId": {"type": "String"}}}}},
+			"GetTicket2": {"memberOf": [{"id": "Get"}], "appliesTo": {"principalTypes": ["User", "TenantGrant"],
+				"resourceTypes": ["Ticket"], "context": {"type": "Record", "attributes": {"requestId": {"type": "String"}}}}},
+			"FindTickets": {"memberOf": [{"id": "Get", "type": "app::Action"}],
+				"appliesTo": {"principalTypes": ["User"], "resourceTypes": ["Ticket"]}},
+			"Audit": {"memberOf": [{"id": "Get"}],
+				"appliesTo": {"principalTypes": ["User"], "resourceTypes": ["Tenant"], "context": {"type": "AuditContext"}}}}}}`), &want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := normalizeDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		t.Fatalf("unexpected document:\n%s", gotJSON)
+	}
+	// Problems inside a declaration are reported at the declaration's line.
+	for path, line := range map[string]int{
+		"commonTypes.Address":                  3,
+		"entityTypes.User.shape.attributes.ip": 6,
+		"entityTypes.GlobalRole":               13,
+		"entityTypes.Ticket":                   16,
+		"actions.GetTicket2":                   20,
+		"actions.GetTicket.appliesTo.context":  20,
+		"actions.FindTickets.memberOf[0]":      25,
+	} {
+		if got := pos.line(path); got != line {
+			t.Errorf("line of %s = %d, want %d", path, got, line)
+		}
+	}
+}
+
+func TestParseCedarSchemaErrors(t *testing.T) {
+	cases := map[string]string{
+		"namespace app {\n  entity User = String;\n}": "s.cedarschema:2:",
+		"namespace app {\n  entity User\n}":           "s.cedarschema:3:",
+		"namespace app {\n  entity \"User\";\n}":      "s.cedarschema:2:",
+		"namespace app {\n  entity User;":             "s.cedarschema:2:",
+		"namespace \"my-app\" {\n  entity ;\n}":       "s.cedarschema:2:",
+	}
+	for src, want := range cases {
+		if _, _, err := ParseCedarSchema("s.cedarschema", []byte(src)); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("ParseCedarSchema(%q) error = %v, want prefix %q", src, err, want)
+		}
+	}
+
+	doc, _, err := ParseCedarSchema("s.cedarschema", []byte("// namespace \"commented\" {}\nnamespace \"my-app\" {\n  entity User;\n}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := doc["my-app"]; !ok || len(doc) != 1 {
+		t.Fatalf("quoted namespace not restored: %v", doc)
+	}
+}
+
+func TestLoadSchemaCedarSchemaFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.cedarschema")
+	if err := os.WriteFile(path, []byte(naturalSchema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Namespace != "app" || len(s.Actions) != 5 || !strings.Contains(s.CedarJSON, `"memberOfTypes":["Tenant"]`) {
+		t.Fatalf("unexpected schema: %+v", s)
+	}
+	if _, err := EnforceActionGroups(s.ActionMemberOf, "error"); err != nil {
+		t.Fatalf("unexpected enforcement error: %v", err)
+	}
+
+	bad := strings.Replace(naturalSchema, "resource: Ticket,", "resource: Tickt,", 1)
+	if err := os.WriteFile(path, []byte(bad), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	want := `line 20: actions.GetTicket.appliesTo.resourceTypes[0]: unknown entity type "Tickt"`
+	if _, err := LoadSchema(path); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+}
+
+func TestFormatCedarSchemaRoundTrip(t *testing.T) {
+	for _, path := range []string{
+		filepath.Join("..", "..", "infra", "authorizer", "schema.yaml"),
+		filepath.Join(t.TempDir(), "schema.cedarschema"),
+	} {
+		if strings.HasSuffix(path, CedarSchemaExt) {
+			if err := os.WriteFile(path, []byte(naturalSchema), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s, err := LoadSchema(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		text, err := FormatSchemaFile(path)
+		if err != nil {
+			t.Fatalf("%s: format: %v", path, err)
+		}
+		parsed, _, err := ParseCedarSchema("formatted.cedarschema", []byte(text))
+		if err != nil {
+			t.Fatalf("%s: formatted schema does not parse: %v\n%s", path, err, text)
+		}
+		got, err := normalizeDocument(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want map[string]any
+		if err := json.Unmarshal([]byte(s.CedarJSON), &want); err != nil {
+			t.Fatal(err)
+		}
+		// Actions that never apply are written without appliesTo.
+		for _, a := range want[s.Namespace].(map[string]any)["actions"].(map[string]any) {
+			action := a.(map[string]any)
+			if applies, ok := action["appliesTo"].(map[string]any); ok && (applies["principalTypes"] == nil || applies["resourceTypes"] == nil) {
+				delete(action, "appliesTo")
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: round trip changed the schema:\n%s", path, text)
+		}
+	}
+}
+
+func TestFormatCedarSchema(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(`{"app": {
+		"entityTypes": {"User": {"memberOfTypes": ["Tenant"], "shape": {"type": "Record", "attributes": {
+			"name": {"type": "String"}, "tags": {"type": "Set", "element": {"type": "String"}, "required": false}}}},
+			"Tenant": {}},
+		"actions": {"Get": {}, "GetUser": {"memberOf": [{"id": "Get"}],
+			"appliesTo": {"principalTypes": ["User"], "resourceTypes": ["User"]}}}}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FormatCedarSchema(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `namespace app {
+  entity Tenant;
+  entity User in [Tenant] {
+    name: String,
+    tags?: Set<String>,
+  };
+
+  action "Get";
+  action "GetUser" in ["Get"] appliesTo {
+    principal: [User],
+    resource: [User],
+  };
+}
+`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	doc["app"].(map[string]any)["entityTypes"].(map[string]any)["Tenant"] = map[string]any{
+		"shape": map[string]any{"type": "Record", "attributes": map[string]any{}, "additionalAttributes": true},
+	}
+	if _, err := FormatCedarSchema(doc); err == nil || !strings.Contains(err.Error(), "additionalAttributes") {
+		t.Fatalf("expected additionalAttributes to be rejected, got %v", err)
+	}
+}
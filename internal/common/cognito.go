@@ -0,0 +1,163 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// signInAliasAttributes maps supported sign-in aliases to Cognito user pool attribute names.
+var signInAliasAttributes = map[string]string{
+	"email":             "email",
+	"phone":             "phone_number",
+	"preferredUsername": "preferred_username",
+}
+
+// DefaultSignInAliases applies when no sign-in aliases are configured.
+var DefaultSignInAliases = []string{"email"}
+
+// SignInAttributes is the user pool sign-in configuration derived from sign-in aliases. Exactly one of
+// UsernameAttributes and AliasAttributes is set, as Cognito does not allow both.
+type SignInAttributes struct {
+	// UsernameAttributes lets users sign in with email and/or phone number instead of a username.
+	UsernameAttributes []string
+	// AliasAttributes is used when preferredUsername is requested, which Cognito only supports as an alias.
+	AliasAttributes []string
+}
+
+// ResolveSignInAliases validates sign-in aliases (email, phone, preferredUsername; default email) and
+// returns the corresponding user pool attributes. username is intentionally not supported.
+func ResolveSignInAliases(aliases []string) (SignInAttributes, error) {
+	if len(aliases) == 0 {
+		aliases = DefaultSignInAliases
+	}
+	seen := map[string]bool{}
+	attrs := make([]string, 0, len(aliases))
+	preferred := false
+	for _, a := range aliases {
+		attr, ok := signInAliasAttributes[a]
+		if !ok {
+			return SignInAttributes{}, fmt.Errorf("cognito.signInAliases contains unsupported value %q (allowed: email, phone, preferredUsername)", a)
+		}
+		if seen[a] {
+			return SignInAttributes{}, fmt.Errorf("cognito.signInAliases contains duplicate value %q", a)
+		}
+		seen[a] = true
+		preferred = preferred || a == "preferredUsername"
+		attrs = append(attrs, attr)
+	}
+	if preferred {
+		return SignInAttributes{AliasAttributes: attrs}, nil
+	}
+	return SignInAttributes{UsernameAttributes: attrs}, nil
+}
+
+var allowedAuthFlows = map[string]bool{
+	"ALLOW_ADMIN_USER_PASSWORD_AUTH": true,
+	"ALLOW_CUSTOM_AUTH":              true,
+	"ALLOW_USER_PASSWORD_AUTH":       true,
+	"ALLOW_USER_SRP_AUTH":            true,
+	"ALLOW_REFRESH_TOKEN_AUTH":       true,
+	"ALLOW_USER_AUTH":                true,
+}
+
+var allowedOAuthFlows = map[string]bool{"code": true, "implicit": true, "client_credentials": true}
+
+// UserPoolClientSpec describes an app client to create on the user pool.
+type UserPoolClientSpec struct {
+	Name           string
+	AuthFlows      []string
+	OAuthFlows     []string
+	OAuthScopes    []string
+	CallbackURLs   []string
+	LogoutURLs     []string
+	GenerateSecret bool
+	// Token validity; zero keeps the Cognito default.
+	AccessTokenValidityMinutes int
+	IdTokenValidityMinutes     int
+	RefreshTokenValidityDays   int
+}
+
+// OAuthEnabled reports whether the client uses OAuth (scopes or callback URLs configured).
+func (c UserPoolClientSpec) OAuthEnabled() bool {
+	return len(c.OAuthScopes) > 0 || len(c.CallbackURLs) > 0
+}
+
+// EffectiveOAuthFlows returns the configured OAuth flows, defaulting to the authorization code flow
+// when OAuth is enabled.
+func (c UserPoolClientSpec) EffectiveOAuthFlows() []string {
+	if len(c.OAuthFlows) > 0 || !c.OAuthEnabled() {
+		return c.OAuthFlows
+	}
+	return []string{"code"}
+}
+
+// ValidateUserPoolClients checks app client definitions for unique names, known auth/OAuth flows,
+// callback URLs compatible with OAuth and token validity within Cognito limits.
+func ValidateUserPoolClients(clients []UserPoolClientSpec) error {
+	names := map[string]bool{}
+	for i, c := range clients {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			return fmt.Errorf("cognito.clients[%d].name is required", i)
+		}
+		if names[name] {
+			return fmt.Errorf("cognito.clients[%d].name %q is not unique", i, name)
+		}
+		names[name] = true
+		for _, f := range c.AuthFlows {
+			if !allowedAuthFlows[f] {
+				return fmt.Errorf("cognito.clients[%d].authFlows contains unsupported flow %q", i, f)
+			}
+		}
+		for _, f := range c.OAuthFlows {
+			if !allowedOAuthFlows[f] {
+				return fmt.Errorf("cognito.clients[%d].oauthFlows contains unsupported flow %q (allowed: code, implicit, client_credentials)", i, f)
+			}
+		}
+		if len(c.OAuthFlows) > 0 && len(c.OAuthScopes) == 0 {
+			return fmt.Errorf("cognito.clients[%d].oauthScopes is required when oauthFlows is set", i)
+		}
+		if len(c.OAuthScopes) > 0 && len(c.CallbackURLs) == 0 && !onlyClientCredentials(c.OAuthFlows) {
+			return fmt.Errorf("cognito.clients[%d].callbackUrls is required when oauthScopes is set", i)
+		}
+		for _, u := range append(append([]string{}, c.CallbackURLs...), c.LogoutURLs...) {
+			if err := validateRedirectURL(u); err != nil {
+				return fmt.Errorf("cognito.clients[%d]: %w", i, err)
+			}
+		}
+		if err := validateRange(c.AccessTokenValidityMinutes, 5, 1440, "accessTokenValidity (minutes)"); err != nil {
+			return fmt.Errorf("cognito.clients[%d].%w", i, err)
+		}
+		if err := validateRange(c.IdTokenValidityMinutes, 5, 1440, "idTokenValidity (minutes)"); err != nil {
+			return fmt.Errorf("cognito.clients[%d].%w", i, err)
+		}
+		if err := validateRange(c.RefreshTokenValidityDays, 1, 3650, "refreshTokenValidity (days)"); err != nil {
+			return fmt.Errorf("cognito.clients[%d].%w", i, err)
+		}
+	}
+	return nil
+}
+
+func onlyClientCredentials(flows []string) bool {
+	return len(flows) == 1 && flows[0] == "client_credentials"
+}
+
+// validateRedirectURL enforces Cognito's rule: HTTPS, except http://localhost for testing.
+func validateRedirectURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid callback/logout URL %q", raw)
+	}
+	if u.Scheme == "https" || (u.Scheme == "http" && u.Hostname() == "localhost") {
+		return nil
+	}
+	return fmt.Errorf("callback/logout URL %q must use https (http is only allowed for localhost)", raw)
+}
+
+func validateRange(v int, lo int, hi int, field string) error {
+	if v == 0 || (v >= lo && v <= hi) {
+		return nil
+	}
+	return fmt.Errorf("%s must be between %d and %d (got %d)", field, lo, hi, v)
+}
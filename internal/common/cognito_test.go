@@ -0,0 +1,54 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveSignInAliases(t *testing.T) {
+	got, err := ResolveSignInAliases(nil)
+	if err != nil || len(got.UsernameAttributes) != 1 || got.UsernameAttributes[0] != "email" || got.AliasAttributes != nil {
+		t.Fatalf("default aliases = %+v, %v", got, err)
+	}
+	got, err = ResolveSignInAliases([]string{"phone", "preferredUsername"})
+	if err != nil || got.UsernameAttributes != nil || strings.Join(got.AliasAttributes, ",") != "phone_number,preferred_username" {
+		t.Fatalf("preferredUsername aliases = %+v, %v", got, err)
+	}
+	for _, bad := range [][]string{{"username"}, {"email", "email"}} {
+		if _, err := ResolveSignInAliases(bad); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestValidateUserPoolClients(t *testing.T) {
+	ok := []UserPoolClientSpec{
+		{Name: "web", OAuthScopes: []string{"openid"}, CallbackURLs: []string{"https://app.example.com/cb", "http://localhost:3000/cb"}},
+		{Name: "svc", OAuthFlows: []string{"client_credentials"}, OAuthScopes: []string{"api/read"}, GenerateSecret: true},
+		{Name: "cli", AuthFlows: []string{"ALLOW_USER_SRP_AUTH"}, AccessTokenValidityMinutes: 60, RefreshTokenValidityDays: 30},
+	}
+	if err := ValidateUserPoolClients(ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := ok[0].EffectiveOAuthFlows(); len(got) != 1 || got[0] != "code" {
+		t.Fatalf("EffectiveOAuthFlows = %v, want [code]", got)
+	}
+	bad := map[string]UserPoolClientSpec{
+		"name is required":     {},
+		"unsupported flow":     {Name: "a", AuthFlows: []string{"USER_PASSWORD_AUTH"}},
+		"oauthScopes":          {Name: "a", OAuthFlows: []string{"code"}},
+		"callbackUrls":         {Name: "a", OAuthScopes: []string{"openid"}},
+		"must use https":       {Name: "a", CallbackURLs: []string{"http://app.example.com"}},
+		"accessTokenValidity":  {Name: "a", AccessTokenValidityMinutes: 2},
+		"refreshTokenValidity": {Name: "a", RefreshTokenValidityDays: 4000},
+	}
+	for want, c := range bad {
+		err := ValidateUserPoolClients([]UserPoolClientSpec{c})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+	if err := ValidateUserPoolClients([]UserPoolClientSpec{{Name: "a"}, {Name: "a"}}); err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"strings"
+)
+
+//go:embed assets/guardrails/*.cedar
+var guardrailFS embed.FS
+
+// GuardrailMarker prefixes the description of provider-managed guardrail policies, followed by
+// the guardrail name. It is distinct from PolicyMarker so consumer policy files can never collide
+// with guardrails.
+const GuardrailMarker = "vpauthorizer:guardrail:"
+
+// Guardrail is a provider-managed deny policy rendered for a namespace.
+type Guardrail struct {
+	// Name is the asset file name without extension (e.g., "base-global-actions").
+	Name      string
+	Statement string
+}
+
+// baseGuardrails are always installed. Each asset holds exactly one policy, as CreatePolicy accepts a
+// single statement per static policy.
+var baseGuardrails = []string{"base-global-actions", "base-tenant-actions"}
+
+// RenderGuardrails returns the guardrail policies for namespace with ${NAMESPACE} interpolated.
+// - Base guardrails are always included.
+// - The action-enforcement guardrail is included when agMode != "off".
+func RenderGuardrails(namespace string, agMode string) ([]Guardrail, error) {
+	names := append([]string{}, baseGuardrails...)
+	if !strings.EqualFold(agMode, "off") {
+		names = append(names, "action-enforcement")
+	}
+	out := make([]Guardrail, 0, len(names))
+	for _, n := range names {
+		f := "assets/guardrails/" + n + ".cedar"
+		b, err := guardrailFS.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedded guardrail %s: %w", f, err)
+		}
+		out = append(out, Guardrail{Name: n, Statement: strings.ReplaceAll(string(b), "${NAMESPACE}", namespace)})
+	}
+	return out, nil
+}
+
+// InstallGuardrails reconciles the guardrail policies in a store: the rendered guardrails are
+// created or updated, and guardrails no longer wanted (disabled, or action enforcement turned off)
+// are deleted. tracked holds previously recorded guardrail policy IDs keyed by name.
+func InstallGuardrails(ctx context.Context, client PolicyAPI, policyStoreId string, namespace string, agMode string, disabled bool, tracked map[string]string) (map[string]string, error) {
+	desired := map[string]string{}
+	if !disabled {
+		guardrails, err := RenderGuardrails(namespace, agMode)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range guardrails {
+			desired[g.Name] = g.Statement
+		}
+	}
+	ids, _, err := ReconcilePolicies(ctx, client, policyStoreId, GuardrailMarker, desired, tracked)
+	if err != nil {
+		return nil, fmt.Errorf("guardrail installation failed: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRenderGuardrails(t *testing.T) {
+	gs, err := RenderGuardrails("Acme", "error")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gs) != 3 || gs[0].Name != "base-global-actions" || gs[1].Name != "base-tenant-actions" || gs[2].Name != "action-enforcement" {
+		t.Fatalf("unexpected guardrails: %+v", gs)
+	}
+	statement := regexp.MustCompile(`\b(forbid|permit)\s*\(`)
+	for _, g := range gs {
+		if strings.Contains(g.Statement, "${NAMESPACE}") || !strings.Contains(g.Statement, `Acme::ActionGroup::"`) {
+			t.Fatalf("namespace not interpolated in %s", g.Name)
+		}
+		// CreatePolicy accepts exactly one policy per static statement.
+		if n := len(statement.FindAllString(g.Statement, -1)); n != 1 {
+			t.Fatalf("guardrail %s has %d policies, want 1", g.Name, n)
+		}
+	}
+	gs, err = RenderGuardrails("Acme", "OFF")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gs) != 2 || gs[0].Name != "base-global-actions" || gs[1].Name != "base-tenant-actions" {
+		t.Fatalf("expected only base guardrails when enforcement is off: %+v", gs)
+	}
+}
+
+func TestInstallGuardrails(t *testing.T) {
+	ctx := context.Background()
+	api := newFakePolicyAPI()
+	policyID := api.add("permit(principal, action, resource);", PolicyMarker+"base-global-actions")
+
+	ids, err := InstallGuardrails(ctx, api, "ps", "Acme", "error", false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 3 || ids["base-global-actions"] == policyID {
+		t.Fatalf("unexpected guardrail IDs: %v", ids)
+	}
+
+	ids, err = InstallGuardrails(ctx, api, "ps", "Acme", "off", false, ids)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := ids["action-enforcement"]; ok || len(api.policies) != 3 {
+		t.Fatalf("expected action-enforcement guardrail to be removed: %v", ids)
+	}
+
+	ids, err = InstallGuardrails(ctx, api, "ps", "Acme", "off", true, ids)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 0 || len(api.policies) != 1 {
+		t.Fatalf("expected guardrails to be removed when disabled: %v", ids)
+	}
+	if _, ok := api.policies[policyID]; !ok {
+		t.Fatalf("consumer policy must not be touched by guardrail installation")
+	}
+}
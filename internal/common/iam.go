@@ -0,0 +1,97 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AuthorizerRolePolicyName is the name of the inline policy attached to the authorizer Lambda role.
+const AuthorizerRolePolicyName = "authorizer-access"
+
+// authorizerVerifiedPermissionsActions are the Verified Permissions calls made by the authorizer Lambda.
+var authorizerVerifiedPermissionsActions = []string{
+	"verifiedpermissions:IsAuthorized",
+	"verifiedpermissions:IsAuthorizedWithToken",
+	"verifiedpermissions:BatchIsAuthorized",
+	"verifiedpermissions:GetPolicyStore",
+}
+
+// authorizerDynamoActions are the read-only DynamoDB calls made by the authorizer Lambda.
+var authorizerDynamoActions = []string{
+	"dynamodb:GetItem",
+	"dynamodb:Query",
+	"dynamodb:BatchGetItem",
+}
+
+// PolicyStatement is an IAM policy statement. Effect defaults to Allow.
+type PolicyStatement struct {
+	Sid      string   `json:"Sid,omitempty"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []PolicyStatement `json:"Statement"`
+}
+
+// ValidatePolicyStatements checks user-supplied statements: Effect must be Allow or Deny (empty means
+// Allow) and each statement needs at least one action and one resource.
+func ValidatePolicyStatements(statements []PolicyStatement) error {
+	for i, s := range statements {
+		if s.Effect != "" && s.Effect != "Allow" && s.Effect != "Deny" {
+			return fmt.Errorf("policy statement %d: effect must be Allow or Deny (got %q)", i, s.Effect)
+		}
+		if len(s.Action) == 0 {
+			return fmt.Errorf("policy statement %d: at least one action is required", i)
+		}
+		if len(s.Resource) == 0 {
+			return fmt.Errorf("policy statement %d: at least one resource is required", i)
+		}
+		for _, a := range s.Action {
+			if !strings.Contains(a, ":") && a != "*" {
+				return fmt.Errorf("policy statement %d: action %q must be of the form <service>:<action>", i, a)
+			}
+		}
+	}
+	return nil
+}
+
+// AuthorizerRolePolicy returns the least-privilege inline policy for the authorizer Lambda role:
+// authorization calls scoped to the policy store, read-only access to the auth table and its indexes,
+// followed by any extra statements (validated with ValidatePolicyStatements).
+func AuthorizerRolePolicy(policyStoreArn string, tableArn string, extra []PolicyStatement) (string, error) {
+	if err := ValidatePolicyStatements(extra); err != nil {
+		return "", err
+	}
+	doc := policyDocument{
+		Version: "2012-10-17",
+		Statement: []PolicyStatement{
+			{
+				Sid:      "VerifiedPermissionsAuthorize",
+				Effect:   "Allow",
+				Action:   authorizerVerifiedPermissionsActions,
+				Resource: []string{policyStoreArn},
+			},
+			{
+				Sid:      "AuthTableRead",
+				Effect:   "Allow",
+				Action:   authorizerDynamoActions,
+				Resource: []string{tableArn, tableArn + "/index/*"},
+			},
+		},
+	}
+	for _, s := range extra {
+		if s.Effect == "" {
+			s.Effect = "Allow"
+		}
+		doc.Statement = append(doc.Statement, s)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to render role policy: %w", err)
+	}
+	return string(b), nil
+}
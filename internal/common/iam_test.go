@@ -0,0 +1,45 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAuthorizerRolePolicy(t *testing.T) {
+	psArn := "arn:aws:verifiedpermissions::123456789012:policy-store/ps-1"
+	tableArn := "arn:aws:dynamodb:us-east-1:123456789012:table/vpa-auth"
+	out, err := AuthorizerRolePolicy(psArn, tableArn, []PolicyStatement{{Action: []string{"s3:GetObject"}, Resource: []string{"arn:aws:s3:::bucket/*"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc policyDocument
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid policy JSON: %v", err)
+	}
+	if len(doc.Statement) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(doc.Statement))
+	}
+	if doc.Statement[0].Resource[0] != psArn || len(doc.Statement[0].Action) != 4 {
+		t.Fatalf("unexpected verified permissions statement: %+v", doc.Statement[0])
+	}
+	if r := doc.Statement[1].Resource; len(r) != 2 || r[1] != tableArn+"/index/*" {
+		t.Fatalf("unexpected table resources: %v", r)
+	}
+	if doc.Statement[2].Effect != "Allow" {
+		t.Fatalf("expected extra statement to default to Allow, got %q", doc.Statement[2].Effect)
+	}
+}
+
+func TestValidatePolicyStatements(t *testing.T) {
+	bad := []PolicyStatement{
+		{Effect: "Maybe", Action: []string{"s3:GetObject"}, Resource: []string{"*"}},
+		{Resource: []string{"*"}},
+		{Action: []string{"s3:GetObject"}},
+		{Action: []string{"GetObject"}, Resource: []string{"*"}},
+	}
+	for _, s := range bad {
+		if err := ValidatePolicyStatements([]PolicyStatement{s}); err == nil {
+			t.Fatalf("expected error for %+v", s)
+		}
+	}
+}
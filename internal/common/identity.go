@@ -0,0 +1,26 @@
+package common
+
+import "strings"
+
+// DefaultPrincipalEntityType is the required principal entity type that Cognito identities map to
+// unless configured otherwise.
+const DefaultPrincipalEntityType = "User"
+
+// QualifyEntityType prefixes an unqualified entity type with the schema namespace. Types that already
+// contain a namespace ("Ns::Type"), and all types when no namespace is known, are returned as-is.
+func QualifyEntityType(namespace string, entityType string) string {
+	entityType = strings.TrimSpace(entityType)
+	if namespace == "" || entityType == "" || strings.Contains(entityType, "::") {
+		return entityType
+	}
+	return namespace + "::" + entityType
+}
+
+// IdentitySourceArn derives an identity source ARN from its policy store ARN
+// (arn:<partition>:verifiedpermissions::<account>:policy-store/<id>).
+func IdentitySourceArn(policyStoreArn string, identitySourceId string) string {
+	if policyStoreArn == "" || identitySourceId == "" {
+		return ""
+	}
+	return strings.Replace(policyStoreArn, ":policy-store/", ":identity-source/", 1) + "/" + identitySourceId
+}
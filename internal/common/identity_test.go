@@ -0,0 +1,27 @@
+package common
+
+import "testing"
+
+func TestQualifyEntityType(t *testing.T) {
+	cases := map[[2]string]string{
+		{"acme", "User"}:        "acme::User",
+		{"acme", "other::User"}: "other::User",
+		{"", "User"}:            "User",
+		{"acme", " "}:           "",
+	}
+	for in, want := range cases {
+		if got := QualifyEntityType(in[0], in[1]); got != want {
+			t.Fatalf("QualifyEntityType(%q, %q) = %q, want %q", in[0], in[1], got, want)
+		}
+	}
+}
+
+func TestIdentitySourceArn(t *testing.T) {
+	got := IdentitySourceArn("arn:aws:verifiedpermissions::123456789012:policy-store/PS1", "IS1")
+	if got != "arn:aws:verifiedpermissions::123456789012:identity-source/PS1/IS1" {
+		t.Fatalf("unexpected identity source ARN %q", got)
+	}
+	if IdentitySourceArn("", "IS1") != "" {
+		t.Fatalf("expected empty ARN without a policy store ARN")
+	}
+}
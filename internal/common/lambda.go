@@ -0,0 +1,153 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Lambda authorizer defaults shared by the Pulumi and Terraform providers.
+const (
+	DefaultLambdaMemorySize          = 128
+	DefaultLambdaReservedConcurrency = 1
+	DefaultLambdaTimeout             = 10
+	DefaultLogRetentionDays          = 14
+	DefaultLambdaLogLevel            = "INFO"
+	// LambdaLogFormat is the structured log format configured on the authorizer.
+	LambdaLogFormat = "JSON"
+
+	// PolicyStoreIdEnvVar is set by the providers and cannot be overridden.
+	PolicyStoreIdEnvVar = "POLICY_STORE_ID"
+	// LambdaAliasName is the alias that carries provisioned concurrency.
+	LambdaAliasName = "live"
+)
+
+// logRetentionDays are the retention periods accepted by CloudWatch Logs.
+var logRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// lambdaReservedEnvVars are set by the Lambda runtime and rejected by the Lambda API.
+var lambdaReservedEnvVars = map[string]bool{
+	"_HANDLER": true, "_X_AMZN_TRACE_ID": true, "AWS_DEFAULT_REGION": true, "AWS_REGION": true,
+	"AWS_EXECUTION_ENV": true, "AWS_LAMBDA_FUNCTION_NAME": true, "AWS_LAMBDA_FUNCTION_MEMORY_SIZE": true,
+	"AWS_LAMBDA_FUNCTION_VERSION": true, "AWS_LAMBDA_INITIALIZATION_TYPE": true, "AWS_LAMBDA_LOG_GROUP_NAME": true,
+	"AWS_LAMBDA_LOG_STREAM_NAME": true, "AWS_ACCESS_KEY": true, "AWS_ACCESS_KEY_ID": true,
+	"AWS_SECRET_ACCESS_KEY": true, "AWS_SESSION_TOKEN": true, "AWS_LAMBDA_RUNTIME_API": true,
+	"LAMBDA_TASK_ROOT": true, "LAMBDA_RUNTIME_DIR": true,
+}
+
+var envVarNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// lambdaLogLevels are the application log levels supported by Lambda JSON logging.
+var lambdaLogLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+var kmsKeyArnRe = regexp.MustCompile(`^arn:(aws|aws-us-gov|aws-cn):kms:[a-z0-9-]+:[0-9]{12}:key/.+$`)
+
+// LambdaOptions are the user-facing Lambda knobs; nil fields take the defaults above.
+type LambdaOptions struct {
+	MemorySize             *int
+	ReservedConcurrency    *int
+	ProvisionedConcurrency *int
+	Timeout                *int
+	LogRetentionDays       *int
+	// LogLevel is the application log level for JSON logs (default INFO).
+	LogLevel *string
+	// LogKmsKeyArn optionally encrypts the log group with a customer managed KMS key.
+	LogKmsKeyArn *string
+	Environment  map[string]string
+}
+
+// LambdaSettings are validated Lambda settings with defaults applied.
+type LambdaSettings struct {
+	MemorySize             int
+	ReservedConcurrency    int
+	ProvisionedConcurrency int
+	Timeout                int
+	LogRetentionDays       int
+	LogLevel               string
+	LogKmsKeyArn           string
+	Environment            map[string]string
+}
+
+// ResolveLambdaSettings applies defaults and validates Lambda options: memory 128-10240 MB, timeout 1-900
+// seconds, reserved concurrency of at least 1, provisioned concurrency not above reserved concurrency, a
+// CloudWatch-supported log retention, a known log level, a KMS key ARN and environment overrides that do
+// not shadow reserved variables.
+func ResolveLambdaSettings(o LambdaOptions) (LambdaSettings, error) {
+	s := LambdaSettings{
+		MemorySize:             intOr(o.MemorySize, DefaultLambdaMemorySize),
+		ReservedConcurrency:    intOr(o.ReservedConcurrency, DefaultLambdaReservedConcurrency),
+		ProvisionedConcurrency: intOr(o.ProvisionedConcurrency, 0),
+		Timeout:                intOr(o.Timeout, DefaultLambdaTimeout),
+		LogRetentionDays:       intOr(o.LogRetentionDays, DefaultLogRetentionDays),
+		LogLevel:               DefaultLambdaLogLevel,
+		Environment:            o.Environment,
+	}
+	if o.LogLevel != nil && *o.LogLevel != "" {
+		s.LogLevel = strings.ToUpper(*o.LogLevel)
+	}
+	if o.LogKmsKeyArn != nil {
+		s.LogKmsKeyArn = *o.LogKmsKeyArn
+	}
+	if s.MemorySize < 128 || s.MemorySize > 10240 {
+		return LambdaSettings{}, fmt.Errorf("memory size must be between 128 and 10240 MB (got %d)", s.MemorySize)
+	}
+	if s.Timeout < 1 || s.Timeout > 900 {
+		return LambdaSettings{}, fmt.Errorf("timeout must be between 1 and 900 seconds (got %d)", s.Timeout)
+	}
+	if s.ReservedConcurrency < 1 {
+		return LambdaSettings{}, fmt.Errorf("reserved concurrency must be at least 1 (got %d)", s.ReservedConcurrency)
+	}
+	if s.ProvisionedConcurrency < 0 {
+		return LambdaSettings{}, fmt.Errorf("provisioned concurrency must not be negative (got %d)", s.ProvisionedConcurrency)
+	}
+	if s.ProvisionedConcurrency > s.ReservedConcurrency {
+		return LambdaSettings{}, fmt.Errorf("provisioned concurrency (%d) must be <= reserved concurrency (%d)", s.ProvisionedConcurrency, s.ReservedConcurrency)
+	}
+	if !slices.Contains(logRetentionDays, s.LogRetentionDays) {
+		return LambdaSettings{}, fmt.Errorf("log retention %d days is not supported by CloudWatch Logs (allowed: %v)", s.LogRetentionDays, logRetentionDays)
+	}
+	if !slices.Contains(lambdaLogLevels, s.LogLevel) {
+		return LambdaSettings{}, fmt.Errorf("log level %q is not supported (allowed: %s)", s.LogLevel, strings.Join(lambdaLogLevels, ", "))
+	}
+	if s.LogKmsKeyArn != "" && !kmsKeyArnRe.MatchString(s.LogKmsKeyArn) {
+		return LambdaSettings{}, fmt.Errorf("log KMS key %q must be a KMS key ARN (arn:<partition>:kms:<region>:<account>:key/<id>)", s.LogKmsKeyArn)
+	}
+	keys := make([]string, 0, len(s.Environment))
+	for k := range s.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k == PolicyStoreIdEnvVar || lambdaReservedEnvVars[k] {
+			return LambdaSettings{}, fmt.Errorf("environment variable %s is reserved and cannot be overridden", k)
+		}
+		if !envVarNameRe.MatchString(k) {
+			return LambdaSettings{}, fmt.Errorf("environment variable name %q must start with a letter and contain only letters, digits and '_'", k)
+		}
+	}
+	return s, nil
+}
+
+// EnvironmentVariables returns the environment overrides plus POLICY_STORE_ID.
+func (s LambdaSettings) EnvironmentVariables(policyStoreId string) map[string]string {
+	out := make(map[string]string, len(s.Environment)+1)
+	for k, v := range s.Environment {
+		out[k] = v
+	}
+	out[PolicyStoreIdEnvVar] = policyStoreId
+	return out
+}
+
+// LogGroupName is the CloudWatch log group Lambda writes to for functionName.
+func LogGroupName(functionName string) string {
+	return "/aws/lambda/" + functionName
+}
+
+func intOr(p *int, def int) int {
+	if p == nil {
+		return def
+	}
+	return *p
+}
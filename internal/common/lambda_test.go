@@ -0,0 +1,52 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveLambdaSettings(t *testing.T) {
+	s, err := ResolveLambdaSettings(LambdaOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.MemorySize != 128 || s.ReservedConcurrency != 1 || s.ProvisionedConcurrency != 0 || s.Timeout != 10 || s.LogRetentionDays != 14 || s.LogLevel != "INFO" {
+		t.Fatalf("unexpected defaults: %+v", s)
+	}
+	env := s.EnvironmentVariables("ps-1")
+	if len(env) != 1 || env[PolicyStoreIdEnvVar] != "ps-1" {
+		t.Fatalf("unexpected environment: %v", env)
+	}
+
+	n := func(v int) *int { return &v }
+	badLevel, badKey := "VERBOSE", "alias/logs"
+	bad := map[string]LambdaOptions{
+		"memory size":          {MemorySize: n(64)},
+		"timeout":              {Timeout: n(901)},
+		"reserved concurrency": {ReservedConcurrency: n(0)},
+		"must be <=":           {ReservedConcurrency: n(2), ProvisionedConcurrency: n(3)},
+		"log retention":        {LogRetentionDays: n(10)},
+		"log level":            {LogLevel: &badLevel},
+		"KMS key ARN":          {LogKmsKeyArn: &badKey},
+		"POLICY_STORE_ID":      {Environment: map[string]string{"POLICY_STORE_ID": "x"}},
+		"AWS_REGION":           {Environment: map[string]string{"AWS_REGION": "x"}},
+		"must start":           {Environment: map[string]string{"1BAD": "x"}},
+	}
+	for want, o := range bad {
+		_, err := ResolveLambdaSettings(o)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestResolveLambdaSettingsLogging(t *testing.T) {
+	level, key := "debug", "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	s, err := ResolveLambdaSettings(LambdaOptions{LogLevel: &level, LogKmsKeyArn: &key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.LogLevel != "DEBUG" || s.LogKmsKeyArn != key {
+		t.Fatalf("unexpected logging settings: %+v", s)
+	}
+}
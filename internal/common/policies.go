@@ -0,0 +1,251 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	vpapi "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
+	vpapiTypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+)
+
+// PolicyMarker prefixes the description of every static policy created from a policy file,
+// followed by the file path relative to the policy directory. Reconciliation uses it to
+// recognize provider-owned policies that are missing from state (e.g., after import).
+const PolicyMarker = "vpauthorizer:policy:"
+
+// maxPolicyDescriptionLength is the Verified Permissions limit for static policy descriptions.
+const maxPolicyDescriptionLength = 150
+
+// PolicyAPI is the subset of the Verified Permissions client used to reconcile static policies.
+type PolicyAPI interface {
+	ListPolicies(context.Context, *vpapi.ListPoliciesInput, ...func(*vpapi.Options)) (*vpapi.ListPoliciesOutput, error)
+	GetPolicy(context.Context, *vpapi.GetPolicyInput, ...func(*vpapi.Options)) (*vpapi.GetPolicyOutput, error)
+	CreatePolicy(context.Context, *vpapi.CreatePolicyInput, ...func(*vpapi.Options)) (*vpapi.CreatePolicyOutput, error)
+	UpdatePolicy(context.Context, *vpapi.UpdatePolicyInput, ...func(*vpapi.Options)) (*vpapi.UpdatePolicyOutput, error)
+	DeletePolicy(context.Context, *vpapi.DeletePolicyInput, ...func(*vpapi.Options)) (*vpapi.DeletePolicyOutput, error)
+}
+
+// PolicyChanges summarizes the keys touched by a reconciliation run.
+type PolicyChanges struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+// LoadPolicyFiles reads every .cedar file under dir and returns statements keyed by
+// slash-separated path relative to dir.
+func LoadPolicyFiles(dir string) (map[string]string, error) {
+	files, err := CollectPolicyFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(files))
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy %s: %w", f, err)
+		}
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			return nil, err
+		}
+		out[filepath.ToSlash(rel)] = string(b)
+	}
+	return out, nil
+}
+
+// ReconcilePolicies makes the static policies owned under marker match desired (key → Cedar statement).
+// Ownership is the union of tracked (key → policy ID, typically from state) and policies whose
+// description is marker+key. Missing policies are created, changed statements are updated in place
+// (or recreated when AVP rejects an in-place update), and owned policies without a desired key are
+// deleted. It returns the resulting key → policy ID map.
+func ReconcilePolicies(ctx context.Context, client PolicyAPI, policyStoreId string, marker string, desired map[string]string, tracked map[string]string) (map[string]string, PolicyChanges, error) {
+	var changes PolicyChanges
+	owned, orphans, err := ownedPolicies(ctx, client, policyStoreId, marker, tracked)
+	if err != nil {
+		return nil, changes, err
+	}
+
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ids := make(map[string]string, len(desired))
+	for _, key := range keys {
+		stmt := desired[key]
+		desc := marker + key
+		if len(desc) > maxPolicyDescriptionLength {
+			return nil, changes, fmt.Errorf("policy key %q is too long to record in the policy description (max %d characters including %q)", key, maxPolicyDescriptionLength, marker)
+		}
+		id, ok := owned[key]
+		if !ok {
+			newID, err := createStaticPolicy(ctx, client, policyStoreId, stmt, desc)
+			if err != nil {
+				return nil, changes, fmt.Errorf("failed to create policy %s: %w", key, err)
+			}
+			ids[key] = newID
+			changes.Created = append(changes.Created, key)
+			continue
+		}
+		newID, updated, err := syncStaticPolicy(ctx, client, policyStoreId, id, stmt, desc)
+		if err != nil {
+			return nil, changes, fmt.Errorf("failed to update policy %s: %w", key, err)
+		}
+		ids[key] = newID
+		if updated {
+			changes.Updated = append(changes.Updated, key)
+		}
+	}
+
+	for key, id := range owned {
+		if _, ok := desired[key]; !ok {
+			orphans[id] = key
+		}
+	}
+	orphanIDs := make([]string, 0, len(orphans))
+	for id := range orphans {
+		orphanIDs = append(orphanIDs, id)
+	}
+	sort.Strings(orphanIDs)
+	for _, id := range orphanIDs {
+		if err := deletePolicy(ctx, client, policyStoreId, id); err != nil {
+			return nil, changes, fmt.Errorf("failed to delete policy %s: %w", orphans[id], err)
+		}
+		changes.Deleted = append(changes.Deleted, orphans[id])
+	}
+	return ids, changes, nil
+}
+
+// ownedPolicies lists the store and returns key → policy ID for owned policies that still exist,
+// plus duplicate IDs (policy ID → key) that should be deleted.
+func ownedPolicies(ctx context.Context, client PolicyAPI, policyStoreId string, marker string, tracked map[string]string) (map[string]string, map[string]string, error) {
+	existing := map[string]string{} // policy ID → description
+	p := vpapi.NewListPoliciesPaginator(client, &vpapi.ListPoliciesInput{PolicyStoreId: &policyStoreId})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list policies: %w", awserrors.Classify(err))
+		}
+		for _, item := range page.Policies {
+			if item.PolicyId == nil {
+				continue
+			}
+			desc := ""
+			if st, ok := item.Definition.(*vpapiTypes.PolicyDefinitionItemMemberStatic); ok && st.Value.Description != nil {
+				desc = *st.Value.Description
+			}
+			existing[*item.PolicyId] = desc
+		}
+	}
+
+	owned := map[string]string{}
+	orphans := map[string]string{}
+	for key, id := range tracked {
+		if _, ok := existing[id]; ok {
+			owned[key] = id
+		}
+	}
+	ids := make([]string, 0, len(existing))
+	for id := range existing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		desc := existing[id]
+		if !strings.HasPrefix(desc, marker) {
+			continue
+		}
+		key := strings.TrimPrefix(desc, marker)
+		switch cur, ok := owned[key]; {
+		case !ok:
+			owned[key] = id
+		case cur != id:
+			orphans[id] = key
+		}
+	}
+	return owned, orphans, nil
+}
+
+func createStaticPolicy(ctx context.Context, client PolicyAPI, policyStoreId string, statement string, description string) (string, error) {
+	out, err := client.CreatePolicy(ctx, &vpapi.CreatePolicyInput{
+		PolicyStoreId: &policyStoreId,
+		Definition: &vpapiTypes.PolicyDefinitionMemberStatic{Value: vpapiTypes.StaticPolicyDefinition{
+			Statement:   &statement,
+			Description: &description,
+		}},
+	})
+	if err != nil {
+		return "", awserrors.Classify(err)
+	}
+	if out.PolicyId == nil {
+		return "", errors.New("CreatePolicy returned no policy ID")
+	}
+	return *out.PolicyId, nil
+}
+
+// syncStaticPolicy updates an existing policy when its statement or description differs. AVP rejects
+// in-place changes to a static policy's effect or scope; those are applied by delete and recreate.
+func syncStaticPolicy(ctx context.Context, client PolicyAPI, policyStoreId string, policyId string, statement string, description string) (string, bool, error) {
+	cur, err := client.GetPolicy(ctx, &vpapi.GetPolicyInput{PolicyStoreId: &policyStoreId, PolicyId: &policyId})
+	if err != nil {
+		return "", false, awserrors.Classify(err)
+	}
+	if st, ok := cur.Definition.(*vpapiTypes.PolicyDefinitionDetailMemberStatic); ok {
+		if strings.TrimSpace(valueOf(st.Value.Statement)) == strings.TrimSpace(statement) && valueOf(st.Value.Description) == description {
+			return policyId, false, nil
+		}
+	}
+	_, err = client.UpdatePolicy(ctx, &vpapi.UpdatePolicyInput{
+		PolicyStoreId: &policyStoreId,
+		PolicyId:      &policyId,
+		Definition: &vpapiTypes.UpdatePolicyDefinitionMemberStatic{Value: vpapiTypes.UpdateStaticPolicyDefinition{
+			Statement:   &statement,
+			Description: &description,
+		}},
+	})
+	if err == nil {
+		return policyId, true, nil
+	}
+	err = awserrors.Classify(err)
+	var invalid *awserrors.ValidationError
+	if !errors.As(err, &invalid) {
+		return "", false, err
+	}
+	if err := deletePolicy(ctx, client, policyStoreId, policyId); err != nil {
+		return "", false, err
+	}
+	newID, err := createStaticPolicy(ctx, client, policyStoreId, statement, description)
+	if err != nil {
+		return "", false, err
+	}
+	return newID, true, nil
+}
+
+func deletePolicy(ctx context.Context, client PolicyAPI, policyStoreId string, policyId string) error {
+	_, err := client.DeletePolicy(ctx, &vpapi.DeletePolicyInput{PolicyStoreId: &policyStoreId, PolicyId: &policyId})
+	if err == nil {
+		return nil
+	}
+	err = awserrors.Classify(err)
+	var missing *awserrors.NotFoundError
+	if errors.As(err, &missing) {
+		return nil
+	}
+	return err
+}
+
+func valueOf(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
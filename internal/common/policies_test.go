@@ -0,0 +1,170 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vpapi "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
+	vpapiTypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+	"github.com/aws/smithy-go"
+)
+
+type fakePolicy struct {
+	statement   string
+	description string
+}
+
+// fakePolicyAPI is an in-memory policy store implementing PolicyAPI.
+type fakePolicyAPI struct {
+	policies       map[string]fakePolicy
+	next           int
+	rejectUpdates  bool
+	updateRequests int
+}
+
+func newFakePolicyAPI() *fakePolicyAPI {
+	return &fakePolicyAPI{policies: map[string]fakePolicy{}}
+}
+
+func (f *fakePolicyAPI) add(statement, description string) string {
+	f.next++
+	id := fmt.Sprintf("p-%d", f.next)
+	f.policies[id] = fakePolicy{statement: statement, description: description}
+	return id
+}
+
+func (f *fakePolicyAPI) ListPolicies(_ context.Context, _ *vpapi.ListPoliciesInput, _ ...func(*vpapi.Options)) (*vpapi.ListPoliciesOutput, error) {
+	out := &vpapi.ListPoliciesOutput{}
+	for id, p := range f.policies {
+		id, desc := id, p.description
+		out.Policies = append(out.Policies, vpapiTypes.PolicyItem{
+			PolicyId:   &id,
+			Definition: &vpapiTypes.PolicyDefinitionItemMemberStatic{Value: vpapiTypes.StaticPolicyDefinitionItem{Description: &desc}},
+		})
+	}
+	return out, nil
+}
+
+func (f *fakePolicyAPI) GetPolicy(_ context.Context, in *vpapi.GetPolicyInput, _ ...func(*vpapi.Options)) (*vpapi.GetPolicyOutput, error) {
+	p, ok := f.policies[*in.PolicyId]
+	if !ok {
+		return nil, &smithy.GenericAPIError{Code: "ResourceNotFoundException"}
+	}
+	return &vpapi.GetPolicyOutput{
+		PolicyId:   in.PolicyId,
+		Definition: &vpapiTypes.PolicyDefinitionDetailMemberStatic{Value: vpapiTypes.StaticPolicyDefinitionDetail{Statement: &p.statement, Description: &p.description}},
+	}, nil
+}
+
+func (f *fakePolicyAPI) CreatePolicy(_ context.Context, in *vpapi.CreatePolicyInput, _ ...func(*vpapi.Options)) (*vpapi.CreatePolicyOutput, error) {
+	def := in.Definition.(*vpapiTypes.PolicyDefinitionMemberStatic).Value
+	id := f.add(*def.Statement, *def.Description)
+	return &vpapi.CreatePolicyOutput{PolicyId: &id}, nil
+}
+
+func (f *fakePolicyAPI) UpdatePolicy(_ context.Context, in *vpapi.UpdatePolicyInput, _ ...func(*vpapi.Options)) (*vpapi.UpdatePolicyOutput, error) {
+	f.updateRequests++
+	if f.rejectUpdates {
+		return nil, &smithy.GenericAPIError{Code: "ValidationException"}
+	}
+	def := in.Definition.(*vpapiTypes.UpdatePolicyDefinitionMemberStatic).Value
+	f.policies[*in.PolicyId] = fakePolicy{statement: *def.Statement, description: *def.Description}
+	return &vpapi.UpdatePolicyOutput{PolicyId: in.PolicyId}, nil
+}
+
+func (f *fakePolicyAPI) DeletePolicy(_ context.Context, in *vpapi.DeletePolicyInput, _ ...func(*vpapi.Options)) (*vpapi.DeletePolicyOutput, error) {
+	if _, ok := f.policies[*in.PolicyId]; !ok {
+		return nil, &smithy.GenericAPIError{Code: "ResourceNotFoundException"}
+	}
+	delete(f.policies, *in.PolicyId)
+	return &vpapi.DeletePolicyOutput{}, nil
+}
+
+func TestReconcilePolicies(t *testing.T) {
+	ctx := context.Background()
+	api := newFakePolicyAPI()
+	foreign := api.add("permit(principal, action, resource);", "managed elsewhere")
+
+	ids, changes, err := ReconcilePolicies(ctx, api, "ps", PolicyMarker, map[string]string{
+		"a.cedar":     "permit(principal, action, resource);",
+		"dir/b.cedar": "forbid(principal, action, resource);",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 2 || len(changes.Created) != 2 || len(api.policies) != 3 {
+		t.Fatalf("expected two created policies, got ids=%v changes=%+v", ids, changes)
+	}
+
+	// Unchanged statements are left alone; changed ones are updated in place; removed files are deleted.
+	ids2, changes, err := ReconcilePolicies(ctx, api, "ps", PolicyMarker, map[string]string{
+		"a.cedar": "permit(principal, action, resource);\n",
+		"c.cedar": "permit(principal, action == Action::\"x\", resource);",
+	}, ids)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids2["a.cedar"] != ids["a.cedar"] || len(changes.Updated) != 0 {
+		t.Fatalf("expected a.cedar to be unchanged: %+v", changes)
+	}
+	if len(changes.Created) != 1 || len(changes.Deleted) != 1 || changes.Deleted[0] != "dir/b.cedar" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if _, ok := api.policies[foreign]; !ok {
+		t.Fatalf("policy without marker must not be deleted")
+	}
+
+	// Without tracked IDs, ownership is recovered from descriptions.
+	_, changes, err = ReconcilePolicies(ctx, api, "ps", PolicyMarker, map[string]string{
+		"a.cedar": "forbid(principal, action, resource);",
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes.Updated) != 1 || len(changes.Deleted) != 1 || len(changes.Created) != 0 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+}
+
+func TestReconcilePoliciesRecreatesOnValidationError(t *testing.T) {
+	ctx := context.Background()
+	api := newFakePolicyAPI()
+	id := api.add("permit(principal, action, resource);", PolicyMarker+"a.cedar")
+	api.rejectUpdates = true
+
+	ids, changes, err := ReconcilePolicies(ctx, api, "ps", PolicyMarker, map[string]string{
+		"a.cedar": "forbid(principal, action, resource);",
+	}, map[string]string{"a.cedar": id})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids["a.cedar"] == id || len(changes.Updated) != 1 || api.updateRequests != 1 {
+		t.Fatalf("expected policy to be recreated: ids=%v changes=%+v", ids, changes)
+	}
+	if got := api.policies[ids["a.cedar"]].statement; got != "forbid(principal, action, resource);" {
+		t.Fatalf("unexpected statement %q", got)
+	}
+}
+
+func TestLoadPolicyFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "p.cedar"), []byte("permit(principal, action, resource);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPolicyFiles(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got["nested/p.cedar"] == "" {
+		t.Fatalf("unexpected policy files: %v", got)
+	}
+}
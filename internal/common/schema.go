@@ -7,14 +7,17 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"unicode"
 
 	vpapi "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
 	vpapiTypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
 	"gopkg.in/yaml.v3"
 
 	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
 	"github.com/mikecbrant/verified-permissions-authorizer/internal/utils"
 )
 
@@ -22,59 +25,147 @@ var namespaceNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]+$`)
 
 var requiredPrincipals = []string{"Tenant", "User", "Role", "GlobalRole", "TenantGrant"}
 
+// Schema is a loaded and validated consumer schema file.
+type Schema struct {
+	MergedSchema
+	// Actions are the action names of the merged schema.
+	Actions []string
+	// ActionMemberOf maps every action to the action groups listed in its memberOf.
+	ActionMemberOf map[string][]string
+	Warnings       []string
+}
+
 // LoadAndValidateSchema parses a YAML/JSON Verified Permissions schema definition and returns
-// canonical JSON (minified), the namespace name, the set of action names, and any warnings.
+// canonical Cedar-only JSON (minified), the namespace name, the set of action names, and any warnings.
+// See LoadSchema.
 func LoadAndValidateSchema(schemaPath string) (cedarJSON string, namespace string, actions []string, warnings []string, err error) {
-	doc, err := loadSchemaDocument(schemaPath)
+	s, err := LoadSchema(schemaPath)
 	if err != nil {
 		return "", "", nil, nil, err
 	}
+	return s.CedarJSON, s.Namespace, s.Actions, s.Warnings, nil
+}
+
+// LoadSchema parses a YAML/JSON schema file. Shorthand action references are normalized
+// (normalizeActionRefs) and the resulting Cedar JSON is checked offline with ValidateCedarSchema, whose
+// problems carry line numbers from the file. A partial superset (one that uses superset extensions or
+// omits base principal types) is merged onto the base schema (MergeSchemaSuperset) and its
+// cross-references are checked (ValidateSuperset); a complete plain Cedar schema is used as is, so its
+// Cedar JSON is unchanged and equals its superset JSON. The Cedar JSON must fit the 100,000 byte
+// PutSchema limit.
+func LoadSchema(schemaPath string) (Schema, error) {
+	doc, pos, err := loadSchemaDocument(schemaPath)
+	if err != nil {
+		return Schema{}, err
+	}
 
 	top, ns, body, err := extractSingleNamespace(doc)
 	if err != nil {
-		return "", "", nil, nil, err
+		return Schema{}, err
 	}
+	normalizeActionRefs(body)
 
-	warnings = namespaceWarnings(ns)
+	var merged MergedSchema
+	if isPartialSuperset(top, body) {
+		base, err := BaseSchema(ns)
+		if err != nil {
+			return Schema{}, err
+		}
+		if merged, err = MergeSchemaSuperset(base, top); err != nil {
+			return Schema{}, fmt.Errorf("schema %s: %w", schemaPath, err)
+		}
+		var superset map[string]any
+		if err := json.Unmarshal([]byte(merged.SupersetJSON), &superset); err != nil {
+			return Schema{}, fmt.Errorf("failed to decode merged schema: %w", err)
+		}
+		if problems := ValidateSuperset(superset); len(problems) > 0 {
+			return Schema{}, fmt.Errorf("schema %s is invalid:\n  - %s", schemaPath, strings.Join(problems, "\n  - "))
+		}
+		body = superset[ns].(map[string]any)
+	} else if merged, err = plainSchema(ns, top); err != nil {
+		return Schema{}, err
+	}
 
 	acts, err := collectActionNames(body)
 	if err != nil {
-		return "", "", nil, nil, err
+		return Schema{}, err
 	}
 
 	if err := validateRequiredPrincipals(ns, body); err != nil {
-		return "", "", nil, nil, err
+		return Schema{}, err
 	}
 
-	cedarJSON, err = canonicalizeSchema(ns, top)
-	if err != nil {
-		return "", "", nil, nil, err
+	var cedar map[string]any
+	if err := json.Unmarshal([]byte(merged.CedarJSON), &cedar); err != nil {
+		return Schema{}, fmt.Errorf("failed to decode Cedar schema: %w", err)
+	}
+	if problems := ValidateCedarSchema(cedar, pos); len(problems) > 0 {
+		msgs := make([]string, 0, len(problems))
+		for _, p := range problems {
+			msgs = append(msgs, p.String())
+		}
+		return Schema{}, fmt.Errorf("schema %s is invalid:\n  - %s", schemaPath, strings.Join(msgs, "\n  - "))
 	}
 
-	return cedarJSON, ns, acts, warnings, nil
+	if sz := len(merged.CedarJSON); sz > 100000 {
+		return Schema{}, fmt.Errorf("schema JSON size %d exceeds 100,000 byte limit for namespace %q", sz, ns)
+	}
+
+	return Schema{MergedSchema: merged, Actions: acts, ActionMemberOf: actionMemberOf(body), Warnings: namespaceWarnings(ns)}, nil
+}
+
+// isPartialSuperset reports whether a consumer schema must be merged onto the base schema.
+func isPartialSuperset(top map[string]any, body map[string]any) bool {
+	if hasSupersetExtensions(top) {
+		return true
+	}
+	et, _ := body["entityTypes"].(map[string]any)
+	for _, p := range requiredPrincipals {
+		if _, ok := et[p]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// plainSchema encodes a complete Cedar schema, which has nothing to merge or prune.
+func plainSchema(ns string, top map[string]any) (MergedSchema, error) {
+	b, err := json.Marshal(top)
+	if err != nil {
+		return MergedSchema{}, fmt.Errorf("failed to encode schema for namespace %q as JSON: %w", ns, err)
+	}
+	return MergedSchema{Namespace: ns, SupersetJSON: string(b), CedarJSON: string(b)}, nil
 }
 
-func loadSchemaDocument(schemaPath string) (any, error) {
+// loadSchemaDocument decodes a schema file and records the line of each definition under its namespace.
+func loadSchemaDocument(schemaPath string) (any, SchemaPositions, error) {
 	raw, err := os.ReadFile(schemaPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read schema file %s: %w", schemaPath, err)
+		return nil, nil, fmt.Errorf("failed to read schema file %s: %w", schemaPath, err)
 	}
 
 	var doc any
 	switch strings.ToLower(filepath.Ext(schemaPath)) {
 	case ".yaml", ".yml":
 		if err := yaml.Unmarshal(raw, &doc); err != nil {
-			return nil, fmt.Errorf("invalid YAML in %s: %w", schemaPath, err)
+			return nil, nil, fmt.Errorf("invalid YAML in %s: %w", schemaPath, err)
 		}
 	case ".json":
 		if err := json.Unmarshal(raw, &doc); err != nil {
-			return nil, fmt.Errorf("invalid JSON in %s: %w", schemaPath, err)
+			return nil, nil, fmt.Errorf("invalid JSON in %s: %w", schemaPath, err)
+		}
+	case CedarSchemaExt:
+		parsed, pos, err := ParseCedarSchema(schemaPath, raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid Cedar schema: %w", err)
 		}
+		return parsed, pos, nil
 	default:
-		return nil, fmt.Errorf("unsupported schema extension %q; expected .yaml, .yml, or .json", filepath.Ext(schemaPath))
+		return nil, nil, fmt.Errorf("unsupported schema extension %q; expected .yaml, .yml, .json, or .cedarschema", filepath.Ext(schemaPath))
 	}
 
-	return doc, nil
+	// JSON is a subset of YAML, so the YAML parser yields line numbers for both formats.
+	return doc, schemaPositions(raw), nil
 }
 
 func extractSingleNamespace(doc any) (top map[string]any, ns string, body map[string]any, err error) {
@@ -126,6 +217,24 @@ func validateRequiredPrincipals(ns string, body map[string]any) error {
 	return nil
 }
 
+// normalizeActionRefs rewrites the shorthand `memberOf: [Get]` used in authored schemas to the Cedar JSON
+// form `memberOf: [{id: Get}]`, which is what PutSchema accepts.
+func normalizeActionRefs(body map[string]any) {
+	actions, _ := body["actions"].(map[string]any)
+	for _, a := range actions {
+		def, _ := a.(map[string]any)
+		refs, ok := def["memberOf"].([]any)
+		if !ok {
+			continue
+		}
+		for i, r := range refs {
+			if id, ok := r.(string); ok {
+				refs[i] = map[string]any{"id": id}
+			}
+		}
+	}
+}
+
 func collectActionNames(body map[string]any) ([]string, error) {
 	acts := []string{}
 	aRaw, ok := body["actions"]
@@ -142,15 +251,22 @@ func collectActionNames(body map[string]any) ([]string, error) {
 	return acts, nil
 }
 
-func canonicalizeSchema(ns string, top map[string]any) (string, error) {
-	b, err := json.Marshal(top)
-	if err != nil {
-		return "", fmt.Errorf("failed to encode schema for namespace %q as JSON: %w", ns, err)
-	}
-	if sz := len(b); sz > 100000 {
-		return "", fmt.Errorf("schema JSON size %d exceeds 100,000 byte limit for namespace %q", sz, ns)
+// actionMemberOf returns the memberOf action IDs of every action in body (after normalizeActionRefs).
+func actionMemberOf(body map[string]any) map[string][]string {
+	out := map[string][]string{}
+	actions, _ := body["actions"].(map[string]any)
+	for name, a := range actions {
+		def, _ := a.(map[string]any)
+		out[name] = []string{}
+		for _, r := range anyList(def["memberOf"]) {
+			if ref, ok := r.(map[string]any); ok {
+				if id, ok := ref["id"].(string); ok {
+					out[name] = append(out[name], id)
+				}
+			}
+		}
 	}
-	return string(b), nil
+	return out
 }
 
 // Canonical action group identifiers (PascalCase + Global* variants)
@@ -159,32 +275,99 @@ var canonicalActionGroups = []string{
 	"GlobalBatchCreate", "GlobalCreate", "GlobalBatchDelete", "GlobalDelete", "GlobalFind", "GlobalGet", "GlobalBatchUpdate", "GlobalUpdate",
 }
 
-// EnforceActionGroups checks action names map to canonical groups via exact, case-sensitive prefixes.
-// mode: "off" | "warn" | "error". Returns violating action names and (when mode==error) an error.
-func EnforceActionGroups(actions []string, mode string) ([]string, error) {
+// ActionGroupReport is the outcome of EnforceActionGroups.
+type ActionGroupReport struct {
+	// Violations describe leaf actions that do not belong to exactly one canonical action group and
+	// canonical groups that are referenced but not declared as actions.
+	Violations []string
+	// NameMismatches describe leaf actions named for one canonical group (e.g. FindTickets) that belong to
+	// another. They are warnings in every mode but off.
+	NameMismatches []string
+}
+
+// EnforceActionGroups walks the memberOf graph of a schema's actions (Schema.ActionMemberOf) and
+// requires every leaf action (one that no other action lists in memberOf, other than the canonical
+// groups themselves) to belong, directly or transitively, to exactly one canonical action group, which
+// the guardrails rely on. mode: "off" | "warn" | "error"; in error mode any violation is also returned
+// as an error.
+func EnforceActionGroups(memberOf map[string][]string, mode string) (ActionGroupReport, error) {
+	var report ActionGroupReport
 	if strings.EqualFold(mode, "off") {
-		return nil, nil
+		return report, nil
 	}
-	bad := []string{}
-	for _, a := range actions {
-		ok := false
-		for _, g := range canonicalActionGroups {
-			if strings.HasPrefix(a, g) {
-				ok = true
-				break
-			}
+	referenced := map[string]bool{}
+	for _, groups := range memberOf {
+		for _, g := range groups {
+			referenced[g] = true
 		}
-		if !ok {
-			bad = append(bad, a)
+	}
+	for _, g := range canonicalActionGroups {
+		if _, declared := memberOf[g]; referenced[g] && !declared {
+			report.Violations = append(report.Violations, fmt.Sprintf("canonical action group %s is not declared as an action", g))
 		}
 	}
-	if len(bad) == 0 {
-		return nil, nil
+	for _, a := range sortedKeys(memberOf) {
+		if referenced[a] || slices.Contains(canonicalActionGroups, a) {
+			continue
+		}
+		groups := canonicalGroupsOf(a, memberOf)
+		switch {
+		case len(groups) == 0:
+			report.Violations = append(report.Violations, fmt.Sprintf("%s belongs to no canonical action group", a))
+		case len(groups) > 1:
+			report.Violations = append(report.Violations, fmt.Sprintf("%s belongs to multiple canonical action groups: %s", a, strings.Join(groups, ", ")))
+		default:
+			if named := actionNameGroup(a); named != "" && named != groups[0] {
+				report.NameMismatches = append(report.NameMismatches, fmt.Sprintf("%s is named for %s but belongs to %s", a, named, groups[0]))
+			}
+		}
+	}
+	if len(report.Violations) > 0 && mode == "error" {
+		return report, fmt.Errorf("actions not aligned to canonical action groups %v: %s", canonicalActionGroups, strings.Join(report.Violations, "; "))
+	}
+	return report, nil
+}
+
+// canonicalGroupsOf returns the canonical groups reachable from action through memberOf, sorted.
+func canonicalGroupsOf(action string, memberOf map[string][]string) []string {
+	seen := map[string]bool{action: true}
+	found := map[string]bool{}
+	queue := []string{action}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, g := range memberOf[cur] {
+			if slices.Contains(canonicalActionGroups, g) {
+				found[g] = true
+			}
+			if !seen[g] {
+				seen[g] = true
+				queue = append(queue, g)
+			}
+		}
 	}
-	if mode == "error" {
-		return bad, fmt.Errorf("actions not aligned to canonical action groups %v: %s", canonicalActionGroups, strings.Join(bad, ", "))
+	return sortedKeys(found)
+}
+
+// actionNameGroup returns the longest canonical group that prefixes action at a word boundary
+// (GetTicket → Get, GetawayBooking → none), or "".
+func actionNameGroup(action string) string {
+	best := ""
+	for _, g := range canonicalActionGroups {
+		if !strings.HasPrefix(action, g) || len(g) <= len(best) {
+			continue
+		}
+		if rest := action[len(g):]; rest == "" || unicode.IsUpper(rune(rest[0])) {
+			best = g
+		}
 	}
-	return bad, nil
+	return best
+}
+
+// SchemaAPI is the subset of the Verified Permissions client used to apply schemas.
+type SchemaAPI interface {
+	GetSchema(context.Context, *vpapi.GetSchemaInput, ...func(*vpapi.Options)) (*vpapi.GetSchemaOutput, error)
+	PutSchema(context.Context, *vpapi.PutSchemaInput, ...func(*vpapi.Options)) (*vpapi.PutSchemaOutput, error)
 }
 
 // PutSchemaIfChanged fetches the current schema and applies only when content differs.
@@ -193,18 +376,33 @@ func PutSchemaIfChanged(ctx context.Context, policyStoreId string, cedarJSON str
 	if err != nil {
 		return err
 	}
-	client := vpapi.NewFromConfig(cfg)
+	return putSchemaIfChanged(ctx, vpapi.NewFromConfig(cfg), policyStoreId, cedarJSON)
+}
+
+// putSchemaIfChanged implements PutSchemaIfChanged; throttled calls are retried with
+// awssdk.DefaultRetryPolicy. A store without a schema (or any other GetSchema failure) is treated as
+// having an empty schema.
+func putSchemaIfChanged(ctx context.Context, client SchemaAPI, policyStoreId string, cedarJSON string) error {
 	var current string
-	getOut, err := client.GetSchema(ctx, &vpapi.GetSchemaInput{PolicyStoreId: &policyStoreId})
-	if err == nil && getOut.Schema != nil {
-		current = *getOut.Schema
-	}
+	_ = awssdk.Retry(ctx, awssdk.DefaultRetryPolicy, nil, "verifiedpermissions.GetSchema", func(ctx context.Context) error {
+		getOut, err := client.GetSchema(ctx, &vpapi.GetSchemaInput{PolicyStoreId: &policyStoreId})
+		if err != nil {
+			return awserrors.Classify(err)
+		}
+		if getOut.Schema != nil {
+			current = *getOut.Schema
+		}
+		return nil
+	})
 	if utils.NormalizeJSON(current) == utils.NormalizeJSON(cedarJSON) {
 		return nil
 	}
-	_, err = client.PutSchema(ctx, &vpapi.PutSchemaInput{
-		PolicyStoreId: &policyStoreId,
-		Definition:    &vpapiTypes.SchemaDefinitionMemberCedarJson{Value: cedarJSON},
+	err := awssdk.Retry(ctx, awssdk.DefaultRetryPolicy, nil, "verifiedpermissions.PutSchema", func(ctx context.Context) error {
+		_, err := client.PutSchema(ctx, &vpapi.PutSchemaInput{
+			PolicyStoreId: &policyStoreId,
+			Definition:    &vpapiTypes.SchemaDefinitionMemberCedarJson{Value: cedarJSON},
+		})
+		return awserrors.Classify(err)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to put schema: %w", err)
@@ -1,16 +1,92 @@
 package common
 
-import "testing"
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	vpapi "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
+	"github.com/aws/smithy-go"
+)
 
 func TestEnforceActionGroups(t *testing.T) {
-	bad, err := EnforceActionGroups([]string{"GetTenant", "FooBar"}, "warn")
+	memberOf := map[string][]string{
+		"Get":            {},
+		"Find":           {},
+		"GetTenant":      {"Get"},
+		"ReadTenant":     {"TenantReads"},
+		"TenantReads":    {"Get"},
+		"FindTickets":    {"Get"},
+		"GetawayBooking": {},
+		"GetOrFind":      {"Get", "Find"},
+		"DeleteTenant":   {"Delete"},
+	}
+	report, err := EnforceActionGroups(memberOf, "warn")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(bad) != 1 || bad[0] != "FooBar" {
-		t.Fatalf("expected FooBar to violate")
+	wantViolations := []string{
+		"canonical action group Delete is not declared as an action",
+		"GetOrFind belongs to multiple canonical action groups: Find, Get",
+		"GetawayBooking belongs to no canonical action group",
+	}
+	if !slices.Equal(report.Violations, wantViolations) {
+		t.Fatalf("violations = %q, want %q", report.Violations, wantViolations)
 	}
-	if _, err := EnforceActionGroups([]string{"FooBar"}, "error"); err == nil {
+	if want := []string{"FindTickets is named for Find but belongs to Get"}; !slices.Equal(report.NameMismatches, want) {
+		t.Fatalf("name mismatches = %q, want %q", report.NameMismatches, want)
+	}
+	if _, err := EnforceActionGroups(memberOf, "error"); err == nil {
 		t.Fatalf("expected error in error mode")
 	}
+	if report, err := EnforceActionGroups(memberOf, "off"); err != nil || len(report.Violations) != 0 {
+		t.Fatalf("expected off mode to skip enforcement: %+v %v", report, err)
+	}
+}
+
+func TestEnforceActionGroupsExample(t *testing.T) {
+	s, err := LoadSchema(filepath.Join("..", "..", "infra", "authorizer", "schema.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := EnforceActionGroups(s.ActionMemberOf, "error"); err != nil {
+		t.Fatalf("example schema should satisfy action group enforcement: %v", err)
+	}
+}
+
+// fakeSchemaAPI serves a fixed current schema and fails the first PutSchema calls with putErrs.
+type fakeSchemaAPI struct {
+	current string
+	putErrs []error
+	puts    int
+}
+
+func (f *fakeSchemaAPI) GetSchema(context.Context, *vpapi.GetSchemaInput, ...func(*vpapi.Options)) (*vpapi.GetSchemaOutput, error) {
+	return &vpapi.GetSchemaOutput{Schema: &f.current}, nil
+}
+
+func (f *fakeSchemaAPI) PutSchema(context.Context, *vpapi.PutSchemaInput, ...func(*vpapi.Options)) (*vpapi.PutSchemaOutput, error) {
+	f.puts++
+	if len(f.putErrs) > 0 {
+		err := f.putErrs[0]
+		f.putErrs = f.putErrs[1:]
+		return nil, err
+	}
+	return &vpapi.PutSchemaOutput{}, nil
+}
+
+func TestPutSchemaIfChanged(t *testing.T) {
+	api := &fakeSchemaAPI{current: `{"ns": {}}`}
+	if err := putSchemaIfChanged(context.Background(), api, "ps", `{ "ns": {} }`); err != nil || api.puts != 0 {
+		t.Fatalf("unchanged schema: err=%v puts=%d", err, api.puts)
+	}
+	api.putErrs = []error{&smithy.GenericAPIError{Code: "ThrottlingException"}}
+	if err := putSchemaIfChanged(context.Background(), api, "ps", `{"other": {}}`); err != nil || api.puts != 2 {
+		t.Fatalf("expected throttled put to be retried: err=%v puts=%d", err, api.puts)
+	}
+	api.putErrs = []error{&smithy.GenericAPIError{Code: "ValidationException"}}
+	if err := putSchemaIfChanged(context.Background(), api, "ps", `{"other": {}}`); err == nil || api.puts != 3 {
+		t.Fatalf("expected validation error without retry: err=%v puts=%d", err, api.puts)
+	}
 }
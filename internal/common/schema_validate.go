@@ -0,0 +1,417 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaProblem is a semantic error found in a Cedar JSON schema. Path is relative to the namespace
+// body (e.g. entityTypes.Ticket.memberOfTypes[0]); Line is its 1-based line in the schema file, or 0
+// when unknown (e.g. definitions merged from the base schema).
+type SchemaProblem struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (p SchemaProblem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// SchemaPositions maps paths relative to the namespace body to their line in the schema file.
+type SchemaPositions map[string]int
+
+// line returns the line of path, falling back to its closest enclosing path.
+func (p SchemaPositions) line(path string) int {
+	for path != "" {
+		if l, ok := p[path]; ok {
+			return l
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
+
+// schemaPositions records the line of every key and list item under the namespace of a YAML (or JSON)
+// schema document.
+func schemaPositions(raw []byte) SchemaPositions {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	top := doc.Content[0]
+	if top.Kind != yaml.MappingNode || len(top.Content) != 2 {
+		return nil
+	}
+	out := SchemaPositions{}
+	collectPositions(top.Content[1], "", out)
+	return out
+}
+
+func collectPositions(n *yaml.Node, path string, out SchemaPositions) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			p := n.Content[i].Value
+			if path != "" {
+				p = path + "." + p
+			}
+			out[p] = n.Content[i].Line
+			collectPositions(n.Content[i+1], p, out)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			out[p] = c.Line
+			collectPositions(c, p, out)
+		}
+	case yaml.AliasNode:
+		collectPositions(n.Alias, path, out)
+	}
+}
+
+// Keys accepted by the Cedar JSON schema format on each kind of definition.
+var (
+	entityTypeKeys = []string{"memberOfTypes", "shape", "tags", "enum", "annotations"}
+	actionKeys     = []string{"memberOf", "appliesTo", "attributes", "annotations"}
+	appliesToKeys  = []string{"principalTypes", "resourceTypes", "context"}
+	extensionTypes = []string{"ipaddr", "decimal", "datetime", "duration"}
+	primitiveTypes = []string{"String", "Long", "Boolean"}
+)
+
+// schemaValidator checks one namespace body and accumulates problems.
+type schemaValidator struct {
+	ns          string
+	entityTypes map[string]any
+	commonTypes map[string]any
+	actions     map[string]any
+	pos         SchemaPositions
+	problems    []SchemaProblem
+}
+
+// ValidateCedarSchema checks the references and type grammar of a single-namespace Cedar JSON schema
+// (superset keys must already be pruned) and returns every problem found, ordered by line: unknown
+// entity and common types in memberOfTypes, attribute types and appliesTo, malformed Record/Set/Entity/
+// Extension types, unknown action memberOf references, and cycles in memberOfTypes or the action
+// hierarchy. A type listing itself in memberOfTypes (a nested hierarchy such as Tenant) is allowed.
+// pos may be nil.
+func ValidateCedarSchema(doc map[string]any, pos SchemaPositions) []SchemaProblem {
+	_, ns, body, err := extractSingleNamespace(doc)
+	if err != nil {
+		return []SchemaProblem{{Message: err.Error()}}
+	}
+	v := &schemaValidator{ns: ns, pos: pos}
+	v.entityTypes = v.section(body, "entityTypes")
+	v.commonTypes = v.section(body, "commonTypes")
+	v.actions = v.section(body, "actions")
+
+	for _, name := range sortedKeys(v.commonTypes) {
+		v.checkType("commonTypes."+name, v.commonTypes[name], false)
+	}
+	for _, name := range sortedKeys(v.entityTypes) {
+		v.checkEntityType(name)
+	}
+	for _, name := range sortedKeys(v.actions) {
+		v.checkAction(name)
+	}
+	v.checkCycles("entityTypes", v.entityTypes, func(def map[string]any) []string {
+		var out []string
+		for _, t := range stringList(def["memberOfTypes"]) {
+			if local, ok := v.localName(t); ok {
+				out = append(out, local)
+			}
+		}
+		return out
+	}, "memberOfTypes", true)
+	v.checkCycles("actions", v.actions, func(def map[string]any) []string {
+		var out []string
+		for _, ref := range anyList(def["memberOf"]) {
+			if m, ok := ref.(map[string]any); ok {
+				if id, ok := m["id"].(string); ok {
+					out = append(out, id)
+				}
+			}
+		}
+		return out
+	}, "memberOf", false)
+
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Line < v.problems[j].Line })
+	return v.problems
+}
+
+func (v *schemaValidator) add(path string, format string, args ...any) {
+	v.problems = append(v.problems, SchemaProblem{Path: path, Line: v.pos.line(path), Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) section(body map[string]any, name string) map[string]any {
+	raw, ok := body[name]
+	if !ok {
+		return map[string]any{}
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		v.add(name, "must be an object")
+		return map[string]any{}
+	}
+	return m
+}
+
+// localName strips this namespace's qualifier from a type name; ok is false for other namespaces.
+func (v *schemaValidator) localName(name string) (string, bool) {
+	name = strings.TrimPrefix(name, v.ns+"::")
+	return name, !strings.Contains(name, "::")
+}
+
+func (v *schemaValidator) isEntityType(name string) bool {
+	local, ok := v.localName(name)
+	_, exists := v.entityTypes[local]
+	return ok && exists
+}
+
+func (v *schemaValidator) isCommonType(name string) bool {
+	local, ok := v.localName(name)
+	_, exists := v.commonTypes[local]
+	return ok && exists
+}
+
+// checkKeys reports keys of def that are not in allowed.
+func (v *schemaValidator) checkKeys(path string, def map[string]any, allowed []string) {
+	for _, k := range sortedKeys(def) {
+		if !slices.Contains(allowed, k) {
+			v.add(path+"."+k, "unknown key %q", k)
+		}
+	}
+}
+
+func (v *schemaValidator) checkEntityRefs(path string, raw any) {
+	refs, ok := raw.([]any)
+	if !ok {
+		v.add(path, "must be a list of entity type names")
+		return
+	}
+	for i, r := range refs {
+		name, ok := r.(string)
+		switch {
+		case !ok:
+			v.add(fmt.Sprintf("%s[%d]", path, i), "must be an entity type name")
+		case !v.isEntityType(name):
+			v.add(fmt.Sprintf("%s[%d]", path, i), "unknown entity type %q", name)
+		}
+	}
+}
+
+func (v *schemaValidator) checkEntityType(name string) {
+	path := "entityTypes." + name
+	def, ok := v.entityTypes[name].(map[string]any)
+	if !ok {
+		v.add(path, "must be an object")
+		return
+	}
+	v.checkKeys(path, def, entityTypeKeys)
+	if raw, ok := def["memberOfTypes"]; ok {
+		v.checkEntityRefs(path+".memberOfTypes", raw)
+	}
+	if shape, ok := def["shape"]; ok {
+		v.checkType(path+".shape", shape, false)
+		if st, _ := shape.(map[string]any); st != nil && !v.isRecord(st) {
+			v.add(path+".shape", "must be a Record type")
+		}
+	}
+	if tags, ok := def["tags"]; ok {
+		v.checkType(path+".tags", tags, false)
+	}
+}
+
+// isRecord reports whether t is a Record or a common type that resolves to one.
+func (v *schemaValidator) isRecord(t map[string]any) bool {
+	typ, _ := t["type"].(string)
+	if typ == "Record" {
+		return true
+	}
+	if local, ok := v.localName(typ); ok {
+		if ct, _ := v.commonTypes[local].(map[string]any); ct != nil {
+			return ct["type"] == "Record"
+		}
+	}
+	return false
+}
+
+// checkType validates a type expression; attr allows the record-attribute "required" flag.
+func (v *schemaValidator) checkType(path string, raw any, attr bool) {
+	t, ok := raw.(map[string]any)
+	if !ok {
+		v.add(path, "type must be an object")
+		return
+	}
+	typ, ok := t["type"].(string)
+	if !ok {
+		v.add(path, "missing type")
+		return
+	}
+	allowed := []string{"type"}
+	if attr {
+		allowed = append(allowed, "required")
+		if r, ok := t["required"]; ok {
+			if _, isBool := r.(bool); !isBool {
+				v.add(path+".required", "must be a boolean")
+			}
+		}
+	}
+	switch typ {
+	case "String", "Long", "Boolean":
+	case "Record":
+		allowed = append(allowed, "attributes", "additionalAttributes")
+		attrs, ok := t["attributes"].(map[string]any)
+		if !ok {
+			v.add(path, "Record type requires an attributes object")
+			break
+		}
+		for _, name := range sortedKeys(attrs) {
+			v.checkType(path+".attributes."+name, attrs[name], true)
+		}
+	case "Set":
+		allowed = append(allowed, "element")
+		elem, ok := t["element"]
+		if !ok {
+			v.add(path, "Set type requires an element type")
+			break
+		}
+		v.checkType(path+".element", elem, false)
+	case "Entity":
+		allowed = append(allowed, "name")
+		name, _ := t["name"].(string)
+		if name == "" {
+			v.add(path, "Entity type requires a name")
+		} else if !v.isEntityType(name) {
+			v.add(path+".name", "unknown entity type %q", name)
+		}
+	case "Extension":
+		allowed = append(allowed, "name")
+		if name, _ := t["name"].(string); !slices.Contains(extensionTypes, name) {
+			v.add(path, "unknown extension type %q (expected one of %s)", name, strings.Join(extensionTypes, ", "))
+		}
+	case "EntityOrCommon":
+		allowed = append(allowed, "name")
+		name, _ := t["name"].(string)
+		if !v.isEntityType(name) && !v.isCommonType(name) && !slices.Contains(primitiveTypes, name) && !slices.Contains(extensionTypes, name) {
+			v.add(path, "unknown entity or common type %q", name)
+		}
+	default:
+		if !v.isCommonType(typ) {
+			v.add(path+".type", "unknown type %q", typ)
+		}
+	}
+	v.checkKeys(path, t, allowed)
+}
+
+func (v *schemaValidator) checkAction(name string) {
+	path := "actions." + name
+	def, ok := v.actions[name].(map[string]any)
+	if !ok {
+		v.add(path, "must be an object")
+		return
+	}
+	v.checkKeys(path, def, actionKeys)
+	if raw, ok := def["memberOf"]; ok {
+		refs, ok := raw.([]any)
+		if !ok {
+			v.add(path+".memberOf", "must be a list of action references")
+		}
+		for i, r := range refs {
+			v.checkActionRef(fmt.Sprintf("%s.memberOf[%d]", path, i), r)
+		}
+	}
+	if raw, ok := def["appliesTo"]; ok {
+		applies, ok := raw.(map[string]any)
+		if !ok {
+			v.add(path+".appliesTo", "must be an object")
+			return
+		}
+		v.checkKeys(path+".appliesTo", applies, appliesToKeys)
+		for _, k := range []string{"principalTypes", "resourceTypes"} {
+			if refs, ok := applies[k]; ok {
+				v.checkEntityRefs(path+".appliesTo."+k, refs)
+			}
+		}
+		if ctx, ok := applies["context"]; ok {
+			v.checkType(path+".appliesTo.context", ctx, false)
+			if ct, _ := ctx.(map[string]any); ct != nil && !v.isRecord(ct) {
+				v.add(path+".appliesTo.context", "must be a Record type")
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) checkActionRef(path string, raw any) {
+	ref, ok := raw.(map[string]any)
+	if !ok {
+		v.add(path, "must be an object with an id")
+		return
+	}
+	id, _ := ref["id"].(string)
+	if id == "" {
+		v.add(path, "missing action id")
+		return
+	}
+	if typ, ok := ref["type"].(string); ok && typ != "Action" && typ != v.ns+"::Action" {
+		v.add(path+".type", "action groups must be actions of namespace %q, got %q", v.ns, typ)
+		return
+	}
+	if _, ok := v.actions[id]; !ok {
+		v.add(path, "unknown action %q", id)
+	}
+}
+
+// checkCycles reports each cycle in the graph formed by edges over defs once, at its first member in
+// name order. Self-references are skipped when allowSelf is set.
+func (v *schemaValidator) checkCycles(section string, defs map[string]any, edges func(map[string]any) []string, key string, allowSelf bool) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		def, _ := defs[name].(map[string]any)
+		for _, next := range edges(def) {
+			if _, ok := defs[next]; !ok || (allowSelf && next == name) {
+				continue
+			}
+			switch state[next] {
+			case visiting:
+				start := slices.Index(stack, next)
+				cycle := append(slices.Clone(stack[start:]), next)
+				v.add(section+"."+next+"."+key, "%s cycle: %s", key, strings.Join(cycle, " -> "))
+			case unvisited:
+				visit(next)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, name := range sortedKeys(defs) {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+}
+
+func anyList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
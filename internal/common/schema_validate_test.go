@@ -0,0 +1,144 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const invalidSchemaYAML = `app:
+  commonTypes:
+    Address:
+      type: Record
+      attributes:
+        street: { type: String }
+  entityTypes:
+    Tenant:
+      memberOfTypes: [Tenantt]
+    Folder:
+      memberOfTypes: [Doc]
+    Doc:
+      memberOfTypes: [Folder, app::Tenant]
+      shape:
+        type: Record
+        attributes:
+          tags: { type: Set }
+          owner: { type: Entity, name: Person }
+          ip: { type: Extension, name: ipaddress }
+          home: { type: Address, required: true }
+          size: { type: Integer }
+          title: { type: String, requird: true }
+  actions:
+    Get:
+      memberOf: [{ id: Get }]
+    GetDoc:
+      memberOf: [{ id: Gett }]
+      appliesTo:
+        principalTypes: [Tenant]
+        resourceTypes: [Document]
+        context: { type: String }
+`
+
+func validateYAML(t *testing.T, src string) []SchemaProblem {
+	t.Helper()
+	var doc map[string]any
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatalf("invalid test YAML: %v", err)
+	}
+	return ValidateCedarSchema(doc, schemaPositions([]byte(src)))
+}
+
+func TestValidateCedarSchema(t *testing.T) {
+	problems := validateYAML(t, invalidSchemaYAML)
+	want := []string{
+		`line 9: entityTypes.Tenant.memberOfTypes[0]: unknown entity type "Tenantt"`,
+		`line 13: entityTypes.Doc.memberOfTypes: memberOfTypes cycle: Doc -> Folder -> Doc`,
+		`line 17: entityTypes.Doc.shape.attributes.tags: Set type requires an element type`,
+		`line 18: entityTypes.Doc.shape.attributes.owner.name: unknown entity type "Person"`,
+		`line 19: entityTypes.Doc.shape.attributes.ip: unknown extension type "ipaddress"`,
+		`line 21: entityTypes.Doc.shape.attributes.size.type: unknown type "Integer"`,
+		`line 22: entityTypes.Doc.shape.attributes.title.requird: unknown key "requird"`,
+		`line 25: actions.Get.memberOf: memberOf cycle: Get -> Get`,
+		`line 27: actions.GetDoc.memberOf[0]: unknown action "Gett"`,
+		`line 30: actions.GetDoc.appliesTo.resourceTypes[0]: unknown entity type "Document"`,
+		`line 31: actions.GetDoc.appliesTo.context: must be a Record type`,
+	}
+	got := make([]string, 0, len(problems))
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems:\n%s", strings.Join(got, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("problem %d = %q, want prefix %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidateCedarSchemaValid(t *testing.T) {
+	problems := validateYAML(t, `app:
+  commonTypes:
+    Address: { type: Record, attributes: { street: { type: String } } }
+  entityTypes:
+    Tenant: { memberOfTypes: [Tenant] }
+    Doc:
+      memberOfTypes: [Tenant]
+      shape:
+        type: Record
+        attributes:
+          tags: { type: Set, element: { type: String } }
+          owner: { type: Entity, name: app::Tenant, required: false }
+          ip: { type: Extension, name: ipaddr }
+          home: { type: Address }
+          kind: { type: EntityOrCommon, name: Long }
+  actions:
+    Get: {}
+    GetDoc:
+      memberOf: [{ id: Get, type: app::Action }]
+      appliesTo:
+        principalTypes: [Tenant]
+        resourceTypes: [Doc]
+        context: { type: Record, attributes: {} }
+`)
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+}
+
+func TestLoadSchemaReportsLineNumbers(t *testing.T) {
+	cases := map[string]struct{ src, want string }{
+		".yaml": {
+			src: `app:
+  entityTypes:
+    Tenant: { memberOfTypes: [Tenant] }
+    User: { memberOfTypes: [GlobalRolee] }
+    Role: {}
+    GlobalRole: {}
+    TenantGrant: {}
+`,
+			want: `line 4: entityTypes.User.memberOfTypes[0]: unknown entity type "GlobalRolee"`,
+		},
+		".json": {
+			src: `{"app": {"entityTypes": {
+  "Tenant": {"memberOfTypes": ["Tenant"]},
+  "User": {"memberOfTypes": ["GlobalRolee"]},
+  "Role": {}, "GlobalRole": {}, "TenantGrant": {}
+}}}`,
+			want: `line 3: entityTypes.User.memberOfTypes[0]: unknown entity type "GlobalRolee"`,
+		},
+	}
+	for ext, tc := range cases {
+		path := filepath.Join(t.TempDir(), "schema"+ext)
+		if err := os.WriteFile(path, []byte(tc.src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSchema(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected %q, got %v", ext, tc.want, err)
+		}
+	}
+}
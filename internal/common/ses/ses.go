@@ -1,6 +1,7 @@
 package ses
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/mail"
 	"regexp"
@@ -125,3 +126,20 @@ func validateSesRegions(partition string, identityRegion string, userPoolRegion
 	}
 	return nil
 }
+
+// CognitoSendingPolicy returns the SES identity policy document that allows the Cognito User Pool
+// identified by userPoolArn to send email through the identity.
+func CognitoSendingPolicy(account string, identityRegion string, identity string, userPoolArn string) string {
+	pol := map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":    "Allow",
+			"Action":    []string{"ses:SendEmail", "ses:SendRawEmail"},
+			"Principal": map[string]any{"Service": "cognito-idp.amazonaws.com"},
+			"Resource":  fmt.Sprintf("arn:%s:ses:%s:%s:identity/%s", awssdk.PartitionForRegion(identityRegion), identityRegion, account, identity),
+			"Condition": map[string]any{"StringEquals": map[string]any{"AWS:SourceArn": userPoolArn}},
+		}},
+	}
+	b, _ := json.Marshal(pol)
+	return string(b)
+}
@@ -1,6 +1,9 @@
 package ses
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestValidateSesConfig_EmailIdentityMatch(t *testing.T) {
 	acc, ident, region, err := ValidateSesConfig("arn:aws:ses:us-east-1:123456789012:identity/sender@example.com", "sender@example.com", nil, "us-east-1")
@@ -18,3 +21,10 @@ func TestValidateSesConfig_DomainMismatch(t *testing.T) {
 		t.Fatalf("expected error for domain mismatch")
 	}
 }
+
+func TestCognitoSendingPolicy(t *testing.T) {
+	pol := CognitoSendingPolicy("123456789012", "us-gov-west-1", "example.com", "arn:aws-us-gov:cognito-idp:us-gov-west-1:123456789012:userpool/pool")
+	if !strings.Contains(pol, `"arn:aws-us-gov:ses:us-gov-west-1:123456789012:identity/example.com"`) || !strings.Contains(pol, `userpool/pool`) {
+		t.Fatalf("unexpected policy: %s", pol)
+	}
+}
@@ -0,0 +1,434 @@
+package common
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// baseSchemaYAML is the base superset schema (principal entity types) that consumer schemas are
+// merged onto; see ADR-0004.
+//
+//go:embed assets/schema/base.yaml
+var baseSchemaYAML []byte
+
+// MergedSchemaFileName is the name of the merged superset bundled next to index.mjs in the authorizer
+// code archive.
+const MergedSchemaFileName = "schema.merged.json"
+
+// Superset-only keys that may be added to existing base definitions; they are pruned before PutSchema.
+var (
+	entityTypeSupersetKeys = map[string]bool{"resourceEntities": true}
+	actionSupersetKeys     = map[string]bool{"entityMap": true, "input": true}
+)
+
+// SchemaMappings is the optional root-level `mappings` section, used by the authorizer only to extract
+// the action identifier from an event.
+type SchemaMappings struct {
+	Actions struct {
+		AppSync    *MappingPath `json:"appsync,omitempty"`
+		ApiGateway *MappingPath `json:"apiGateway,omitempty"`
+	} `json:"actions"`
+}
+
+// MappingPath is a dotted path into the integration event, e.g. info.fieldName.
+type MappingPath struct {
+	Path string `json:"path"`
+}
+
+// MergedSchema is a consumer schema merged onto the base superset schema.
+type MergedSchema struct {
+	Namespace string
+	// SupersetJSON is the merged document with superset extensions (resourceEntities, entityMap,
+	// input, mappings); it is bundled with the Lambda as schema.merged.json.
+	SupersetJSON string
+	// CedarJSON is SupersetJSON with the extensions pruned, safe to send to PutSchema.
+	CedarJSON string
+	Mappings  *SchemaMappings
+}
+
+// Hash returns the hex SHA-256 of SupersetJSON, used to track changes to the bundled document.
+func (m MergedSchema) Hash() string {
+	sum := sha256.Sum256([]byte(m.SupersetJSON))
+	return hex.EncodeToString(sum[:])
+}
+
+// BaseSchema returns the base superset schema with namespace as its single top-level key.
+func BaseSchema(namespace string) (map[string]any, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(baseSchemaYAML, &doc); err != nil {
+		return nil, fmt.Errorf("invalid base schema: %w", err)
+	}
+	_, _, body, err := extractSingleNamespace(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base schema: %w", err)
+	}
+	return normalizeDocument(map[string]any{namespace: body})
+}
+
+// MergeSchemaSuperset merges a consumer partial superset onto base (ADR-0004). The namespaces must
+// match. The partial may add entity types, actions and common types; on existing definitions it may
+// only add superset keys (entityTypes.<T>.resourceEntities, actions.<A>.entityMap and .input) and may
+// restate Cedar fields unchanged. Base principal types cannot be added or modified, resourceEntities
+// templates and entityMap entries cannot be overridden, and per-integration inputs are replaced.
+func MergeSchemaSuperset(base map[string]any, partial map[string]any) (MergedSchema, error) {
+	_, bns, _, err := extractSingleNamespace(base)
+	if err != nil {
+		return MergedSchema{}, err
+	}
+	_, pns, _, err := extractSingleNamespace(partial)
+	if err != nil {
+		return MergedSchema{}, err
+	}
+	if bns != pns {
+		return MergedSchema{}, fmt.Errorf("namespace mismatch: base=%s partial=%s", bns, pns)
+	}
+	// Work on JSON-normalized copies so YAML and JSON inputs compare alike and inputs are not mutated.
+	out, err := normalizeDocument(base)
+	if err != nil {
+		return MergedSchema{}, err
+	}
+	in, err := normalizeDocument(partial)
+	if err != nil {
+		return MergedSchema{}, err
+	}
+	obody, pbody := out[bns].(map[string]any), in[pns].(map[string]any)
+
+	for _, section := range sortedKeys(pbody) {
+		switch section {
+		case "entityTypes":
+			err = mergeSection(obody, pbody, section, mergeEntityType)
+		case "actions":
+			err = mergeSection(obody, pbody, section, mergeAction)
+		case "commonTypes":
+			err = mergeSection(obody, pbody, section, mergeCommonType)
+		case "mappings":
+			obody["mappings"] = pbody["mappings"]
+		default:
+			err = fmt.Errorf("unsupported schema section %q (expected entityTypes, actions, commonTypes or mappings)", section)
+		}
+		if err != nil {
+			return MergedSchema{}, err
+		}
+	}
+
+	res := MergedSchema{Namespace: bns}
+	if raw, ok := obody["mappings"]; ok {
+		b, _ := json.Marshal(raw)
+		res.Mappings = &SchemaMappings{}
+		if err := json.Unmarshal(b, res.Mappings); err != nil {
+			return MergedSchema{}, fmt.Errorf("invalid mappings: %w", err)
+		}
+	}
+	superset, err := json.Marshal(out)
+	if err != nil {
+		return MergedSchema{}, fmt.Errorf("failed to encode merged schema: %w", err)
+	}
+	res.SupersetJSON = string(superset)
+	pruneForCedar(out)
+	cedar, err := json.Marshal(out)
+	if err != nil {
+		return MergedSchema{}, fmt.Errorf("failed to encode Cedar schema: %w", err)
+	}
+	res.CedarJSON = string(cedar)
+	return res, nil
+}
+
+// mergeSection merges each definition of pbody[section] into obody[section]: new names are added,
+// existing ones are handed to mergeExisting.
+func mergeSection(obody, pbody map[string]any, section string, mergeExisting func(name string, base, partial map[string]any) error) error {
+	pdefs, ok := pbody[section].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s must be an object", section)
+	}
+	odefs, ok := obody[section].(map[string]any)
+	if !ok {
+		odefs = map[string]any{}
+		obody[section] = odefs
+	}
+	for _, name := range sortedKeys(pdefs) {
+		pdef := pdefs[name]
+		bdef, exists := odefs[name]
+		if !exists {
+			if section == "entityTypes" && slices.Contains(requiredPrincipals, name) {
+				return fmt.Errorf("cannot add or modify principal type %s", name)
+			}
+			odefs[name] = pdef
+			continue
+		}
+		pm, ok := pdef.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s.%s must be an object", section, name)
+		}
+		bm, _ := bdef.(map[string]any)
+		if err := mergeExisting(name, bm, pm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeEntityType(name string, bdef, pdef map[string]any) error {
+	for _, k := range sortedKeys(pdef) {
+		v := pdef[k]
+		if !entityTypeSupersetKeys[k] {
+			if reflect.DeepEqual(bdef[k], v) {
+				continue
+			}
+			return fmt.Errorf("cannot override base entityType %s.%s", name, k)
+		}
+		templates, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("entityTypes.%s.%s must be an object", name, k)
+		}
+		existing, _ := bdef[k].(map[string]any)
+		if existing == nil {
+			existing = map[string]any{}
+			bdef[k] = existing
+		}
+		for _, tpl := range sortedKeys(templates) {
+			if _, ok := existing[tpl]; ok {
+				return fmt.Errorf("cannot override existing resourceEntities template %s.%s", name, tpl)
+			}
+			existing[tpl] = templates[tpl]
+		}
+	}
+	return nil
+}
+
+func mergeAction(name string, bdef, pdef map[string]any) error {
+	for _, k := range sortedKeys(pdef) {
+		v := pdef[k]
+		if !actionSupersetKeys[k] {
+			if reflect.DeepEqual(bdef[k], v) {
+				continue
+			}
+			return fmt.Errorf("cannot override base action %s.%s", name, k)
+		}
+		entries, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("actions.%s.%s must be an object", name, k)
+		}
+		existing, _ := bdef[k].(map[string]any)
+		if existing == nil {
+			existing = map[string]any{}
+			bdef[k] = existing
+		}
+		for _, key := range sortedKeys(entries) {
+			if _, ok := existing[key]; ok && k == "entityMap" {
+				return fmt.Errorf("cannot override existing actions.%s.entityMap for %s", name, key)
+			}
+			// input is replaced per integration
+			existing[key] = entries[key]
+		}
+	}
+	return nil
+}
+
+func mergeCommonType(name string, bdef, pdef map[string]any) error {
+	if !reflect.DeepEqual(bdef, pdef) {
+		return fmt.Errorf("cannot override base commonType %s", name)
+	}
+	return nil
+}
+
+// pruneForCedar removes the superset extensions from doc in place.
+func pruneForCedar(doc map[string]any) {
+	for _, v := range doc {
+		body, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		delete(body, "mappings")
+		for section, keys := range map[string]map[string]bool{"entityTypes": entityTypeSupersetKeys, "actions": actionSupersetKeys} {
+			defs, _ := body[section].(map[string]any)
+			for _, d := range defs {
+				def, ok := d.(map[string]any)
+				if !ok {
+					continue
+				}
+				for k := range keys {
+					delete(def, k)
+				}
+			}
+		}
+	}
+}
+
+var (
+	templateVarRe    = regexp.MustCompile(`\$([a-zA-Z0-9_]+)`)
+	urlTemplateVarRe = regexp.MustCompile(`:([a-zA-Z0-9_]+)`)
+)
+
+// ValidateSuperset checks the ADR-0004 cross-references of a merged superset and returns every problem
+// found (nil when valid): each action with appliesTo.resourceTypes needs an entityMap entry per type
+// naming an existing resourceEntities template, and every $variable a template uses must be provided by
+// each declared integration input (appsync.body; rest url, body or query). Action groups, which have no
+// resource types, are skipped.
+func ValidateSuperset(doc map[string]any) []string {
+	_, _, body, err := extractSingleNamespace(doc)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	entityTypes, _ := body["entityTypes"].(map[string]any)
+	actions, _ := body["actions"].(map[string]any)
+	var problems []string
+	for _, name := range sortedKeys(actions) {
+		action, _ := actions[name].(map[string]any)
+		appliesTo, _ := action["appliesTo"].(map[string]any)
+		resourceTypes := stringList(appliesTo["resourceTypes"])
+		if len(resourceTypes) == 0 {
+			continue
+		}
+		entityMap, ok := action["entityMap"].(map[string]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("actions.%s.entityMap is required", name))
+			continue
+		}
+		input, _ := action["input"].(map[string]any)
+		for _, rt := range resourceTypes {
+			tplName, _ := entityMap[rt].(string)
+			if tplName == "" {
+				problems = append(problems, fmt.Sprintf("actions.%s.entityMap missing key for resourceType %s", name, rt))
+				continue
+			}
+			et, _ := entityTypes[rt].(map[string]any)
+			templates, _ := et["resourceEntities"].(map[string]any)
+			tpl, ok := templates[tplName].(map[string]any)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("actions.%s.entityMap.%s references missing template %s.resourceEntities.%s", name, rt, rt, tplName))
+				continue
+			}
+			problems = append(problems, templateInputProblems(name, templateVars(tpl), input)...)
+		}
+	}
+	return problems
+}
+
+// templateVars lists the $variables used by a resourceEntities template's id and attributes.
+func templateVars(tpl map[string]any) []string {
+	seen := map[string]bool{}
+	collect := func(v any) {
+		if s, ok := v.(string); ok {
+			for _, m := range templateVarRe.FindAllStringSubmatch(s, -1) {
+				seen[m[1]] = true
+			}
+		}
+	}
+	collect(tpl["id"])
+	attrs, _ := tpl["attributes"].(map[string]any)
+	for _, v := range attrs {
+		collect(v)
+	}
+	return sortedKeys(seen)
+}
+
+func templateInputProblems(action string, vars []string, input map[string]any) []string {
+	if len(vars) == 0 {
+		return nil
+	}
+	appsync, hasAppSync := input["appsync"].(map[string]any)
+	rest, hasRest := input["rest"].(map[string]any)
+	if !hasAppSync && !hasRest {
+		return []string{fmt.Sprintf("actions.%s.input must declare appsync or rest inputs for template variables", action)}
+	}
+	var problems []string
+	if hasAppSync {
+		body, _ := appsync["body"].(map[string]any)
+		for _, v := range vars {
+			if _, ok := body[v]; !ok {
+				problems = append(problems, fmt.Sprintf("actions.%s (appsync): template requires variable $%s not provided in input.appsync.body", action, v))
+			}
+		}
+	}
+	if hasRest {
+		provided := map[string]bool{}
+		if url, ok := rest["url"].(string); ok {
+			for _, m := range urlTemplateVarRe.FindAllStringSubmatch(url, -1) {
+				provided[m[1]] = true
+			}
+		}
+		body, _ := rest["body"].(map[string]any)
+		for k := range body {
+			provided[k] = true
+		}
+		switch q := rest["query"].(type) {
+		case string:
+			provided[q] = true
+		case map[string]any:
+			for k := range q {
+				provided[k] = true
+			}
+		}
+		for _, v := range vars {
+			if !provided[v] {
+				problems = append(problems, fmt.Sprintf("actions.%s (rest): template requires variable $%s not provided in input.rest (url/body/query)", action, v))
+			}
+		}
+	}
+	return problems
+}
+
+// hasSupersetExtensions reports whether doc uses any ADR-0004 extension.
+func hasSupersetExtensions(doc map[string]any) bool {
+	_, _, body, err := extractSingleNamespace(doc)
+	if err != nil {
+		return false
+	}
+	if _, ok := body["mappings"]; ok {
+		return true
+	}
+	for section, keys := range map[string]map[string]bool{"entityTypes": entityTypeSupersetKeys, "actions": actionSupersetKeys} {
+		defs, _ := body[section].(map[string]any)
+		for _, d := range defs {
+			def, _ := d.(map[string]any)
+			for k := range keys {
+				if _, ok := def[k]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// normalizeDocument deep-copies doc through JSON so YAML- and JSON-decoded values compare alike.
+func normalizeDocument(doc map[string]any) (map[string]any, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema as JSON: %w", err)
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode schema JSON: %w", err)
+	}
+	return out, nil
+}
+
+func stringList(v any) []string {
+	l, _ := v.([]any)
+	out := make([]string, 0, len(l))
+	for _, e := range l {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,281 @@
+package common
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const partialSupersetYAML = `
+app:
+  entityTypes:
+    TenantGrant:
+      resourceEntities:
+        byTenantIdAndUserId:
+          id: $tenantId:$userId
+          type: TenantGrant
+          attributes: { tenantId: $tenantId, userId: $userId }
+  actions:
+    Get:
+      appliesTo: {}
+    getTenantGrant:
+      memberOf: [{ id: Get }]
+      appliesTo: { principalTypes: [User], resourceTypes: [TenantGrant] }
+      entityMap: { TenantGrant: byTenantIdAndUserId }
+      input:
+        appsync:
+          body: { tenantId: tenantId, userId: userId }
+        rest:
+          url: /tenant-grant/:tenantId/:userId
+  mappings:
+    actions:
+      appsync: { path: info.fieldName }
+`
+
+func parseSchemaYAML(t *testing.T, src string) map[string]any {
+	t.Helper()
+	var doc map[string]any
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatalf("invalid test YAML: %v", err)
+	}
+	return doc
+}
+
+func mergeOntoBase(t *testing.T, partial string) (MergedSchema, error) {
+	t.Helper()
+	base, err := BaseSchema("app")
+	if err != nil {
+		t.Fatalf("base schema: %v", err)
+	}
+	return MergeSchemaSuperset(base, parseSchemaYAML(t, partial))
+}
+
+func TestMergeSchemaSupersetAndPrune(t *testing.T) {
+	merged, err := mergeOntoBase(t, partialSupersetYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.Namespace != "app" {
+		t.Fatalf("namespace = %q", merged.Namespace)
+	}
+	if merged.Mappings == nil || merged.Mappings.Actions.AppSync == nil || merged.Mappings.Actions.AppSync.Path != "info.fieldName" {
+		t.Fatalf("mappings not decoded: %+v", merged.Mappings)
+	}
+	for _, key := range []string{"resourceEntities", "entityMap", "input", "mappings"} {
+		if !strings.Contains(merged.SupersetJSON, `"`+key+`"`) {
+			t.Errorf("superset JSON is missing %s", key)
+		}
+		if strings.Contains(merged.CedarJSON, `"`+key+`"`) {
+			t.Errorf("Cedar JSON still contains %s", key)
+		}
+	}
+	var cedar map[string]map[string]map[string]any
+	if err := json.Unmarshal([]byte(merged.CedarJSON), &cedar); err != nil {
+		t.Fatalf("invalid Cedar JSON: %v", err)
+	}
+	for _, p := range requiredPrincipals {
+		if _, ok := cedar["app"]["entityTypes"][p]; !ok {
+			t.Errorf("base principal %s missing from merged schema", p)
+		}
+	}
+	if _, ok := cedar["app"]["actions"]["getTenantGrant"]; !ok {
+		t.Errorf("consumer action missing from merged schema")
+	}
+}
+
+func TestMergeSchemaSupersetRejectsOverrides(t *testing.T) {
+	cases := map[string]string{
+		"principal shape": `
+app:
+  entityTypes:
+    User:
+      shape: { type: Record, attributes: { email: { type: String } } }`,
+		"principal memberOfTypes": `
+app:
+  entityTypes:
+    Tenant:
+      memberOfTypes: []`,
+		"namespace": `
+other:
+  entityTypes: {}`,
+		"unknown section": `
+app:
+  entities: {}`,
+	}
+	for name, partial := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := mergeOntoBase(t, partial); err == nil {
+				t.Fatalf("expected merge to be rejected")
+			}
+		})
+	}
+
+	// Restating base Cedar fields unchanged is allowed.
+	if _, err := mergeOntoBase(t, `
+app:
+  entityTypes:
+    Tenant:
+      memberOfTypes: [Tenant]`); err != nil {
+		t.Fatalf("unchanged restatement rejected: %v", err)
+	}
+}
+
+func TestMergeSchemaSupersetRejectsAppliesToOverride(t *testing.T) {
+	base := parseSchemaYAML(t, `
+app:
+  actions:
+    getDoc:
+      appliesTo: { resourceTypes: [Doc] }`)
+	partial := parseSchemaYAML(t, `
+app:
+  actions:
+    getDoc:
+      appliesTo: { resourceTypes: [Other] }`)
+	if _, err := MergeSchemaSuperset(base, partial); err == nil {
+		t.Fatalf("expected appliesTo override to be rejected")
+	}
+}
+
+func TestMergeSchemaSupersetRejectsTemplateOverride(t *testing.T) {
+	base := parseSchemaYAML(t, `
+app:
+  entityTypes:
+    Doc:
+      resourceEntities:
+        byId: { id: $id, type: Doc }`)
+	partial := parseSchemaYAML(t, `
+app:
+  entityTypes:
+    Doc:
+      resourceEntities:
+        byId: { id: $other, type: Doc }`)
+	if _, err := MergeSchemaSuperset(base, partial); err == nil {
+		t.Fatalf("expected resourceEntities template override to be rejected")
+	}
+	partial = parseSchemaYAML(t, `
+app:
+  entityTypes:
+    Doc:
+      resourceEntities:
+        byName: { id: $name, type: Doc }`)
+	if _, err := MergeSchemaSuperset(base, partial); err != nil {
+		t.Fatalf("adding a template should be allowed: %v", err)
+	}
+}
+
+func TestValidateSuperset(t *testing.T) {
+	merged, err := mergeOntoBase(t, partialSupersetYAML)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(merged.SupersetJSON), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if problems := ValidateSuperset(doc); len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+
+	merged, err = mergeOntoBase(t, `
+app:
+  entityTypes:
+    TenantGrant:
+      resourceEntities:
+        byTenantIdAndUserId: { id: $tenantId:$userId, type: TenantGrant }
+  actions:
+    getTenantGrant:
+      appliesTo: { resourceTypes: [TenantGrant] }
+      entityMap: { TenantGrant: byTenantIdAndUserId }
+      input:
+        appsync: { body: { tenantId: tenantId } }
+        rest: { url: '/tenant-grant/:tenantId', query: { userId: userId } }
+    deleteTenantGrant:
+      appliesTo: { resourceTypes: [TenantGrant] }
+      entityMap: { TenantGrant: missing }
+    listTenantGrants:
+      appliesTo: { resourceTypes: [TenantGrant] }
+`)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if err := json.Unmarshal([]byte(merged.SupersetJSON), &doc); err != nil {
+		t.Fatal(err)
+	}
+	problems := ValidateSuperset(doc)
+	want := []string{
+		"actions.deleteTenantGrant.entityMap.TenantGrant references missing template",
+		"actions.getTenantGrant (appsync): template requires variable $userId",
+		"actions.listTenantGrants.entityMap is required",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %v", problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i], w) {
+			t.Errorf("problem %d = %q, want prefix %q", i, problems[i], w)
+		}
+	}
+}
+
+func TestLoadSchemaPlainSchemaUnchanged(t *testing.T) {
+	src := `app:
+  entityTypes:
+    Tenant: { memberOfTypes: [Tenant] }
+    User: {}
+    Role: {}
+    GlobalRole: {}
+    TenantGrant: {}
+  actions:
+    Get: {}
+    GetTenant:
+      memberOf: [{ id: Get }]
+      appliesTo: { principalTypes: [User], resourceTypes: [Tenant] }
+`
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc any
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(doc)
+	if s.CedarJSON != string(want) || s.SupersetJSON != s.CedarJSON {
+		t.Fatalf("plain schema should be uploaded unchanged:\n got %s\nwant %s", s.CedarJSON, want)
+	}
+}
+
+func TestLoadSchemaExample(t *testing.T) {
+	s, err := LoadSchema(filepath.Join("..", "..", "infra", "authorizer", "schema.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(s.CedarJSON, `"memberOf":[{"id":"Get"}]`) {
+		t.Fatalf("shorthand memberOf not normalized: %s", s.CedarJSON)
+	}
+}
+
+func TestLoadSchemaMergesPartialSuperset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(path, []byte(partialSupersetYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(s.CedarJSON, "entityMap") || !strings.Contains(s.SupersetJSON, "entityMap") {
+		t.Fatalf("expected pruned Cedar JSON and full superset JSON")
+	}
+	if !strings.Contains(s.CedarJSON, `"TenantGrant"`) || len(s.Actions) != 2 {
+		t.Fatalf("unexpected merge result: actions=%v", s.Actions)
+	}
+}
@@ -1,12 +1,61 @@
 package provider
 
+import sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+
 // CognitoConfig captures optional Cognito-related settings for the component.
-// Currently only SES email configuration is supported; additional fields can be
-// added as the implementation grows.
 type CognitoConfig struct {
 	// Optional SES settings for configuring Cognito email sending.
 	SesConfig *CognitoSesConfig `pulumi:"sesConfig,optional"`
-	// Optional set of sign-in aliases to enable on the user pool (e.g., username, email).
-	// Present for parity with the overall project schema; not currently used by this component.
+	// Sign-in identifiers for the user pool: email, phone, preferredUsername (default: email).
 	SignInAliases []string `pulumi:"signInAliases,optional"`
+	// App clients to create on the user pool; their IDs are bound to the identity source.
+	Clients []CognitoClientConfig `pulumi:"clients,optional"`
+	// Principal entity type for identities from the user pool (default: User). Unqualified types are
+	// prefixed with the schema namespace when verifiedPermissions is configured.
+	PrincipalEntityType *string `pulumi:"principalEntityType,optional"`
+	// Optional entity type for Cognito groups (e.g., Role). Unqualified types are prefixed with the
+	// schema namespace when verifiedPermissions is configured.
+	GroupEntityType *string `pulumi:"groupEntityType,optional"`
+}
+
+// CognitoClientConfig defines a user pool app client.
+type CognitoClientConfig struct {
+	Name string `pulumi:"name"`
+	// Explicit auth flows (e.g., ALLOW_USER_SRP_AUTH, ALLOW_REFRESH_TOKEN_AUTH).
+	AuthFlows []string `pulumi:"authFlows,optional"`
+	// OAuth flows: code, implicit, client_credentials (default: code when OAuth is configured).
+	OAuthFlows   []string `pulumi:"oauthFlows,optional"`
+	OAuthScopes  []string `pulumi:"oauthScopes,optional"`
+	CallbackUrls []string `pulumi:"callbackUrls,optional"`
+	LogoutUrls   []string `pulumi:"logoutUrls,optional"`
+	// Generate a client secret (default: false).
+	GenerateSecret *bool `pulumi:"generateSecret,optional"`
+	// Access token validity in minutes (5-1440).
+	AccessTokenValidity *int `pulumi:"accessTokenValidity,optional"`
+	// ID token validity in minutes (5-1440).
+	IdTokenValidity *int `pulumi:"idTokenValidity,optional"`
+	// Refresh token validity in days (1-3650).
+	RefreshTokenValidity *int `pulumi:"refreshTokenValidity,optional"`
+}
+
+func (c CognitoClientConfig) spec() sharedavp.UserPoolClientSpec {
+	return sharedavp.UserPoolClientSpec{
+		Name:                       c.Name,
+		AuthFlows:                  c.AuthFlows,
+		OAuthFlows:                 c.OAuthFlows,
+		OAuthScopes:                c.OAuthScopes,
+		CallbackURLs:               c.CallbackUrls,
+		LogoutURLs:                 c.LogoutUrls,
+		GenerateSecret:             c.GenerateSecret != nil && *c.GenerateSecret,
+		AccessTokenValidityMinutes: intValue(c.AccessTokenValidity),
+		IdTokenValidityMinutes:     intValue(c.IdTokenValidity),
+		RefreshTokenValidityDays:   intValue(c.RefreshTokenValidity),
+	}
+}
+
+func intValue(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
 }
@@ -1,19 +1,16 @@
 package provider
 
 import (
-	"embed"
 	"fmt"
-	"path/filepath"
-	"strings"
 
 	awsvp "github.com/pulumi/pulumi-aws/sdk/v6/go/aws/verifiedpermissions"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
-)
 
-//go:embed assets/guardrails/*.cedar
-var guardrailFS embed.FS
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+)
 
 // installGuardrails installs provider-managed guardrail policies as child resources.
+// The statements come from the shared guardrail assets so both providers install identical policies.
 // - Base guardrails are always applied (unless DisableGuardrails=true).
 // - Action-enforcement guardrail is applied when actionGroupEnforcement != "off".
 func installGuardrails(
@@ -24,26 +21,19 @@ func installGuardrails(
 	namespace string,
 	agMode string,
 ) error {
-	// Load base guardrails
-	files := []string{"assets/guardrails/base.cedar"}
-	if !strings.EqualFold(agMode, "off") {
-		files = append(files, "assets/guardrails/action-enforcement.cedar")
+	guardrails, err := sharedavp.RenderGuardrails(namespace, agMode)
+	if err != nil {
+		return err
 	}
-	for _, f := range files {
-		b, err := guardrailFS.ReadFile(f)
-		if err != nil {
-			return fmt.Errorf("failed to read embedded guardrail %s: %w", f, err)
-		}
-		// Simple namespace interpolation placeholder: ${NAMESPACE}
-		text := strings.ReplaceAll(string(b), "${NAMESPACE}", namespace)
-		base := filepath.Base(f)
-		resName := fmt.Sprintf("%s-%s", name, strings.TrimSuffix(base, filepath.Ext(base)))
+	for _, g := range guardrails {
+		text := g.Statement
+		resName := fmt.Sprintf("%s-%s", name, g.Name)
 		stmt := pulumi.All(after).ApplyT(func(_ []interface{}) string { return text }).(pulumi.StringOutput)
 		if _, err := awsvp.NewPolicy(ctx, resName, &awsvp.PolicyArgs{
 			PolicyStoreId: store.ID(),
 			Definition:    &awsvp.PolicyDefinitionArgs{Static: &awsvp.PolicyDefinitionStaticArgs{Statement: stmt}},
 		}, pulumi.Parent(store)); err != nil {
-			return fmt.Errorf("failed to create guardrail policy %s: %w", base, err)
+			return fmt.Errorf("failed to create guardrail policy %s: %w", g.Name, err)
 		}
 	}
 	return nil
@@ -1,11 +1,11 @@
 package provider
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	aws "github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	awscloudwatch "github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
 	awscognito "github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cognito"
 	awsdynamodb "github.com/pulumi/pulumi-aws/sdk/v6/go/aws/dynamodb"
 	awsiam "github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
@@ -17,7 +17,9 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
 	sharedassets "github.com/mikecbrant/verified-permissions-authorizer/internal/common/assets"
+	sharedses "github.com/mikecbrant/verified-permissions-authorizer/internal/common/ses"
 )
 
 var authorizerIndexMjs = sharedassets.GetAuthorizerIndexMjs()
@@ -58,6 +60,63 @@ type LambdaConfig struct {
 	MemorySize             *int `pulumi:"memorySize,optional"`
 	ReservedConcurrency    *int `pulumi:"reservedConcurrency,optional"`
 	ProvisionedConcurrency *int `pulumi:"provisionedConcurrency,optional"`
+	// Function timeout in seconds (default: 10).
+	Timeout *int `pulumi:"timeout,optional"`
+	// CloudWatch log retention in days (default: 14).
+	LogRetentionDays *int `pulumi:"logRetentionDays,optional"`
+	// Application log level for JSON logs: TRACE, DEBUG, INFO, WARN, ERROR or FATAL (default: INFO).
+	LogLevel *string `pulumi:"logLevel,optional"`
+	// Optional KMS key ARN used to encrypt the log group.
+	LogKmsKeyArn *string `pulumi:"logKmsKeyArn,optional"`
+	// Extra environment variables; POLICY_STORE_ID is reserved.
+	Environment map[string]string `pulumi:"environment,optional"`
+	// Extra IAM statements appended to the role's least-privilege inline policy.
+	AdditionalPolicyStatements []LambdaPolicyStatement `pulumi:"additionalPolicyStatements,optional"`
+}
+
+// LambdaPolicyStatement is an extra IAM statement granted to the Lambda authorizer role.
+type LambdaPolicyStatement struct {
+	// Allow or Deny (default: Allow).
+	Effect    *string  `pulumi:"effect,optional"`
+	Actions   []string `pulumi:"actions"`
+	Resources []string `pulumi:"resources"`
+}
+
+// settings validates the configured knobs and applies the shared defaults.
+func (c *LambdaConfig) settings() (sharedavp.LambdaSettings, error) {
+	if c == nil {
+		return sharedavp.ResolveLambdaSettings(sharedavp.LambdaOptions{})
+	}
+	s, err := sharedavp.ResolveLambdaSettings(sharedavp.LambdaOptions{
+		MemorySize:             c.MemorySize,
+		ReservedConcurrency:    c.ReservedConcurrency,
+		ProvisionedConcurrency: c.ProvisionedConcurrency,
+		Timeout:                c.Timeout,
+		LogRetentionDays:       c.LogRetentionDays,
+		LogLevel:               c.LogLevel,
+		LogKmsKeyArn:           c.LogKmsKeyArn,
+		Environment:            c.Environment,
+	})
+	if err != nil {
+		return s, fmt.Errorf("lambda: %w", err)
+	}
+	return s, nil
+}
+
+// policyStatements converts the configured extra statements to their shared representation.
+func (c *LambdaConfig) policyStatements() []sharedavp.PolicyStatement {
+	if c == nil {
+		return nil
+	}
+	out := make([]sharedavp.PolicyStatement, 0, len(c.AdditionalPolicyStatements))
+	for _, s := range c.AdditionalPolicyStatements {
+		st := sharedavp.PolicyStatement{Action: s.Actions, Resource: s.Resources}
+		if s.Effect != nil {
+			st.Effect = *s.Effect
+		}
+		out = append(out, st)
+	}
+	return out
 }
 
 // DynamoConfig groups DynamoDB table-related provider options.
@@ -84,6 +143,8 @@ type AuthorizerWithPolicyStore struct {
 
 // CognitoOutputs groups optional Cognito-related outputs under the `cognito` object.
 type CognitoOutputs struct {
+	IdentitySourceArn pulumi.StringPtrOutput   `pulumi:"identitySourceArn,optional"`
+	IdentitySourceId  pulumi.StringPtrOutput   `pulumi:"identitySourceId,optional"`
 	UserPoolArn       pulumi.StringPtrOutput   `pulumi:"userPoolArn,optional"`
 	UserPoolClientIDs pulumi.StringArrayOutput `pulumi:"userPoolClientIds,optional"`
 	UserPoolId        pulumi.StringPtrOutput   `pulumi:"userPoolId,optional"`
@@ -99,6 +160,8 @@ type DynamoOutputs struct {
 type LambdaOutputs struct {
 	AuthorizerFunctionArn pulumi.StringOutput `pulumi:"authorizerFunctionArn"`
 	RoleArn               pulumi.StringOutput `pulumi:"roleArn"`
+	// SHA-256 of the bundled schema.merged.json; set when a schema is configured.
+	MergedSchemaHash pulumi.StringPtrOutput `pulumi:"mergedSchemaHash,optional"`
 }
 
 // Annotate attaches schema metadata used for provider docs and code generation.
@@ -123,6 +186,15 @@ func NewAuthorizerWithPolicyStore(
 	normalizeAuthorizerArgs(&args)
 	childOpts, retOpts := buildChildOptions(comp, opts, *args.RetainOnDelete)
 
+	// Load the schema before registering children so schema errors fail fast.
+	var vpSchema sharedavp.Schema
+	if args.VerifiedPermissions != nil {
+		var err error
+		if vpSchema, err = loadSchema(*args.VerifiedPermissions); err != nil {
+			return nil, err
+		}
+	}
+
 	store, err := createPolicyStore(ctx, name, args, retOpts)
 	if err != nil {
 		return nil, err
@@ -133,7 +205,7 @@ func NewAuthorizerWithPolicyStore(
 		return nil, err
 	}
 
-	role, fn, err := createAuthorizerLambda(ctx, name, store, childOpts)
+	lambdaOut, err := createAuthorizerLambda(ctx, name, store, table, args.Lambda, vpSchema.MergedSchema, *args.RetainOnDelete, childOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -142,21 +214,21 @@ func NewAuthorizerWithPolicyStore(
 	comp.PolicyStoreId = store.ID().ToStringOutput()
 	comp.PolicyStoreArn = store.Arn
 	comp.Dynamo = DynamoOutputs{AuthTableArn: table.Arn, AuthTableStreamArn: table.StreamArn}
-	comp.Lambda = LambdaOutputs{AuthorizerFunctionArn: fn.Arn, RoleArn: role.Arn}
+	comp.Lambda = lambdaOut
 
-	if args.Cognito != nil {
-		cog, err := createCognito(ctx, name, *args.Cognito, childOpts)
-		if err != nil {
+	// Verified Permissions schema and policy ingestion
+	if args.VerifiedPermissions != nil {
+		if err := applySchemaAndPolicies(ctx, name, store, *args.VerifiedPermissions, vpSchema); err != nil {
 			return nil, err
 		}
-		comp.Cognito = cog
 	}
 
-	// Verified Permissions schema and policy ingestion
-	if args.VerifiedPermissions != nil {
-		if err := applySchemaAndPolicies(ctx, name, store, *args.VerifiedPermissions); err != nil {
+	if args.Cognito != nil {
+		cog, err := createCognito(ctx, name, *args.Cognito, store, vpSchema.Namespace, childOpts)
+		if err != nil {
 			return nil, err
 		}
+		comp.Cognito = cog
 	}
 
 	return comp, nil
@@ -239,12 +311,25 @@ func createAuthTable(ctx *pulumi.Context, name string, args AuthorizerArgs, opts
 	return awsdynamodb.NewTable(ctx, fmt.Sprintf("%s-auth", name), targs, opts...)
 }
 
-func createAuthorizerLambda(ctx *pulumi.Context, name string, store *awsvp.PolicyStore, opts []pulumi.ResourceOption) (*awsiam.Role, *awslambda.Function, error) {
+// createAuthorizerLambda creates the role, function and log group for the bundled authorizer. The code
+// archive carries the merged schema as schema.merged.json when one is configured, so schema changes
+// redeploy the function. The log group is retained with the other stateful resources when
+// retainOnDelete is set. When provisioned concurrency is configured it is applied to a "live" alias of
+// the published version, and the alias ARN is exported as the authorizer ARN.
+func createAuthorizerLambda(ctx *pulumi.Context, name string, store *awsvp.PolicyStore, table *awsdynamodb.Table, cfg *LambdaConfig, merged sharedavp.MergedSchema, retainOnDelete bool, opts []pulumi.ResourceOption) (LambdaOutputs, error) {
+	settings, err := cfg.settings()
+	if err != nil {
+		return LambdaOutputs{}, err
+	}
+	extra := cfg.policyStatements()
+	if err := sharedavp.ValidatePolicyStatements(extra); err != nil {
+		return LambdaOutputs{}, fmt.Errorf("lambda.additionalPolicyStatements: %w", err)
+	}
 	role, err := awsiam.NewRole(ctx, fmt.Sprintf("%s-role", name), &awsiam.RoleArgs{
 		AssumeRolePolicy: pulumi.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["lambda.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`),
 	}, opts...)
 	if err != nil {
-		return nil, nil, err
+		return LambdaOutputs{}, err
 	}
 
 	_, _ = awsiam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-role-basic", name), &awsiam.RolePolicyAttachmentArgs{
@@ -252,28 +337,137 @@ func createAuthorizerLambda(ctx *pulumi.Context, name string, store *awsvp.Polic
 		Role:      role.Name,
 	}, pulumi.Parent(role))
 
+	// Least-privilege access to the policy store and auth table; statements were validated above.
+	policy := pulumi.All(store.Arn, table.Arn).ApplyT(func(args []interface{}) (string, error) {
+		return sharedavp.AuthorizerRolePolicy(args[0].(string), args[1].(string), extra)
+	}).(pulumi.StringOutput)
+	rolePolicy, err := awsiam.NewRolePolicy(ctx, fmt.Sprintf("%s-role-policy", name), &awsiam.RolePolicyArgs{
+		Name:   pulumi.String(sharedavp.AuthorizerRolePolicyName),
+		Role:   role.ID(),
+		Policy: policy,
+	}, pulumi.Parent(role))
+	if err != nil {
+		return LambdaOutputs{}, err
+	}
+
+	env := pulumi.StringMap{}
+	for k, v := range settings.Environment {
+		env[k] = pulumi.String(v)
+	}
+	env[sharedavp.PolicyStoreIdEnvVar] = store.ID().ToStringOutput()
+	code := map[string]interface{}{
+		"index.mjs": pulumi.NewStringAsset(authorizerIndexMjs),
+	}
+	if merged.SupersetJSON != "" {
+		code[sharedavp.MergedSchemaFileName] = pulumi.NewStringAsset(merged.SupersetJSON)
+	}
+	// The log group is named explicitly (the function name is auto-generated) and created first, so
+	// Lambda never creates an unmanaged default group before ours exists.
+	logArgs := &awscloudwatch.LogGroupArgs{
+		Name:            pulumi.String(sharedavp.LogGroupName(fmt.Sprintf("%s-%s-%s-authorizer", ctx.Project(), ctx.Stack(), name))),
+		RetentionInDays: pulumi.Int(settings.LogRetentionDays),
+	}
+	if settings.LogKmsKeyArn != "" {
+		logArgs.KmsKeyId = pulumi.String(settings.LogKmsKeyArn)
+	}
+	logGroup, err := awscloudwatch.NewLogGroup(ctx, fmt.Sprintf("%s-authorizer-logs", name), logArgs, append(opts, pulumi.RetainOnDelete(retainOnDelete))...)
+	if err != nil {
+		return LambdaOutputs{}, err
+	}
 	fn, err := awslambda.NewFunction(ctx, fmt.Sprintf("%s-authorizer", name), &awslambda.FunctionArgs{
-		Role:          role.Arn,
-		Runtime:       pulumi.String("nodejs22.x"),
-		Handler:       pulumi.String("index.handler"),
-		Architectures: pulumi.ToStringArray([]string{"arm64"}),
-		Timeout:       pulumi.Int(10),
-		MemorySize:    pulumi.Int(128),
-		Environment:   &awslambda.FunctionEnvironmentArgs{Variables: pulumi.StringMap{"POLICY_STORE_ID": store.ID().ToStringOutput()}},
-		Code: pulumi.NewAssetArchive(map[string]interface{}{
-			"index.mjs": pulumi.NewStringAsset(authorizerIndexMjs),
-		}),
+		Role:                         role.Arn,
+		Runtime:                      pulumi.String("nodejs22.x"),
+		Handler:                      pulumi.String("index.handler"),
+		Architectures:                pulumi.ToStringArray([]string{"arm64"}),
+		Timeout:                      pulumi.Int(settings.Timeout),
+		MemorySize:                   pulumi.Int(settings.MemorySize),
+		ReservedConcurrentExecutions: pulumi.Int(settings.ReservedConcurrency),
+		Environment:                  &awslambda.FunctionEnvironmentArgs{Variables: env},
+		LoggingConfig: &awslambda.FunctionLoggingConfigArgs{
+			LogFormat:           pulumi.String(sharedavp.LambdaLogFormat),
+			ApplicationLogLevel: pulumi.String(settings.LogLevel),
+			LogGroup:            logGroup.Name,
+		},
+		Code:    pulumi.NewAssetArchive(code),
 		Publish: pulumi.Bool(true),
-	}, opts...)
+	}, append(opts, pulumi.DependsOn([]pulumi.Resource{rolePolicy, logGroup}))...)
+	if err != nil {
+		return LambdaOutputs{}, err
+	}
+
+	var schemaHash *string
+	if merged.SupersetJSON != "" {
+		h := merged.Hash()
+		schemaHash = &h
+	}
+	out := LambdaOutputs{AuthorizerFunctionArn: fn.Arn, RoleArn: role.Arn, MergedSchemaHash: pulumi.ToOutput(schemaHash).(pulumi.StringPtrOutput)}
+	if settings.ProvisionedConcurrency == 0 {
+		return out, nil
+	}
+	alias, err := awslambda.NewAlias(ctx, fmt.Sprintf("%s-authorizer-%s", name, sharedavp.LambdaAliasName), &awslambda.AliasArgs{
+		Name:            pulumi.String(sharedavp.LambdaAliasName),
+		FunctionName:    fn.Name,
+		FunctionVersion: fn.Version,
+	}, pulumi.Parent(fn))
+	if err != nil {
+		return LambdaOutputs{}, err
+	}
+	if _, err := awslambda.NewProvisionedConcurrencyConfig(ctx, fmt.Sprintf("%s-authorizer-pc", name), &awslambda.ProvisionedConcurrencyConfigArgs{
+		FunctionName:                    fn.Name,
+		Qualifier:                       alias.Name,
+		ProvisionedConcurrentExecutions: pulumi.Int(settings.ProvisionedConcurrency),
+	}, pulumi.Parent(alias)); err != nil {
+		return LambdaOutputs{}, err
+	}
+	out.AuthorizerFunctionArn = alias.Arn
+	return out, nil
+}
+
+// createCognito provisions the user pool (with SES email sending when configured) and registers it as
+// the policy store's identity source. ns qualifies unqualified principal/group entity types.
+func createCognito(ctx *pulumi.Context, name string, cfg CognitoConfig, store *awsvp.PolicyStore, ns string, opts []pulumi.ResourceOption) (*CognitoOutputs, error) {
+	specs := make([]sharedavp.UserPoolClientSpec, 0, len(cfg.Clients))
+	for _, c := range cfg.Clients {
+		specs = append(specs, c.spec())
+	}
+	if err := sharedavp.ValidateUserPoolClients(specs); err != nil {
+		return nil, err
+	}
+	up, err := createUserPool(ctx, name, cfg, opts)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return role, fn, nil
+	clientIDs, err := createUserPoolClients(ctx, name, up, specs, opts)
+	if err != nil {
+		return nil, err
+	}
+	src, err := createIdentitySource(ctx, name, cfg, store, up, clientIDs, ns, opts)
+	if err != nil {
+		return nil, err
+	}
+	srcArn := pulumi.All(store.Arn, src.ID()).ApplyT(func(args []interface{}) string {
+		return sharedavp.IdentitySourceArn(args[0].(string), string(args[1].(pulumi.ID)))
+	}).(pulumi.StringOutput)
+	return &CognitoOutputs{
+		IdentitySourceArn: srcArn.ToStringPtrOutput(),
+		IdentitySourceId:  src.ID().ToStringPtrOutput(),
+		UserPoolArn:       up.Arn.ToStringPtrOutput(),
+		UserPoolId:        up.ID().ToStringPtrOutput(),
+		UserPoolClientIDs: clientIDs,
+	}, nil
 }
 
-func createCognito(ctx *pulumi.Context, name string, cfg CognitoConfig, opts []pulumi.ResourceOption) (*CognitoOutputs, error) {
+func createUserPool(ctx *pulumi.Context, name string, cfg CognitoConfig, opts []pulumi.ResourceOption) (*awscognito.UserPool, error) {
+	signIn, err := sharedavp.ResolveSignInAliases(cfg.SignInAliases)
+	if err != nil {
+		return nil, err
+	}
+	poolArgs := &awscognito.UserPoolArgs{
+		UsernameAttributes: pulumi.ToStringArray(signIn.UsernameAttributes),
+		AliasAttributes:    pulumi.ToStringArray(signIn.AliasAttributes),
+	}
 	if cfg.SesConfig == nil {
-		return createBareUserPool(ctx, name, opts)
+		return awscognito.NewUserPool(ctx, fmt.Sprintf("%s-userpool", name), poolArgs, opts...)
 	}
 	reg, err := aws.GetRegion(ctx, nil)
 	if err != nil {
@@ -296,22 +490,77 @@ func createCognito(ctx *pulumi.Context, name string, cfg CognitoConfig, opts []p
 		emailConf.ConfigurationSet = pulumi.StringPtr(*cfg.SesConfig.ConfigurationSet)
 	}
 
-	up, err := awscognito.NewUserPool(ctx, fmt.Sprintf("%s-userpool", name), &awscognito.UserPoolArgs{EmailConfiguration: emailConf}, opts...)
+	poolArgs.EmailConfiguration = emailConf
+	up, err := awscognito.NewUserPool(ctx, fmt.Sprintf("%s-userpool", name), poolArgs, opts...)
 	if err != nil {
 		return nil, err
 	}
 	if err := createSesIdentityPolicy(ctx, name, up, account, reg.Name, identityRegion, identity, opts); err != nil {
 		return nil, err
 	}
-	return &CognitoOutputs{UserPoolArn: up.Arn.ToStringPtrOutput(), UserPoolId: up.ID().ToStringPtrOutput(), UserPoolClientIDs: pulumi.ToStringArrayOutput([]pulumi.StringOutput{})}, nil
+	return up, nil
 }
 
-func createBareUserPool(ctx *pulumi.Context, name string, opts []pulumi.ResourceOption) (*CognitoOutputs, error) {
-	up, err := awscognito.NewUserPool(ctx, fmt.Sprintf("%s-userpool", name), &awscognito.UserPoolArgs{}, opts...)
-	if err != nil {
-		return nil, err
+// createUserPoolClients creates one app client per spec and returns their IDs in order.
+func createUserPoolClients(ctx *pulumi.Context, name string, up *awscognito.UserPool, specs []sharedavp.UserPoolClientSpec, opts []pulumi.ResourceOption) (pulumi.StringArrayOutput, error) {
+	ids := make([]pulumi.StringOutput, 0, len(specs))
+	for _, c := range specs {
+		args := &awscognito.UserPoolClientArgs{
+			UserPoolId:        up.ID(),
+			Name:              pulumi.String(c.Name),
+			ExplicitAuthFlows: pulumi.ToStringArray(c.AuthFlows),
+			GenerateSecret:    pulumi.Bool(c.GenerateSecret),
+			CallbackUrls:      pulumi.ToStringArray(c.CallbackURLs),
+			LogoutUrls:        pulumi.ToStringArray(c.LogoutURLs),
+		}
+		if c.OAuthEnabled() {
+			args.AllowedOauthFlowsUserPoolClient = pulumi.Bool(true)
+			args.AllowedOauthFlows = pulumi.ToStringArray(c.EffectiveOAuthFlows())
+			args.AllowedOauthScopes = pulumi.ToStringArray(c.OAuthScopes)
+			args.SupportedIdentityProviders = pulumi.ToStringArray([]string{"COGNITO"})
+		}
+		units := &awscognito.UserPoolClientTokenValidityUnitsArgs{}
+		if c.AccessTokenValidityMinutes > 0 {
+			args.AccessTokenValidity = pulumi.Int(c.AccessTokenValidityMinutes)
+			units.AccessToken = pulumi.String("minutes")
+		}
+		if c.IdTokenValidityMinutes > 0 {
+			args.IdTokenValidity = pulumi.Int(c.IdTokenValidityMinutes)
+			units.IdToken = pulumi.String("minutes")
+		}
+		if c.RefreshTokenValidityDays > 0 {
+			args.RefreshTokenValidity = pulumi.Int(c.RefreshTokenValidityDays)
+			units.RefreshToken = pulumi.String("days")
+		}
+		if c.AccessTokenValidityMinutes > 0 || c.IdTokenValidityMinutes > 0 || c.RefreshTokenValidityDays > 0 {
+			args.TokenValidityUnits = units
+		}
+		client, err := awscognito.NewUserPoolClient(ctx, fmt.Sprintf("%s-client-%s", name, c.Name), args, append(opts, pulumi.Parent(up))...)
+		if err != nil {
+			return pulumi.StringArrayOutput{}, fmt.Errorf("failed to create user pool client %s: %w", c.Name, err)
+		}
+		ids = append(ids, client.ID().ToStringOutput())
+	}
+	return pulumi.ToStringArrayOutput(ids), nil
+}
+
+// createIdentitySource binds the user pool and its clients to the policy store.
+func createIdentitySource(ctx *pulumi.Context, name string, cfg CognitoConfig, store *awsvp.PolicyStore, up *awscognito.UserPool, clientIDs pulumi.StringArrayOutput, ns string, opts []pulumi.ResourceOption) (*awsvp.IdentitySource, error) {
+	principal := sharedavp.QualifyEntityType(ns, valueOrDefault(cfg.PrincipalEntityType, sharedavp.DefaultPrincipalEntityType))
+	poolConf := &awsvp.IdentitySourceConfigurationCognitoUserPoolConfigurationArgs{
+		UserPoolArn: up.Arn,
+		ClientIds:   clientIDs,
+	}
+	if group := sharedavp.QualifyEntityType(ns, valueOrDefault(cfg.GroupEntityType, "")); group != "" {
+		poolConf.GroupConfiguration = &awsvp.IdentitySourceConfigurationCognitoUserPoolConfigurationGroupConfigurationArgs{
+			GroupEntityType: pulumi.String(group),
+		}
 	}
-	return &CognitoOutputs{UserPoolArn: up.Arn.ToStringPtrOutput(), UserPoolId: up.ID().ToStringPtrOutput(), UserPoolClientIDs: pulumi.ToStringArrayOutput([]pulumi.StringOutput{})}, nil
+	return awsvp.NewIdentitySource(ctx, fmt.Sprintf("%s-identity-source", name), &awsvp.IdentitySourceArgs{
+		PolicyStoreId:       store.ID(),
+		PrincipalEntityType: pulumi.String(principal),
+		Configuration:       &awsvp.IdentitySourceConfigurationArgs{CognitoUserPoolConfiguration: poolConf},
+	}, opts...)
 }
 
 func createSesIdentityPolicy(ctx *pulumi.Context, name string, up *awscognito.UserPool, account string, userPoolRegion string, identityRegion string, identityName string, opts []pulumi.ResourceOption) error {
@@ -324,18 +573,7 @@ func createSesIdentityPolicy(ctx *pulumi.Context, name string, up *awscognito.Us
 	)
 
 	policy := userPoolArn.ApplyT(func(userPoolArn string) string {
-		pol := map[string]any{
-			"Version": "2012-10-17",
-			"Statement": []map[string]any{{
-				"Effect":    "Allow",
-				"Action":    []string{"ses:SendEmail", "ses:SendRawEmail"},
-				"Principal": map[string]any{"Service": "cognito-idp.amazonaws.com"},
-				"Resource":  fmt.Sprintf("arn:%s:ses:%s:%s:identity/%s", partitionForRegion(identityRegion), identityRegion, account, identityName),
-				"Condition": map[string]any{"StringEquals": map[string]any{"AWS:SourceArn": userPoolArn}},
-			}},
-		}
-		b, _ := json.Marshal(pol)
-		return string(b)
+		return sharedses.CognitoSendingPolicy(account, identityRegion, identityName, userPoolArn)
 	}).(pulumi.StringOutput)
 	_, err := awssesv2.NewEmailIdentityPolicy(ctx, fmt.Sprintf("%s-ses-policy", name), &awssesv2.EmailIdentityPolicyArgs{
 		EmailIdentity: pulumi.String(identityName),
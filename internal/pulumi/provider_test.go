@@ -9,6 +9,8 @@ import (
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
 )
 
 type capturedResource struct {
@@ -151,6 +153,189 @@ func TestCognito_WithSesConfig_ConfiguresEmailAndPolicy(t *testing.T) {
 	}
 }
 
+func TestCognito_CreatesIdentitySource(t *testing.T) {
+	t.Parallel()
+	mocks := &testMocks{region: "us-east-1"}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Cognito: &CognitoConfig{GroupEntityType: pulumi.StringRef("Role")}})
+		return err
+	}, pulumi.WithMocks("test", "dev", mocks))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	src := findResourceInputs(mocks.resources, "aws:verifiedpermissions/identitySource:IdentitySource")
+	if src == nil {
+		t.Fatalf("identity source not created")
+	}
+	if got := src[resource.PropertyKey("principalEntityType")].StringValue(); got != "User" {
+		t.Fatalf("principalEntityType = %q, want User", got)
+	}
+	pool := src[resource.PropertyKey("configuration")].ObjectValue()[resource.PropertyKey("cognitoUserPoolConfiguration")].ObjectValue()
+	if got := pool[resource.PropertyKey("groupConfiguration")].ObjectValue()[resource.PropertyKey("groupEntityType")].StringValue(); got != "Role" {
+		t.Fatalf("groupEntityType = %q, want Role", got)
+	}
+}
+
+func TestCognito_ClientsAndSignInAliases(t *testing.T) {
+	t.Parallel()
+	mocks := &testMocks{region: "us-east-1"}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Cognito: &CognitoConfig{
+			SignInAliases: []string{"email", "preferredUsername"},
+			Clients: []CognitoClientConfig{{
+				Name:                "web",
+				AuthFlows:           []string{"ALLOW_USER_SRP_AUTH", "ALLOW_REFRESH_TOKEN_AUTH"},
+				OAuthScopes:         []string{"openid", "email"},
+				CallbackUrls:        []string{"https://app.example.com/callback"},
+				AccessTokenValidity: pulumi.IntRef(60),
+			}},
+		}})
+		return err
+	}, pulumi.WithMocks("test", "dev", mocks))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	pool := findResourceInputs(mocks.resources, cognitoUserPoolTypeToken)
+	if got := pool[resource.PropertyKey("aliasAttributes")].ArrayValue(); len(got) != 2 || got[1].StringValue() != "preferred_username" {
+		t.Fatalf("aliasAttributes = %v", got)
+	}
+	client := findResourceInputs(mocks.resources, "aws:cognito/userPoolClient:UserPoolClient")
+	if client == nil {
+		t.Fatalf("user pool client not created")
+	}
+	if flows := client[resource.PropertyKey("allowedOauthFlows")].ArrayValue(); len(flows) != 1 || flows[0].StringValue() != "code" {
+		t.Fatalf("allowedOauthFlows = %v, want [code]", flows)
+	}
+	if got := client[resource.PropertyKey("tokenValidityUnits")].ObjectValue()[resource.PropertyKey("accessToken")].StringValue(); got != "minutes" {
+		t.Fatalf("accessToken unit = %q, want minutes", got)
+	}
+	src := findResourceInputs(mocks.resources, "aws:verifiedpermissions/identitySource:IdentitySource")
+	ids := src[resource.PropertyKey("configuration")].ObjectValue()[resource.PropertyKey("cognitoUserPoolConfiguration")].ObjectValue()[resource.PropertyKey("clientIds")].ArrayValue()
+	if len(ids) != 1 || ids[0].StringValue() != "test-client-web_id" {
+		t.Fatalf("identity source clientIds = %v", ids)
+	}
+}
+
+func TestCognito_InvalidClientRejected(t *testing.T) {
+	t.Parallel()
+	mocks := &testMocks{region: "us-east-1"}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Cognito: &CognitoConfig{
+			Clients: []CognitoClientConfig{{Name: "web", CallbackUrls: []string{"http://app.example.com"}}},
+		}})
+		return err
+	}, pulumi.WithMocks("test", "dev", mocks))
+	if err == nil || !strings.Contains(err.Error(), "https") {
+		t.Fatalf("expected callback URL validation error, got %v", err)
+	}
+}
+
+func TestLambdaRole_LeastPrivilegePolicy(t *testing.T) {
+	t.Parallel()
+	mocks := &testMocks{region: "us-east-1"}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Lambda: &LambdaConfig{
+			AdditionalPolicyStatements: []LambdaPolicyStatement{{Actions: []string{"s3:GetObject"}, Resources: []string{"arn:aws:s3:::bucket/*"}}},
+		}})
+		return err
+	}, pulumi.WithMocks("test", "dev", mocks))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	policy := findResourceInputs(mocks.resources, "aws:iam/rolePolicy:RolePolicy")
+	if policy == nil {
+		t.Fatalf("role policy not created")
+	}
+	if got := policy[resource.PropertyKey("name")].StringValue(); got != "authorizer-access" {
+		t.Fatalf("role policy name = %q", got)
+	}
+
+	err = pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Lambda: &LambdaConfig{
+			AdditionalPolicyStatements: []LambdaPolicyStatement{{Actions: []string{"s3:GetObject"}}},
+		}})
+		return err
+	}, pulumi.WithMocks("test", "dev", &testMocks{region: "us-east-1"}))
+	if err == nil || !strings.Contains(err.Error(), "resource") {
+		t.Fatalf("expected statement validation error, got %v", err)
+	}
+}
+
+func TestLambda_SettingsAndProvisionedConcurrency(t *testing.T) {
+	t.Parallel()
+	mocks := &testMocks{region: "us-east-1"}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Lambda: &LambdaConfig{
+			MemorySize:             pulumi.IntRef(512),
+			ReservedConcurrency:    pulumi.IntRef(5),
+			ProvisionedConcurrency: pulumi.IntRef(2),
+			Timeout:                pulumi.IntRef(20),
+			LogRetentionDays:       pulumi.IntRef(30),
+			LogLevel:               pulumi.StringRef("debug"),
+			LogKmsKeyArn:           pulumi.StringRef("arn:aws:kms:us-east-1:123456789012:key/abcd"),
+			Environment:            map[string]string{"LOG_LEVEL": "debug"},
+		}})
+		return err
+	}, pulumi.WithMocks("test", "dev", mocks))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	fn := findResourceInputs(mocks.resources, "aws:lambda/function:Function")
+	if fn[resource.PropertyKey("memorySize")].NumberValue() != 512 || fn[resource.PropertyKey("timeout")].NumberValue() != 20 ||
+		fn[resource.PropertyKey("reservedConcurrentExecutions")].NumberValue() != 5 {
+		t.Fatalf("unexpected function inputs: %v", fn)
+	}
+	vars := fn[resource.PropertyKey("environment")].ObjectValue()[resource.PropertyKey("variables")].ObjectValue()
+	if vars[resource.PropertyKey("LOG_LEVEL")].StringValue() != "debug" {
+		t.Fatalf("environment override missing: %v", vars)
+	}
+	logging := fn[resource.PropertyKey("loggingConfig")].ObjectValue()
+	if logging[resource.PropertyKey("logFormat")].StringValue() != "JSON" || logging[resource.PropertyKey("applicationLogLevel")].StringValue() != "DEBUG" {
+		t.Fatalf("unexpected logging config: %v", logging)
+	}
+	lg := findResourceInputs(mocks.resources, "aws:cloudwatch/logGroup:LogGroup")
+	if lg == nil || lg[resource.PropertyKey("retentionInDays")].NumberValue() != 30 || lg[resource.PropertyKey("kmsKeyId")].StringValue() == "" {
+		t.Fatalf("unexpected log group: %v", lg)
+	}
+	// The log group is created before the function, which logs to it explicitly.
+	const logGroupName = "/aws/lambda/test-dev-test-authorizer"
+	if lg[resource.PropertyKey("name")].StringValue() != logGroupName || logging[resource.PropertyKey("logGroup")].StringValue() != logGroupName {
+		t.Fatalf("function must log to the managed log group: logGroup=%v loggingConfig=%v", lg, logging)
+	}
+	if lgIdx, fnIdx := resourceIndex(mocks.resources, "aws:cloudwatch/logGroup:LogGroup"), resourceIndex(mocks.resources, "aws:lambda/function:Function"); lgIdx > fnIdx {
+		t.Fatalf("log group registered after the function")
+	}
+	if findResourceInputs(mocks.resources, "aws:lambda/alias:Alias") == nil {
+		t.Fatalf("alias not created")
+	}
+	pc := findResourceInputs(mocks.resources, "aws:lambda/provisionedConcurrencyConfig:ProvisionedConcurrencyConfig")
+	if pc == nil || pc[resource.PropertyKey("provisionedConcurrentExecutions")].NumberValue() != 2 {
+		t.Fatalf("unexpected provisioned concurrency config: %v", pc)
+	}
+}
+
+func TestLambda_InvalidSettingsRejected(t *testing.T) {
+	t.Parallel()
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewAuthorizerWithPolicyStore(ctx, "test", AuthorizerArgs{Lambda: &LambdaConfig{
+			Environment: map[string]string{"POLICY_STORE_ID": "override"},
+		}})
+		return err
+	}, pulumi.WithMocks("test", "dev", &testMocks{region: "us-east-1"}))
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("expected reserved environment variable error, got %v", err)
+	}
+}
+
+func resourceIndex(resources []capturedResource, typeToken string) int {
+	for i, r := range resources {
+		if r.Type == typeToken {
+			return i
+		}
+	}
+	return -1
+}
+
 func findResourceInputs(resources []capturedResource, typeToken string) resource.PropertyMap {
 	for _, r := range resources {
 		if r.Type == typeToken {
@@ -291,6 +476,9 @@ func TestSchema_GroupedOutputs(t *testing.T) {
 	if _, ok := lamProps["roleArn"]; !ok {
 		t.Fatalf("expected lambda.roleArn in schema")
 	}
+	if _, ok := lamProps["mergedSchemaHash"]; !ok {
+		t.Fatalf("expected lambda.mergedSchemaHash in schema")
+	}
 	ddb := props["dynamo"].(map[string]any)
 	ddbProps, _ := ddb["properties"].(map[string]any)
 	if _, ok := ddbProps["authTableArn"]; !ok {
@@ -309,3 +497,35 @@ func TestSchema_GroupedOutputs(t *testing.T) {
 		}
 	}
 }
+
+func TestLambda_BundlesMergedSchema(t *testing.T) {
+	t.Parallel()
+	mocks := &testMocks{region: "us-east-1"}
+	merged := sharedavp.MergedSchema{Namespace: "app", SupersetJSON: `{"app":{}}`, CedarJSON: `{"app":{}}`}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		args := AuthorizerArgs{}
+		normalizeAuthorizerArgs(&args)
+		store, err := createPolicyStore(ctx, "test", args, nil)
+		if err != nil {
+			return err
+		}
+		table, err := createAuthTable(ctx, "test", args, nil)
+		if err != nil {
+			return err
+		}
+		_, err = createAuthorizerLambda(ctx, "test", store, table, nil, merged, false, nil)
+		return err
+	}, pulumi.WithMocks("test", "dev", mocks))
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	fn := findResourceInputs(mocks.resources, "aws:lambda/function:Function")
+	assets := fn[resource.PropertyKey("code")].ArchiveValue().Assets
+	if _, ok := assets["index.mjs"]; !ok {
+		t.Fatalf("index.mjs missing from code archive: %v", assets)
+	}
+	schema, ok := assets[sharedavp.MergedSchemaFileName].(*resource.Asset)
+	if !ok || schema.Text != merged.SupersetJSON {
+		t.Fatalf("schema.merged.json missing from code archive: %v", assets)
+	}
+}
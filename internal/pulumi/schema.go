@@ -28,37 +28,35 @@ type VerifiedPermissionsConfig struct {
 	CanaryFile *string `pulumi:"canaryFile,optional"`
 }
 
-// canonical action group identifiers (PascalCase + Global* variants)
-var canonicalActionGroups = []string{
-	"BatchCreate", "Create", "BatchDelete", "Delete", "Find", "Get", "BatchUpdate", "Update",
-	"GlobalBatchCreate", "GlobalCreate", "GlobalBatchDelete", "GlobalDelete", "GlobalFind", "GlobalGet", "GlobalBatchUpdate", "GlobalUpdate",
-}
-
-// applySchemaAndPolicies loads schema/policies from disk, performs validations, applies schema if changed,
-// and creates static policies as Pulumi resources bound to the created policy store.
-func applySchemaAndPolicies(ctx *pulumi.Context, name string, store *awsvp.PolicyStore, cfg VerifiedPermissionsConfig) error {
-	schemaPath, policyDir, err := resolveSchemaAndPolicyPaths(cfg)
+// loadSchema loads and validates the configured schema file (see sharedavp.LoadSchema).
+func loadSchema(cfg VerifiedPermissionsConfig) (sharedavp.Schema, error) {
+	schemaPath, _, err := resolveSchemaAndPolicyPaths(cfg)
 	if err != nil {
-		return err
+		return sharedavp.Schema{}, err
 	}
+	return sharedavp.LoadSchema(schemaPath)
+}
 
-	// Read and parse schema (YAML or JSON → JSON string)
-	cedarJSON, ns, actions, warns, err := sharedavp.LoadAndValidateSchema(schemaPath)
+// applySchemaAndPolicies performs validations on the loaded schema, applies its Cedar JSON if changed,
+// and creates static policies as Pulumi resources bound to the created policy store.
+func applySchemaAndPolicies(ctx *pulumi.Context, name string, store *awsvp.PolicyStore, cfg VerifiedPermissionsConfig, vpSchema sharedavp.Schema) error {
+	_, policyDir, err := resolveSchemaAndPolicyPaths(cfg)
 	if err != nil {
 		return err
 	}
-	if err := warnAll(ctx, prefixAll("AVP: ", warns)); err != nil {
+	ns := vpSchema.Namespace
+	if err := warnAll(ctx, prefixAll("AVP: ", vpSchema.Warnings)); err != nil {
 		return err
 	}
 
-	// Action-group enforcement (schema-level, based on action names)
-	agMode, err := enforceActionGroups(ctx, actions, cfg)
+	// Action-group enforcement (schema-level, based on the actions' memberOf graph)
+	agMode, err := enforceActionGroups(ctx, vpSchema.ActionMemberOf, cfg)
 	if err != nil {
 		return err
 	}
 
 	// Apply schema if changed (best-effort drift detection via GetSchema comparison)
-	schemaApplied := applySchemaIfChanged(ctx, store, cedarJSON, ns)
+	schemaApplied := applySchemaIfChanged(ctx, store, vpSchema.CedarJSON, ns)
 
 	// Collect policy files (*.cedar under policyDir)
 	files, err := collectPolicyFiles(ctx, policyDir)
@@ -117,21 +115,17 @@ func warnAll(ctx *pulumi.Context, msgs []string) error {
 	return nil
 }
 
-func enforceActionGroups(ctx *pulumi.Context, actions []string, cfg VerifiedPermissionsConfig) (string, error) {
+func enforceActionGroups(ctx *pulumi.Context, memberOf map[string][]string, cfg VerifiedPermissionsConfig) (string, error) {
 	agMode := strings.ToLower(valueOrDefault(cfg.ActionGroupEnforcement, "error"))
-	violations, err := sharedavp.EnforceActionGroups(actions, agMode)
+	report, err := sharedavp.EnforceActionGroups(memberOf, agMode)
 	if err != nil {
 		return "", err
 	}
-	if len(violations) > 0 && agMode == "warn" {
-		msg := fmt.Sprintf(
-			"AVP: actions not aligned to canonical action groups %v: %s",
-			canonicalActionGroups,
-			strings.Join(violations, ", "),
-		)
+	if len(report.Violations) > 0 && agMode == "warn" {
+		msg := fmt.Sprintf("AVP: actions not aligned to canonical action groups: %s", strings.Join(report.Violations, "; "))
 		_ = ctx.Log.Warn(msg, &pulumi.LogArgs{})
 	}
-	return agMode, nil
+	return agMode, warnAll(ctx, prefixAll("AVP: ", report.NameMismatches))
 }
 
 func applySchemaIfChanged(ctx *pulumi.Context, store *awsvp.PolicyStore, cedarJSON string, ns string) pulumi.StringOutput {
@@ -230,17 +224,9 @@ func maybeExportCanaryStatus(ctx *pulumi.Context, name string, store *awsvp.Poli
 }
 
 func resolveCanaryFile(cfg VerifiedPermissionsConfig) (string, bool) {
-	if cfg.CanaryFile != nil && strings.TrimSpace(*cfg.CanaryFile) != "" {
-		return *cfg.CanaryFile, true
-	}
-
-	newDef := "./authorizer/canaries.yaml"
-	oldDef := "./authorize/canaries.yaml"
-	if _, err := os.Stat(newDef); err == nil {
-		return newDef, true
-	}
-	if _, err := os.Stat(oldDef); err == nil {
-		return oldDef, true
+	explicit := ""
+	if cfg.CanaryFile != nil {
+		explicit = *cfg.CanaryFile
 	}
-	return "", false
+	return sharedavp.ResolveCanaryFile(explicit)
 }
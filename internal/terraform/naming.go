@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// maxNamePrefixLength leaves room for the longest child suffix within the 64-character
+// IAM role and Lambda function name limits.
+const maxNamePrefixLength = 32
+
+var namePrefixRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// childNames are the deterministic names of the AWS resources owned by one authorizer.
+type childNames struct {
+	prefix         string
+	table          string
+	role           string
+	function       string
+	userPool       string
+	userPoolClient string
+	sesPolicy      string
+}
+
+// namesForPrefix derives child resource names from a prefix; the suffixes mirror the
+// Pulumi component's child resource names.
+func namesForPrefix(prefix string) childNames {
+	return childNames{
+		prefix:         prefix,
+		table:          prefix + "-auth",
+		role:           prefix + "-role",
+		function:       prefix + "-authorizer",
+		userPool:       prefix + "-userpool",
+		userPoolClient: prefix + "-client",
+		sesPolicy:      prefix + "-cognito-send",
+	}
+}
+
+// generateNamePrefix returns a random prefix used when name_prefix is not configured.
+func generateNamePrefix() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate name prefix: %w", err)
+	}
+	return "vpa-" + hex.EncodeToString(b), nil
+}
+
+func validateNamePrefix(prefix string) error {
+	if len(prefix) > maxNamePrefixLength {
+		return fmt.Errorf("name_prefix %q must be at most %d characters", prefix, maxNamePrefixLength)
+	}
+	if !namePrefixRe.MatchString(prefix) {
+		return fmt.Errorf("name_prefix %q must start with a letter or digit and contain only letters, digits, '-' or '_'", prefix)
+	}
+	return nil
+}
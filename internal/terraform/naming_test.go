@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamesForPrefix(t *testing.T) {
+	n := namesForPrefix("acme")
+	if n.table != "acme-auth" || n.role != "acme-role" || n.function != "acme-authorizer" || n.userPool != "acme-userpool" {
+		t.Fatalf("unexpected names: %+v", n)
+	}
+}
+
+func TestGenerateNamePrefix(t *testing.T) {
+	a, err := generateNamePrefix()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := generateNamePrefix()
+	if a == b || !strings.HasPrefix(a, "vpa-") {
+		t.Fatalf("expected distinct vpa- prefixes, got %q and %q", a, b)
+	}
+	if err := validateNamePrefix(a); err != nil {
+		t.Fatalf("generated prefix invalid: %v", err)
+	}
+}
+
+func TestValidateNamePrefix(t *testing.T) {
+	for _, bad := range []string{"", "-lead", "has space", "dots.not.allowed", strings.Repeat("a", maxNamePrefixLength+1)} {
+		if err := validateNamePrefix(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
@@ -54,9 +54,17 @@ func (p *vpaProvider) DataSources(_ context.Context) []func() datasource.DataSou
 type (
 	// LambdaBlock captures optional Lambda configuration inputs.
 	LambdaBlock struct {
-		MemorySize             types.Int64 `tfsdk:"memory_size"`
-		ReservedConcurrency    types.Int64 `tfsdk:"reserved_concurrency"`
-		ProvisionedConcurrency types.Int64 `tfsdk:"provisioned_concurrency"`
+		MemorySize             types.Int64  `tfsdk:"memory_size"`
+		ReservedConcurrency    types.Int64  `tfsdk:"reserved_concurrency"`
+		ProvisionedConcurrency types.Int64  `tfsdk:"provisioned_concurrency"`
+		Timeout                types.Int64  `tfsdk:"timeout"`
+		LogRetentionDays       types.Int64  `tfsdk:"log_retention_days"`
+		LogLevel               types.String `tfsdk:"log_level"`
+		LogKmsKeyArn           types.String `tfsdk:"log_kms_key_arn"`
+		// Environment holds extra environment variables merged with POLICY_STORE_ID.
+		Environment types.Map `tfsdk:"environment"`
+		// AdditionalPolicyStatements are appended to the role's least-privilege inline policy.
+		AdditionalPolicyStatements types.List `tfsdk:"additional_policy_statements"`
 	}
 	// DynamoBlock captures DynamoDB options.
 	DynamoBlock struct {
@@ -71,8 +79,10 @@ type (
 	}
 	// CognitoBlock captures Cognito configuration.
 	CognitoBlock struct {
-		SignInAliases types.List       `tfsdk:"sign_in_aliases"`
-		SesConfig     *CognitoSesBlock `tfsdk:"ses_config"`
+		SignInAliases       types.List       `tfsdk:"sign_in_aliases"`
+		PrincipalEntityType types.String     `tfsdk:"principal_entity_type"`
+		GroupEntityType     types.String     `tfsdk:"group_entity_type"`
+		SesConfig           *CognitoSesBlock `tfsdk:"ses_config"`
 	}
 	// VerifiedPermissionsBlock configures AVP schema/policies/guardrails.
 	VerifiedPermissionsBlock struct {
@@ -10,21 +10,19 @@ import (
 	"strings"
 	"time"
 
-	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
-	awscfg "github.com/aws/aws-sdk-go-v2/config"
 	// "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	// "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
-	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
 	vptypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
 
@@ -34,6 +32,7 @@ import (
 
 var _ resource.Resource = (*authorizerResource)(nil)
 var _ resource.ResourceWithImportState = (*authorizerResource)(nil)
+var _ resource.ResourceWithModifyPlan = (*authorizerResource)(nil)
 
 // NewAuthorizerResource creates the main Terraform resource for this provider.
 func NewAuthorizerResource() resource.Resource { return &authorizerResource{} }
@@ -44,6 +43,7 @@ type authorizerModel struct {
 	ID                  types.String              `tfsdk:"id"`
 	Description         types.String              `tfsdk:"description"`
 	RetainOnDelete      types.Bool                `tfsdk:"retain_on_delete"`
+	NamePrefix          types.String              `tfsdk:"name_prefix"`
 	Lambda              *LambdaBlock              `tfsdk:"lambda"`
 	Dynamo              *DynamoBlock              `tfsdk:"dynamo"`
 	Cognito             *CognitoBlock             `tfsdk:"cognito"`
@@ -62,6 +62,15 @@ type authorizerModel struct {
 	CognitoUserPoolId        types.String `tfsdk:"cognito_user_pool_id"`
 	CognitoUserPoolArn       types.String `tfsdk:"cognito_user_pool_arn"`
 	CognitoUserPoolClientIDs types.List   `tfsdk:"cognito_user_pool_client_ids"`
+	CognitoIdentitySourceId  types.String `tfsdk:"cognito_identity_source_id"`
+	MergedSchemaHash         types.String `tfsdk:"merged_schema_hash"`
+
+	// Child resource names/IDs owned by this resource
+	DynamoTableName    types.String `tfsdk:"dynamo_table_name"`
+	LambdaRoleName     types.String `tfsdk:"lambda_role_name"`
+	LambdaFunctionName types.String `tfsdk:"lambda_function_name"`
+	PolicyIDs          types.Map    `tfsdk:"policy_ids"`
+	GuardrailPolicyIDs types.Map    `tfsdk:"guardrail_policy_ids"`
 }
 
 func (r *authorizerResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -75,24 +84,53 @@ func (r *authorizerResource) Schema(_ context.Context, _ resource.SchemaRequest,
 			"id":               schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
 			"description":      schema.StringAttribute{Optional: true},
 			"retain_on_delete": schema.BoolAttribute{Optional: true},
+			"name_prefix": schema.StringAttribute{
+				Optional:      true,
+				Computed:      true,
+				Description:   "Prefix for the names of all child resources (table, role, function). Generated when omitted; changing it forces replacement.",
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace(), stringplanmodifier.UseStateForUnknown()},
+			},
 			// Outputs
-			"policy_store_id":              schema.StringAttribute{Computed: true},
-			"policy_store_arn":             schema.StringAttribute{Computed: true},
+			"policy_store_id":              schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
+			"policy_store_arn":             schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
 			"parameters":                   schema.MapAttribute{Computed: true, ElementType: types.StringType},
-			"lambda_authorizer_arn":        schema.StringAttribute{Computed: true},
-			"lambda_role_arn":              schema.StringAttribute{Computed: true},
-			"dynamo_table_arn":             schema.StringAttribute{Computed: true},
+			"lambda_authorizer_arn":        schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
+			"lambda_role_arn":              schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
+			"dynamo_table_arn":             schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
 			"dynamo_stream_arn":            schema.StringAttribute{Computed: true},
 			"cognito_user_pool_id":         schema.StringAttribute{Computed: true},
 			"cognito_user_pool_arn":        schema.StringAttribute{Computed: true},
 			"cognito_user_pool_client_ids": schema.ListAttribute{Computed: true, ElementType: types.StringType},
+			"cognito_identity_source_id":   schema.StringAttribute{Computed: true, Description: "Verified Permissions identity source bound to the Cognito user pool."},
+			"merged_schema_hash":           schema.StringAttribute{Computed: true, Description: "SHA-256 of the merged schema bundled with the authorizer as schema.merged.json; changes redeploy the function code."},
+			"dynamo_table_name":            schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
+			"lambda_role_name":             schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
+			"lambda_function_name":         schema.StringAttribute{Computed: true, PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()}},
+			"policy_ids":                   schema.MapAttribute{Computed: true, ElementType: types.StringType, Description: "Verified Permissions policy IDs keyed by policy file path relative to policy_dir."},
+			"guardrail_policy_ids":         schema.MapAttribute{Computed: true, ElementType: types.StringType, Description: "Provider-managed guardrail policy IDs keyed by guardrail name."},
 		},
 		Blocks: map[string]schema.Block{
 			"lambda": schema.SingleNestedBlock{
 				Attributes: map[string]schema.Attribute{
 					"memory_size":             schema.Int64Attribute{Optional: true},
 					"reserved_concurrency":    schema.Int64Attribute{Optional: true},
-					"provisioned_concurrency": schema.Int64Attribute{Optional: true},
+					"provisioned_concurrency": schema.Int64Attribute{Optional: true, Description: "Provisioned concurrency applied to a `live` alias of the published version (default 0, disabled); must be <= reserved_concurrency."},
+					"timeout":                 schema.Int64Attribute{Optional: true, Description: "Function timeout in seconds (1-900, default 10)."},
+					"log_retention_days":      schema.Int64Attribute{Optional: true, Description: "CloudWatch log retention in days (default 14)."},
+					"log_level":               schema.StringAttribute{Optional: true, Description: "Application log level for JSON logs: TRACE, DEBUG, INFO, WARN, ERROR or FATAL (default INFO)."},
+					"log_kms_key_arn":         schema.StringAttribute{Optional: true, Description: "KMS key ARN used to encrypt the log group; the key policy must allow CloudWatch Logs."},
+					"environment":             schema.MapAttribute{Optional: true, ElementType: types.StringType, Description: "Extra environment variables; POLICY_STORE_ID and Lambda-reserved names cannot be set."},
+					"additional_policy_statements": schema.ListNestedAttribute{
+						Optional:    true,
+						Description: "Extra IAM statements appended to the Lambda role's least-privilege inline policy.",
+						NestedObject: schema.NestedAttributeObject{
+							Attributes: map[string]schema.Attribute{
+								"effect":    schema.StringAttribute{Optional: true, Description: "Allow or Deny (default Allow)."},
+								"actions":   schema.ListAttribute{Required: true, ElementType: types.StringType},
+								"resources": schema.ListAttribute{Required: true, ElementType: types.StringType},
+							},
+						},
+					},
 				},
 			},
 			"dynamo": schema.SingleNestedBlock{
@@ -102,7 +140,9 @@ func (r *authorizerResource) Schema(_ context.Context, _ resource.SchemaRequest,
 			},
 			"cognito": schema.SingleNestedBlock{
 				Attributes: map[string]schema.Attribute{
-					"sign_in_aliases": schema.ListAttribute{Optional: true, ElementType: types.StringType},
+					"sign_in_aliases":       schema.ListAttribute{Optional: true, ElementType: types.StringType, Description: "Sign-in identifiers for the user pool: email, phone, preferredUsername (default email). Cannot be changed after the pool is created."},
+					"principal_entity_type": schema.StringAttribute{Optional: true, Description: "Principal entity type for user pool identities (default User); unqualified types are prefixed with the schema namespace."},
+					"group_entity_type":     schema.StringAttribute{Optional: true, Description: "Optional entity type for Cognito groups; unqualified types are prefixed with the schema namespace."},
 				},
 				Blocks: map[string]schema.Block{
 					"ses_config": schema.SingleNestedBlock{
@@ -136,102 +176,151 @@ func (r *authorizerResource) Create(ctx context.Context, req resource.CreateRequ
 	}
 	lambdaSettings, err := resolveLambdaSettings(plan.Lambda)
 	if err != nil {
-		resp.Diagnostics.AddError("Invalid lambda concurrency settings", err.Error())
+		resp.Diagnostics.AddError("Invalid lambda settings", err.Error())
+		return
+	}
+	if err := validateAdditionalPolicyStatements(plan.Lambda); err != nil {
+		resp.Diagnostics.AddError("Invalid lambda additional_policy_statements", err.Error())
 		return
 	}
 
-	cfg, err := awscfg.LoadDefaultConfig(ctx)
+	prefix := plan.NamePrefix.ValueString()
+	if plan.NamePrefix.IsUnknown() || plan.NamePrefix.IsNull() {
+		if prefix, err = generateNamePrefix(); err != nil {
+			resp.Diagnostics.AddError("Name prefix generation failed", err.Error())
+			return
+		}
+	}
+	if err := validateNamePrefix(prefix); err != nil {
+		resp.Diagnostics.AddError("Invalid name_prefix", err.Error())
+		return
+	}
+	names := namesForPrefix(prefix)
+
+	// Load the schema before creating anything so schema errors fail fast.
+	vpSchema, err := loadSchema(plan.VerifiedPermissions)
+	if err != nil {
+		resp.Diagnostics.AddError("Verified permissions config failed", err.Error())
+		return
+	}
+
+	clients, err := newAWSClients(ctx)
 	if err != nil {
 		resp.Diagnostics.AddError("AWS config error", err.Error())
 		return
 	}
-	region := cfg.Region
 
-	vp := verifiedpermissions.NewFromConfig(cfg)
-	psId, psArn, err := createPolicyStore(ctx, vp)
+	psId, psArn, err := createPolicyStore(ctx, clients.vp, plan.Description.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("Create policy store failed", err.Error())
 		return
 	}
 
-	ddb := dynamodb.NewFromConfig(cfg)
-	_, tableArn, err := createAndDescribeDynamoTable(ctx, ddb)
-	if err != nil {
+	// recordState saves everything created so far. It also runs when a later step fails, so the partially
+	// created authorizer is tracked (and tainted) and Delete cleans its children up instead of leaking them.
+	created := createdResources{policyStoreId: psId, policyStoreArn: psArn}
+	recordState := func() {
+		setCreatedState(&plan, names, created)
+		plan.MergedSchemaHash = mergedSchemaHash(vpSchema)
+		nullUnknownComputed(&plan)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	}
+
+	if created.table, err = createAndDescribeDynamoTable(ctx, clients.ddb, names.table, resolveTableSettings(&plan)); err != nil {
 		resp.Diagnostics.AddError("Create DynamoDB table failed", err.Error())
+		recordState()
 		return
 	}
 
-	roleArn, err := createLambdaRole(ctx, iam.NewFromConfig(cfg))
-	if err != nil {
+	if created.roleArn, err = createLambdaRole(ctx, clients.iam, names.role); err != nil {
 		resp.Diagnostics.AddError("Create IAM role failed", err.Error())
+		recordState()
+		return
+	}
+	if err := putLambdaRolePolicy(ctx, clients.iam, names.role, psArn, created.table.arn, plan.Lambda); err != nil {
+		resp.Diagnostics.AddError("Put IAM role policy failed", err.Error())
+		recordState()
 		return
 	}
 
-	fnArn, err := createLambdaFunction(ctx, lambda.NewFromConfig(cfg), roleArn, psId, lambdaSettings)
-	if err != nil {
+	if created.fnArn, err = createLambdaFunction(ctx, clients, names.function, created.roleArn, psId, lambdaSettings, mergedSchemaJSON(vpSchema)); err != nil {
 		resp.Diagnostics.AddError("Create Lambda failed", err.Error())
+		recordState()
 		return
 	}
 
-	// 5) Optionally apply schema/policies and guardrails
+	// 5) Optionally create Cognito and bind it to the policy store
+	if plan.Cognito != nil {
+		created.cognito, err = createCognito(ctx, clients, names, plan.Cognito, psId, schemaNamespace(vpSchema))
+		if err != nil {
+			resp.Diagnostics.AddError("Create Cognito failed", err.Error())
+			recordState()
+			return
+		}
+	}
+
+	// 6) Optionally apply schema/policies and guardrails
 	if plan.VerifiedPermissions != nil {
-		warns, err := applyVerifiedPermissions(ctx, psId, region, plan.VerifiedPermissions)
+		var warns []string
+		created.managed, warns, err = applyVerifiedPermissions(ctx, clients, psId, plan.VerifiedPermissions, vpSchema, managedPolicies{})
 		if err != nil {
-			resp.Diagnostics.AddError("Verified permissions config failed", err.Error())
+			addAWSError(&resp.Diagnostics, "Verified permissions config failed", err)
+			recordState()
 			return
 		}
 		for _, w := range warns {
 			resp.Diagnostics.AddWarning("AVP", w)
 		}
 	}
+	recordState()
 
-	// Outputs
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), types.StringValue(psId))...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("policy_store_id"), types.StringValue(psId))...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("policy_store_arn"), types.StringValue(psArn))...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("lambda_authorizer_arn"), types.StringValue(fnArn))...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("lambda_role_arn"), types.StringValue(roleArn))...)
-	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("dynamo_table_arn"), types.StringValue(tableArn))...)
-	if resp.Diagnostics.HasError() {
-		return
+	// 7) Canaries run last; failures fail the apply but the resources are already recorded in state.
+	if plan.VerifiedPermissions != nil && !resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(runCanaries(ctx, clients, psId, plan.VerifiedPermissions)...)
 	}
 }
 
-type lambdaSettings struct {
-	memorySize          int64
-	reservedConcurrency int64
-	provisioned         int64
+// createdResources tracks the children Create has made so far; empty fields were not created.
+type createdResources struct {
+	policyStoreId  string
+	policyStoreArn string
+	table          tableInfo
+	roleArn        string
+	fnArn          string
+	cognito        cognitoInfo
+	managed        managedPolicies
 }
 
-func resolveLambdaSettings(plan *LambdaBlock) (lambdaSettings, error) {
-	mem := int64(128)
-	rc := int64(1)
-	pc := int64(0)
-	if plan != nil {
-		if !plan.MemorySize.IsNull() {
-			mem = plan.MemorySize.ValueInt64()
-		}
-		if !plan.ReservedConcurrency.IsNull() {
-			rc = plan.ReservedConcurrency.ValueInt64()
-		}
-		if !plan.ProvisionedConcurrency.IsNull() {
-			pc = plan.ProvisionedConcurrency.ValueInt64()
-		}
-	}
-	if pc > 0 && rc < pc {
-		return lambdaSettings{}, fmt.Errorf(
-			"provisioned_concurrency (%d) must be <= reserved_concurrency (%d)",
-			pc,
-			rc,
-		)
-	}
-	return lambdaSettings{memorySize: mem, reservedConcurrency: rc, provisioned: pc}, nil
+// setCreatedState records the created children in m, leaving those that do not exist yet null.
+func setCreatedState(m *authorizerModel, names childNames, c createdResources) {
+	m.ID = types.StringValue(c.policyStoreId)
+	m.PolicyStoreId = types.StringValue(c.policyStoreId)
+	m.PolicyStoreArn = types.StringValue(c.policyStoreArn)
+	m.NamePrefix = types.StringValue(names.prefix)
+	m.DynamoTableName = stringValueOrNull(c.table.name)
+	m.DynamoTableArn = stringValueOrNull(c.table.arn)
+	m.DynamoStreamArn = stringValueOrNull(c.table.streamArn)
+	m.LambdaRoleName, m.LambdaRoleArn = types.StringNull(), types.StringNull()
+	if c.roleArn != "" {
+		m.LambdaRoleName, m.LambdaRoleArn = types.StringValue(names.role), types.StringValue(c.roleArn)
+	}
+	m.LambdaFunctionName, m.LambdaAuthorizerArn = types.StringNull(), types.StringNull()
+	if c.fnArn != "" {
+		m.LambdaFunctionName, m.LambdaAuthorizerArn = types.StringValue(names.function), types.StringValue(c.fnArn)
+	}
+	m.PolicyIDs = policyIDsValue(c.managed.policies)
+	m.GuardrailPolicyIDs = policyIDsValue(c.managed.guardrails)
+	setCognitoState(m, c.cognito)
 }
 
-func createPolicyStore(ctx context.Context, client *verifiedpermissions.Client) (policyStoreId string, policyStoreArn string, err error) {
-	out, err := client.CreatePolicyStore(ctx, &verifiedpermissions.CreatePolicyStoreInput{
+func createPolicyStore(ctx context.Context, client *verifiedpermissions.Client, description string) (policyStoreId string, policyStoreArn string, err error) {
+	in := &verifiedpermissions.CreatePolicyStoreInput{
 		ValidationSettings: &vptypes.ValidationSettings{Mode: vptypes.ValidationModeStrict},
-	})
+	}
+	if strings.TrimSpace(description) != "" {
+		in.Description = awsString(description)
+	}
+	out, err := client.CreatePolicyStore(ctx, in)
 	if err != nil {
 		return "", "", err
 	}
@@ -243,9 +332,32 @@ func createPolicyStore(ctx context.Context, client *verifiedpermissions.Client)
 	return psId, psArn, nil
 }
 
-func createAndDescribeDynamoTable(ctx context.Context, client *dynamodb.Client) (tableName string, tableArn string, err error) {
-	tableName = fmt.Sprintf("%s-tenant-%d", "vpa", time.Now().Unix())
-	_, err = client.CreateTable(ctx, &dynamodb.CreateTableInput{
+// tableSettings captures the reconcilable DynamoDB table options.
+type tableSettings struct {
+	enableStream   bool
+	retainOnDelete bool
+}
+
+func resolveTableSettings(m *authorizerModel) tableSettings {
+	s := tableSettings{retainOnDelete: m.RetainOnDelete.ValueBool()}
+	if m.Dynamo != nil {
+		s.enableStream = m.Dynamo.EnableDynamoDbStream.ValueBool()
+	}
+	return s
+}
+
+// tableInfo holds the identifiers of the provider-managed DynamoDB table.
+type tableInfo struct {
+	name      string
+	arn       string
+	streamArn string
+}
+
+// tableWaitTimeout bounds how long we wait for the auth table to become ACTIVE.
+const tableWaitTimeout = 5 * time.Minute
+
+func createAndDescribeDynamoTable(ctx context.Context, client *dynamodb.Client, tableName string, settings tableSettings) (tableInfo, error) {
+	in := &dynamodb.CreateTableInput{
 		TableName:   &tableName,
 		BillingMode: dynamodbtypes.BillingModePayPerRequest,
 		AttributeDefinitions: []dynamodbtypes.AttributeDefinition{
@@ -261,25 +373,68 @@ func createAndDescribeDynamoTable(ctx context.Context, client *dynamodb.Client)
 			{IndexName: awsString("GSI1"), KeySchema: []dynamodbtypes.KeySchemaElement{{AttributeName: awsString("GSI1PK"), KeyType: dynamodbtypes.KeyTypeHash}, {AttributeName: awsString("GSI1SK"), KeyType: dynamodbtypes.KeyTypeRange}}, Projection: &dynamodbtypes.Projection{ProjectionType: dynamodbtypes.ProjectionTypeAll}},
 			{IndexName: awsString("GSI2"), KeySchema: []dynamodbtypes.KeySchemaElement{{AttributeName: awsString("GSI2PK"), KeyType: dynamodbtypes.KeyTypeHash}, {AttributeName: awsString("GSI2SK"), KeyType: dynamodbtypes.KeyTypeRange}}, Projection: &dynamodbtypes.Projection{ProjectionType: dynamodbtypes.ProjectionTypeAll}},
 		},
-	})
+		DeletionProtectionEnabled: awsBool(settings.retainOnDelete),
+	}
+	if settings.enableStream {
+		in.StreamSpecification = &dynamodbtypes.StreamSpecification{StreamEnabled: awsBool(true), StreamViewType: dynamodbtypes.StreamViewTypeNewAndOldImages}
+	}
+	out, err := client.CreateTable(ctx, in)
 	if err != nil {
-		return "", "", err
+		return tableInfo{}, err
+	}
+	// Once the table exists, errors still return its identifiers so the caller can track it for cleanup.
+	created := tableInfo{name: tableName}
+	if out.TableDescription != nil {
+		created.arn = awsStringValue(out.TableDescription.TableArn)
 	}
+	if err := dynamodb.NewTableExistsWaiter(client).Wait(ctx, &dynamodb.DescribeTableInput{TableName: &tableName}, tableWaitTimeout); err != nil {
+		return created, fmt.Errorf("waiting for table %s to become active: %w", tableName, err)
+	}
+	if settings.retainOnDelete {
+		if err := setPointInTimeRecovery(ctx, client, tableName, true); err != nil {
+			return created, err
+		}
+	}
+	info, err := describeDynamoTable(ctx, client, tableName)
+	if err != nil {
+		return created, err
+	}
+	return info, nil
+}
+
+func describeDynamoTable(ctx context.Context, client *dynamodb.Client, tableName string) (tableInfo, error) {
 	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &tableName})
 	if err != nil {
-		return "", "", fmt.Errorf("describe table failed for %s: %w", tableName, err)
+		return tableInfo{}, fmt.Errorf("describe table failed for %s: %w", tableName, err)
 	}
 	if desc.Table == nil || desc.Table.TableArn == nil {
-		return "", "", fmt.Errorf("describe table missing TableArn for %s", tableName)
+		return tableInfo{}, fmt.Errorf("describe table missing TableArn for %s", tableName)
+	}
+	info := tableInfo{name: tableName, arn: *desc.Table.TableArn}
+	if spec := desc.Table.StreamSpecification; spec != nil && spec.StreamEnabled != nil && *spec.StreamEnabled {
+		info.streamArn = awsStringValue(desc.Table.LatestStreamArn)
+	}
+	return info, nil
+}
+
+func setPointInTimeRecovery(ctx context.Context, client *dynamodb.Client, tableName string, enabled bool) error {
+	_, err := client.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+		TableName:                        &tableName,
+		PointInTimeRecoverySpecification: &dynamodbtypes.PointInTimeRecoverySpecification{PointInTimeRecoveryEnabled: awsBool(enabled)},
+	})
+	if err != nil {
+		return fmt.Errorf("update point-in-time recovery failed for %s: %w", tableName, err)
 	}
-	return tableName, *desc.Table.TableArn, nil
+	return nil
 }
 
-func createLambdaRole(ctx context.Context, client *iam.Client) (roleArn string, err error) {
+// createLambdaRole creates the Lambda execution role. Once the role exists its ARN is returned even on
+// error so the caller can track it for cleanup.
+func createLambdaRole(ctx context.Context, client *iam.Client, roleName string) (roleArn string, err error) {
 	assume := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["lambda.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`
 	roleOut, err := client.CreateRole(ctx, &iam.CreateRoleInput{
 		AssumeRolePolicyDocument: &assume,
-		RoleName:                 awsString(fmt.Sprintf("vpa-role-%d", time.Now().Unix())),
+		RoleName:                 &roleName,
 	})
 	if err != nil {
 		return "", err
@@ -288,7 +443,7 @@ func createLambdaRole(ctx context.Context, client *iam.Client) (roleArn string,
 		RoleName:  roleOut.Role.RoleName,
 		PolicyArn: awsString("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"),
 	}); err != nil {
-		return "", fmt.Errorf(
+		return awsStringValue(roleOut.Role.Arn), fmt.Errorf(
 			"attach role policy failed (policy=%s role=%s): %w",
 			"AWSLambdaBasicExecutionRole",
 			awsStringValue(roleOut.Role.RoleName),
@@ -298,40 +453,99 @@ func createLambdaRole(ctx context.Context, client *iam.Client) (roleArn string,
 	return awsStringValue(roleOut.Role.Arn), nil
 }
 
-func createLambdaFunction(ctx context.Context, client *lambda.Client, roleArn string, policyStoreId string, settings lambdaSettings) (functionArn string, err error) {
-	zbuf, err := buildLambdaZip()
+// putLambdaRolePolicy writes the least-privilege inline policy (policy store and auth table access plus any
+// additional statements) to the Lambda role, replacing the previous version.
+func putLambdaRolePolicy(ctx context.Context, client *iam.Client, roleName string, policyStoreArn string, tableArn string, cfg *LambdaBlock) error {
+	stmts, _ := additionalPolicyStatements(cfg)
+	doc, err := sharedavp.AuthorizerRolePolicy(policyStoreArn, tableArn, stmts)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("lambda.additional_policy_statements: %w", err)
 	}
-	fnName := fmt.Sprintf("vpa-authorizer-%d", time.Now().Unix())
-	publish := settings.provisioned > 0
-	out, err := client.CreateFunction(ctx, &lambda.CreateFunctionInput{
-		FunctionName:  &fnName,
-		Role:          &roleArn,
-		Runtime:       lambdatypes.RuntimeNodejs20x,
-		Handler:       awsString("index.handler"),
-		Code:          &lambdatypes.FunctionCode{ZipFile: zbuf},
-		Architectures: []lambdatypes.Architecture{lambdatypes.ArchitectureArm64},
-		Timeout:       awsInt32(10),
-		MemorySize:    awsInt32(int32(settings.memorySize)),
-		Publish:       publish,
-		Environment:   &lambdatypes.Environment{Variables: map[string]string{"POLICY_STORE_ID": policyStoreId}},
-	})
-	if err != nil {
-		return "", err
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       &roleName,
+		PolicyName:     awsString(sharedavp.AuthorizerRolePolicyName),
+		PolicyDocument: &doc,
+	}); err != nil {
+		return fmt.Errorf("put role policy failed (policy=%s role=%s): %w", sharedavp.AuthorizerRolePolicyName, roleName, err)
+	}
+	return nil
+}
+
+// additionalPolicyStatements returns the configured extra statements. known is false while any part is
+// unknown (resource ARNs usually come from other resources), in which case validation must wait.
+func additionalPolicyStatements(cfg *LambdaBlock) (stmts []sharedavp.PolicyStatement, known bool) {
+	if cfg == nil || cfg.AdditionalPolicyStatements.IsNull() {
+		return nil, true
+	}
+	if cfg.AdditionalPolicyStatements.IsUnknown() {
+		return nil, false
 	}
-	return awsStringValue(out.FunctionArn), nil
+	for _, v := range cfg.AdditionalPolicyStatements.Elements() {
+		obj, ok := v.(types.Object)
+		if !ok || obj.IsUnknown() {
+			return nil, false
+		}
+		attrs := obj.Attributes()
+		effect, ok := attrs["effect"].(types.String)
+		if !ok || effect.IsUnknown() {
+			return nil, false
+		}
+		actions, ok := stringListValues(attrs["actions"])
+		if !ok {
+			return nil, false
+		}
+		resources, ok := stringListValues(attrs["resources"])
+		if !ok {
+			return nil, false
+		}
+		stmts = append(stmts, sharedavp.PolicyStatement{Effect: effect.ValueString(), Action: actions, Resource: resources})
+	}
+	return stmts, true
 }
 
-func buildLambdaZip() ([]byte, error) {
+// validateAdditionalPolicyStatements validates the extra statements once they are known.
+func validateAdditionalPolicyStatements(cfg *LambdaBlock) error {
+	stmts, known := additionalPolicyStatements(cfg)
+	if !known {
+		return nil
+	}
+	return sharedavp.ValidatePolicyStatements(stmts)
+}
+
+// stringListValues converts a list of strings; ok is false while the list or an element is unknown.
+func stringListValues(v attr.Value) (out []string, ok bool) {
+	l, ok := v.(types.List)
+	if !ok || l.IsUnknown() {
+		return nil, false
+	}
+	for _, e := range l.Elements() {
+		s, ok := e.(types.String)
+		if !ok || s.IsUnknown() {
+			return nil, false
+		}
+		out = append(out, s.ValueString())
+	}
+	return out, true
+}
+
+// buildLambdaZip packages index.mjs and, when a schema is configured, the merged superset as
+// schema.merged.json. Entries carry no timestamps, so the archive (and code hash) only changes with
+// its contents.
+func buildLambdaZip(mergedJSON string) ([]byte, error) {
 	zbuf := new(bytes.Buffer)
 	zw := zip.NewWriter(zbuf)
-	f, err := zw.Create("index.mjs")
-	if err != nil {
-		return nil, err
+	files := [][2]string{{"index.mjs", sharedassets.GetAuthorizerIndexMjs()}}
+	if mergedJSON != "" {
+		files = append(files, [2]string{sharedavp.MergedSchemaFileName, mergedJSON})
 	}
-	if _, err := f.Write([]byte(sharedassets.GetAuthorizerIndexMjs())); err != nil {
-		return nil, err
+	for _, file := range files {
+		f, err := zw.Create(file[0])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write([]byte(file[1])); err != nil {
+			return nil, err
+		}
 	}
 	if err := zw.Close(); err != nil {
 		return nil, err
@@ -339,32 +553,160 @@ func buildLambdaZip() ([]byte, error) {
 	return zbuf.Bytes(), nil
 }
 
-func applyVerifiedPermissions(ctx context.Context, policyStoreId string, region string, cfg *VerifiedPermissionsBlock) ([]string, error) {
-	schemaPath, policyDir, err := resolveVerifiedPermissionsPaths(cfg)
+// managedPolicies holds the IDs of provider-managed static policies recorded in state.
+type managedPolicies struct {
+	// policies maps policy file paths relative to policy_dir to policy IDs.
+	policies map[string]string
+	// guardrails maps guardrail names to policy IDs.
+	guardrails map[string]string
+}
+
+// loadSchema loads and validates the configured schema file, or returns nil when no
+// verified_permissions block is configured.
+func loadSchema(cfg *VerifiedPermissionsBlock) (*sharedavp.Schema, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	schemaPath, _, err := resolveVerifiedPermissionsPaths(cfg)
 	if err != nil {
 		return nil, err
 	}
-
-	cedarJSON, _, actions, warns, err := sharedavp.LoadAndValidateSchema(schemaPath)
+	s, err := sharedavp.LoadSchema(schemaPath)
 	if err != nil {
 		return nil, fmt.Errorf("schema error: %w", err)
 	}
+	return &s, nil
+}
+
+// schemaNamespace returns the namespace of a loaded schema, or "" when no schema is configured.
+func schemaNamespace(s *sharedavp.Schema) string {
+	if s == nil {
+		return ""
+	}
+	return s.Namespace
+}
+
+// mergedSchemaJSON returns the merged superset bundled with the authorizer, or "" when no schema is configured.
+func mergedSchemaJSON(s *sharedavp.Schema) string {
+	if s == nil {
+		return ""
+	}
+	return s.SupersetJSON
+}
+
+// mergedSchemaHash returns the merged_schema_hash value for a loaded schema (null when none is configured).
+func mergedSchemaHash(s *sharedavp.Schema) types.String {
+	if s == nil {
+		return types.StringNull()
+	}
+	return types.StringValue(s.Hash())
+}
+
+// applyVerifiedPermissions puts the Cedar JSON of the loaded schema (see loadSchema), installs
+// guardrails and reconciles the static policies in the store with the .cedar files under policy_dir.
+// tracked holds the IDs recorded in state; when cfg is nil the previously managed policies and
+// guardrails are removed.
+func applyVerifiedPermissions(ctx context.Context, clients *awsClients, policyStoreId string, cfg *VerifiedPermissionsBlock, vpSchema *sharedavp.Schema, tracked managedPolicies) (managedPolicies, []string, error) {
+	var out managedPolicies
+	if cfg == nil {
+		ids, _, err := sharedavp.ReconcilePolicies(ctx, clients.vp, policyStoreId, sharedavp.PolicyMarker, nil, tracked.policies)
+		if err != nil {
+			return out, nil, fmt.Errorf("policy reconciliation failed: %w", err)
+		}
+		out.policies = ids
+		out.guardrails, err = sharedavp.InstallGuardrails(ctx, clients.vp, policyStoreId, "", "", true, tracked.guardrails)
+		if err != nil {
+			return out, nil, err
+		}
+		return out, nil, nil
+	}
+
+	_, policyDir, err := resolveVerifiedPermissionsPaths(cfg)
+	if err != nil {
+		return out, nil, err
+	}
+
+	ns := vpSchema.Namespace
+	warns := append([]string{}, vpSchema.Warnings...)
+	agMode := actionGroupMode(cfg)
+	report, err := sharedavp.EnforceActionGroups(vpSchema.ActionMemberOf, agMode)
+	if err != nil {
+		return out, nil, fmt.Errorf("action group enforcement: %w", err)
+	}
+	if len(report.Violations) > 0 && agMode == "warn" {
+		warns = append(warns, fmt.Sprintf("actions not aligned to canonical action groups: %s", strings.Join(report.Violations, "; ")))
+	}
+	warns = append(warns, report.NameMismatches...)
+	if err := sharedavp.PutSchemaIfChanged(ctx, policyStoreId, vpSchema.CedarJSON, clients.region); err != nil {
+		return out, nil, fmt.Errorf("put schema failed: %w", err)
+	}
+
+	disableGuardrails := cfg.DisableGuardrails.ValueBool()
+	if disableGuardrails {
+		warns = append(warns, "Guardrails disabled: provider will not install deny guardrail policies")
+	}
+	out.guardrails, err = sharedavp.InstallGuardrails(ctx, clients.vp, policyStoreId, ns, agMode, disableGuardrails, tracked.guardrails)
+	if err != nil {
+		return out, nil, err
+	}
+
+	desired, err := sharedavp.LoadPolicyFiles(policyDir)
+	if err != nil {
+		return out, nil, fmt.Errorf("policy discovery failed: %w", err)
+	}
+	out.policies, _, err = sharedavp.ReconcilePolicies(ctx, clients.vp, policyStoreId, sharedavp.PolicyMarker, desired, tracked.policies)
+	if err != nil {
+		return out, nil, fmt.Errorf("policy reconciliation failed: %w", err)
+	}
+	return out, warns, nil
+}
+
+// runCanaries executes provider and consumer canaries against the store, retrying throttled calls like
+// the Pulumi provider, and returns one error diagnostic per failing case. Canaries are skipped when no
+// canary file applies.
+func runCanaries(ctx context.Context, clients *awsClients, policyStoreId string, cfg *VerifiedPermissionsBlock) diag.Diagnostics {
+	var diags diag.Diagnostics
+	canaryPath, ok := sharedavp.ResolveCanaryFile(cfg.CanaryFile.ValueString())
+	if !ok {
+		return diags
+	}
+	failures, err := sharedavp.RunCanaries(ctx, clients.vp, policyStoreId, canaryPath, actionGroupMode(cfg))
+	if err != nil {
+		diags.AddError("Canaries failed", err.Error())
+		return diags
+	}
+	for _, f := range failures {
+		diags.AddError("Canary failed", f.Error())
+	}
+	return diags
+}
+
+// actionGroupMode returns the normalized action_group_enforcement mode (default "error").
+func actionGroupMode(cfg *VerifiedPermissionsBlock) string {
 	agMode := strings.ToLower(strings.TrimSpace(cfg.ActionGroupEnforcement.ValueString()))
 	if agMode == "" {
 		agMode = "error"
 	}
-	if violations, err := sharedavp.EnforceActionGroups(actions, agMode); err != nil {
-		return nil, fmt.Errorf("action group enforcement: %w", err)
-	} else if len(violations) > 0 && agMode == "warn" {
-		warns = append(warns, fmt.Sprintf("actions not aligned to canonical action groups: %s", strings.Join(violations, ", ")))
-	}
-	if err := sharedavp.PutSchemaIfChanged(ctx, policyStoreId, cedarJSON, region); err != nil {
-		return nil, fmt.Errorf("put schema failed: %w", err)
+	return agMode
+}
+
+// policyIDsValue converts reconciled policy IDs into the policy_ids state value.
+func policyIDsValue(ids map[string]string) types.Map {
+	elems := make(map[string]attr.Value, len(ids))
+	for k, v := range ids {
+		elems[k] = types.StringValue(v)
 	}
-	if _, err := sharedavp.CollectPolicyFiles(policyDir); err != nil {
-		return nil, fmt.Errorf("policy discovery failed: %w", err)
+	return types.MapValueMust(types.StringType, elems)
+}
+
+// trackedPolicyIDs reads policy_ids from prior state; null or unknown yields an empty map.
+func trackedPolicyIDs(ctx context.Context, m types.Map) (map[string]string, diag.Diagnostics) {
+	out := map[string]string{}
+	if m.IsNull() || m.IsUnknown() {
+		return out, nil
 	}
-	return warns, nil
+	diags := m.ElementsAs(ctx, &out, false)
+	return out, diags
 }
 
 func resolveVerifiedPermissionsPaths(cfg *VerifiedPermissionsBlock) (schemaPath string, policyDir string, err error) {
@@ -389,17 +731,10 @@ func resolveVerifiedPermissionsPaths(cfg *VerifiedPermissionsBlock) (schemaPath
 	}
 	return schemaPath, policyDir, nil
 }
-func (r *authorizerResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
-}
-func (r *authorizerResource) Update(_ context.Context, _ resource.UpdateRequest, _ *resource.UpdateResponse) {
-}
-func (r *authorizerResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
-}
-func (r *authorizerResource) ImportState(_ context.Context, _ resource.ImportStateRequest, _ *resource.ImportStateResponse) {
-}
 
 func awsString(s string) *string { return &s }
 func awsInt32(v int32) *int32    { return &v }
+func awsBool(v bool) *bool       { return &v }
 
 // awsStringValue safely dereferences an AWS SDK *string, returning an empty string when nil.
 func awsStringValue(p *string) string {
@@ -0,0 +1,401 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	cognitotypes "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
+	vptypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+
+	"github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk"
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+	sharedses "github.com/mikecbrant/verified-permissions-authorizer/internal/common/ses"
+)
+
+// cognitoInfo holds the identifiers of the Cognito resources owned by one authorizer.
+type cognitoInfo struct {
+	userPoolId       string
+	userPoolArn      string
+	clientIds        []string
+	identitySourceId string
+}
+
+// sesIdentity is a validated SES identity used for Cognito developer email sending.
+type sesIdentity struct {
+	account string
+	name    string
+	region  string
+}
+
+// resolveSesIdentity validates the ses_config block against the user pool region; it returns nil when
+// SES is not configured.
+func resolveSesIdentity(cfg *CognitoBlock, userPoolRegion string) (*sesIdentity, error) {
+	if cfg == nil || cfg.SesConfig == nil {
+		return nil, nil
+	}
+	account, identity, identityRegion, err := sharedses.ValidateSesConfig(
+		cfg.SesConfig.SourceArn.ValueString(),
+		cfg.SesConfig.From.ValueString(),
+		cfg.SesConfig.ReplyToEmail.ValueStringPointer(),
+		userPoolRegion,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &sesIdentity{account: account, name: identity, region: identityRegion}, nil
+}
+
+// emailConfiguration mirrors the Pulumi component: DEVELOPER sending through the configured SES identity.
+func emailConfiguration(cfg *CognitoBlock) *cognitotypes.EmailConfigurationType {
+	if cfg == nil || cfg.SesConfig == nil {
+		return nil
+	}
+	return &cognitotypes.EmailConfigurationType{
+		EmailSendingAccount: cognitotypes.EmailSendingAccountTypeDeveloper,
+		SourceArn:           cfg.SesConfig.SourceArn.ValueStringPointer(),
+		From:                cfg.SesConfig.From.ValueStringPointer(),
+		ReplyToEmailAddress: cfg.SesConfig.ReplyToEmail.ValueStringPointer(),
+		ConfigurationSet:    cfg.SesConfig.ConfigurationSet.ValueStringPointer(),
+	}
+}
+
+// createCognito creates the user pool, the SES identity policy (when SES is configured), the default
+// user pool client and a Verified Permissions identity source bound to the policy store.
+func createCognito(ctx context.Context, clients *awsClients, names childNames, cfg *CognitoBlock, policyStoreId string, ns string) (cognitoInfo, error) {
+	ses, err := resolveSesIdentity(cfg, clients.region)
+	if err != nil {
+		return cognitoInfo{}, err
+	}
+
+	signIn, err := sharedavp.ResolveSignInAliases(signInAliases(cfg))
+	if err != nil {
+		return cognitoInfo{}, err
+	}
+	pool, err := clients.cognito.CreateUserPool(ctx, &cognitoidentityprovider.CreateUserPoolInput{
+		PoolName:           awsString(names.userPool),
+		EmailConfiguration: emailConfiguration(cfg),
+		UsernameAttributes: usernameAttributes(signIn.UsernameAttributes),
+		AliasAttributes:    aliasAttributes(signIn.AliasAttributes),
+	})
+	if err != nil {
+		return cognitoInfo{}, fmt.Errorf("create user pool failed for %s: %w", names.userPool, err)
+	}
+	info := cognitoInfo{
+		userPoolId:  awsStringValue(pool.UserPool.Id),
+		userPoolArn: awsStringValue(pool.UserPool.Arn),
+	}
+
+	if ses != nil {
+		if err := putSesIdentityPolicy(ctx, *ses, names.sesPolicy, info.userPoolArn); err != nil {
+			return info, err
+		}
+	}
+
+	client, err := clients.cognito.CreateUserPoolClient(ctx, &cognitoidentityprovider.CreateUserPoolClientInput{
+		UserPoolId:     &info.userPoolId,
+		ClientName:     awsString(names.userPoolClient),
+		GenerateSecret: false,
+	})
+	if err != nil {
+		return info, fmt.Errorf("create user pool client failed for %s: %w", names.userPoolClient, err)
+	}
+	info.clientIds = []string{awsStringValue(client.UserPoolClient.ClientId)}
+
+	principal, group := identitySourceEntityTypes(cfg, ns)
+	poolConf := vptypes.CognitoUserPoolConfiguration{
+		UserPoolArn: &info.userPoolArn,
+		ClientIds:   info.clientIds,
+	}
+	if group != "" {
+		poolConf.GroupConfiguration = &vptypes.CognitoGroupConfiguration{GroupEntityType: &group}
+	}
+	src, err := clients.vp.CreateIdentitySource(ctx, &verifiedpermissions.CreateIdentitySourceInput{
+		PolicyStoreId:       &policyStoreId,
+		PrincipalEntityType: &principal,
+		Configuration:       &vptypes.ConfigurationMemberCognitoUserPoolConfiguration{Value: poolConf},
+	})
+	if err != nil {
+		return info, fmt.Errorf("create identity source failed for %s: %w", info.userPoolId, err)
+	}
+	info.identitySourceId = awsStringValue(src.IdentitySourceId)
+	return info, nil
+}
+
+// signInAliases returns the configured sign-in aliases, or the default when none are set.
+func signInAliases(cfg *CognitoBlock) []string {
+	if cfg == nil || cfg.SignInAliases.IsNull() || cfg.SignInAliases.IsUnknown() || len(cfg.SignInAliases.Elements()) == 0 {
+		return sharedavp.DefaultSignInAliases
+	}
+	out := make([]string, 0, len(cfg.SignInAliases.Elements()))
+	for _, v := range cfg.SignInAliases.Elements() {
+		if s, ok := v.(types.String); ok {
+			out = append(out, s.ValueString())
+		}
+	}
+	return out
+}
+
+func usernameAttributes(attrs []string) []cognitotypes.UsernameAttributeType {
+	out := make([]cognitotypes.UsernameAttributeType, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, cognitotypes.UsernameAttributeType(a))
+	}
+	return out
+}
+
+func aliasAttributes(attrs []string) []cognitotypes.AliasAttributeType {
+	out := make([]cognitotypes.AliasAttributeType, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, cognitotypes.AliasAttributeType(a))
+	}
+	return out
+}
+
+// putSesIdentityPolicy allows the user pool to send through the SES identity. The SES client is created
+// in the identity's region, which may differ from the user pool region.
+func putSesIdentityPolicy(ctx context.Context, ses sesIdentity, policyName string, userPoolArn string) error {
+	client, err := sesClient(ctx, ses.region)
+	if err != nil {
+		return err
+	}
+	policy := sharedses.CognitoSendingPolicy(ses.account, ses.region, ses.name, userPoolArn)
+	_, err = client.CreateEmailIdentityPolicy(ctx, &sesv2.CreateEmailIdentityPolicyInput{
+		EmailIdentity: &ses.name,
+		PolicyName:    &policyName,
+		Policy:        &policy,
+	})
+	if err != nil && isAlreadyExists(err) {
+		_, err = client.UpdateEmailIdentityPolicy(ctx, &sesv2.UpdateEmailIdentityPolicyInput{
+			EmailIdentity: &ses.name,
+			PolicyName:    &policyName,
+			Policy:        &policy,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("put SES identity policy failed for %s: %w", ses.name, err)
+	}
+	return nil
+}
+
+func deleteSesIdentityPolicy(ctx context.Context, ses sesIdentity, policyName string) error {
+	client, err := sesClient(ctx, ses.region)
+	if err != nil {
+		return err
+	}
+	if _, err := client.DeleteEmailIdentityPolicy(ctx, &sesv2.DeleteEmailIdentityPolicyInput{EmailIdentity: &ses.name, PolicyName: &policyName}); err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete SES identity policy failed for %s: %w", ses.name, err)
+	}
+	return nil
+}
+
+func sesClient(ctx context.Context, region string) (*sesv2.Client, error) {
+	cfg, err := awssdk.LoadDefault(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return sesv2.NewFromConfig(cfg), nil
+}
+
+// updateCognito applies cognito block changes in place: the block being added or removed creates or
+// deletes the Cognito resources, sign_in_aliases changes are rejected (Cognito fixes them at pool
+// creation), ses_config changes update the pool's email configuration and the
+// SES identity policy, and entity type changes update the identity source.
+func updateCognito(ctx context.Context, clients *awsClients, names childNames, plan *authorizerModel, state *authorizerModel, ns string) error {
+	switch {
+	case plan.Cognito == nil && !hasCognitoState(state):
+		return nil
+	case plan.Cognito == nil:
+		if err := deleteCognito(ctx, clients, names, state); err != nil {
+			return err
+		}
+		setCognitoState(plan, cognitoInfo{})
+		return nil
+	case state.Cognito == nil || state.CognitoUserPoolId.ValueString() == "":
+		// Remove whatever a previously failed creation left behind before creating afresh.
+		if err := deleteCognito(ctx, clients, names, state); err != nil {
+			return err
+		}
+		setCognitoState(plan, cognitoInfo{})
+		info, err := createCognito(ctx, clients, names, plan.Cognito, plan.PolicyStoreId.ValueString(), ns)
+		setCognitoState(plan, info)
+		return err
+	}
+
+	if !slices.Equal(signInAliases(plan.Cognito), signInAliases(state.Cognito)) {
+		return fmt.Errorf("cognito.sign_in_aliases cannot be changed after the user pool is created; remove and re-add the cognito block to recreate the user pool")
+	}
+	if err := syncIdentitySource(ctx, clients, plan.Cognito, state, ns); err != nil {
+		return err
+	}
+	if sesConfigEqual(plan.Cognito.SesConfig, state.Cognito.SesConfig) {
+		return nil
+	}
+	next, err := resolveSesIdentity(plan.Cognito, clients.region)
+	if err != nil {
+		return err
+	}
+	prev, err := resolveSesIdentity(state.Cognito, clients.region)
+	if err != nil {
+		return err
+	}
+	poolId := state.CognitoUserPoolId.ValueString()
+	// UpdateUserPool resets omitted settings to their defaults; the pool is created with defaults apart
+	// from the email configuration, so sending only the email configuration preserves it.
+	email := emailConfiguration(plan.Cognito)
+	if email == nil {
+		email = &cognitotypes.EmailConfigurationType{EmailSendingAccount: cognitotypes.EmailSendingAccountTypeCognitoDefault}
+	}
+	if _, err := clients.cognito.UpdateUserPool(ctx, &cognitoidentityprovider.UpdateUserPoolInput{UserPoolId: &poolId, EmailConfiguration: email}); err != nil {
+		return fmt.Errorf("update user pool failed for %s: %w", poolId, err)
+	}
+	if prev != nil && (next == nil || prev.name != next.name || prev.region != next.region) {
+		if err := deleteSesIdentityPolicy(ctx, *prev, names.sesPolicy); err != nil {
+			return err
+		}
+	}
+	if next != nil {
+		return putSesIdentityPolicy(ctx, *next, names.sesPolicy, state.CognitoUserPoolArn.ValueString())
+	}
+	return nil
+}
+
+func sesConfigEqual(a *CognitoSesBlock, b *CognitoSesBlock) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.SourceArn.Equal(b.SourceArn) && a.From.Equal(b.From) && a.ReplyToEmail.Equal(b.ReplyToEmail) && a.ConfigurationSet.Equal(b.ConfigurationSet)
+}
+
+// deleteCognito removes the identity source, the SES identity policy and the user pool (which also
+// removes its clients). Missing resources are ignored.
+func deleteCognito(ctx context.Context, clients *awsClients, names childNames, state *authorizerModel) error {
+	psId := state.PolicyStoreId.ValueString()
+	if srcId := state.CognitoIdentitySourceId.ValueString(); srcId != "" && psId != "" {
+		if _, err := clients.vp.DeleteIdentitySource(ctx, &verifiedpermissions.DeleteIdentitySourceInput{PolicyStoreId: &psId, IdentitySourceId: &srcId}); err != nil && !isNotFound(err) {
+			return fmt.Errorf("delete identity source failed for %s: %w", srcId, err)
+		}
+	}
+	ses, err := resolveSesIdentity(state.Cognito, clients.region)
+	if err != nil {
+		return err
+	}
+	if ses != nil {
+		if err := deleteSesIdentityPolicy(ctx, *ses, names.sesPolicy); err != nil {
+			return err
+		}
+	}
+	if poolId := state.CognitoUserPoolId.ValueString(); poolId != "" {
+		if _, err := clients.cognito.DeleteUserPool(ctx, &cognitoidentityprovider.DeleteUserPoolInput{UserPoolId: &poolId}); err != nil && !isNotFound(err) {
+			return fmt.Errorf("delete user pool failed for %s: %w", poolId, err)
+		}
+	}
+	return nil
+}
+
+// refreshCognitoUserPool updates the pool ARN; it returns a description of the pool when it no longer exists.
+func refreshCognitoUserPool(ctx context.Context, clients *awsClients, state *authorizerModel) (string, error) {
+	poolId := state.CognitoUserPoolId.ValueString()
+	if poolId == "" {
+		return "", nil
+	}
+	out, err := clients.cognito.DescribeUserPool(ctx, &cognitoidentityprovider.DescribeUserPoolInput{UserPoolId: &poolId})
+	if isNotFound(err) {
+		state.CognitoUserPoolArn = types.StringNull()
+		return fmt.Sprintf("Cognito user pool %s", poolId), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("describe user pool failed for %s: %w", poolId, err)
+	}
+	state.CognitoUserPoolArn = types.StringValue(awsStringValue(out.UserPool.Arn))
+	return "", nil
+}
+
+// identitySourceEntityTypes resolves the principal (default User) and optional group entity types,
+// qualifying unqualified types with the schema namespace.
+func identitySourceEntityTypes(cfg *CognitoBlock, ns string) (principal string, group string) {
+	principal = sharedavp.QualifyEntityType(ns, strOrDefault(cfg.PrincipalEntityType.ValueString(), sharedavp.DefaultPrincipalEntityType))
+	group = sharedavp.QualifyEntityType(ns, cfg.GroupEntityType.ValueString())
+	return principal, group
+}
+
+// syncIdentitySource updates the identity source when its principal or group entity type differs from config.
+func syncIdentitySource(ctx context.Context, clients *awsClients, cfg *CognitoBlock, state *authorizerModel, ns string) error {
+	psId := state.PolicyStoreId.ValueString()
+	srcId := state.CognitoIdentitySourceId.ValueString()
+	if srcId == "" {
+		return nil
+	}
+	cur, err := clients.vp.GetIdentitySource(ctx, &verifiedpermissions.GetIdentitySourceInput{PolicyStoreId: &psId, IdentitySourceId: &srcId})
+	if err != nil {
+		return fmt.Errorf("get identity source failed for %s: %w", srcId, err)
+	}
+	pool, ok := cur.Configuration.(*vptypes.ConfigurationDetailMemberCognitoUserPoolConfiguration)
+	if !ok {
+		return fmt.Errorf("identity source %s is not backed by a Cognito user pool", srcId)
+	}
+	curGroup := ""
+	if pool.Value.GroupConfiguration != nil {
+		curGroup = awsStringValue(pool.Value.GroupConfiguration.GroupEntityType)
+	}
+	principal, group := identitySourceEntityTypes(cfg, ns)
+	if awsStringValue(cur.PrincipalEntityType) == principal && curGroup == group {
+		return nil
+	}
+	update := vptypes.UpdateCognitoUserPoolConfiguration{
+		UserPoolArn: pool.Value.UserPoolArn,
+		ClientIds:   pool.Value.ClientIds,
+	}
+	if group != "" {
+		update.GroupConfiguration = &vptypes.UpdateCognitoGroupConfiguration{GroupEntityType: &group}
+	}
+	_, err = clients.vp.UpdateIdentitySource(ctx, &verifiedpermissions.UpdateIdentitySourceInput{
+		PolicyStoreId:       &psId,
+		IdentitySourceId:    &srcId,
+		PrincipalEntityType: &principal,
+		UpdateConfiguration: &vptypes.UpdateConfigurationMemberCognitoUserPoolConfiguration{Value: update},
+	})
+	if err != nil {
+		return fmt.Errorf("update identity source failed for %s: %w", srcId, err)
+	}
+	return nil
+}
+
+// hasCognitoState reports whether m configures Cognito or still tracks Cognito resources, such as those
+// left by a failed creation.
+func hasCognitoState(m *authorizerModel) bool {
+	return m.Cognito != nil || m.CognitoUserPoolId.ValueString() != "" || m.CognitoIdentitySourceId.ValueString() != ""
+}
+
+// cognitoFailureState is the state recorded when updateCognito fails: the prior state, so the change is
+// planned again, with the Cognito identifiers that exist now so nothing created is lost track of.
+func cognitoFailureState(state authorizerModel, plan *authorizerModel) authorizerModel {
+	state.CognitoUserPoolId = plan.CognitoUserPoolId
+	state.CognitoUserPoolArn = plan.CognitoUserPoolArn
+	state.CognitoUserPoolClientIDs = plan.CognitoUserPoolClientIDs
+	state.CognitoIdentitySourceId = plan.CognitoIdentitySourceId
+	nullUnknownComputed(&state)
+	return state
+}
+
+// setCognitoState records Cognito identifiers; an empty info clears them.
+func setCognitoState(m *authorizerModel, info cognitoInfo) {
+	m.CognitoUserPoolId = stringValueOrNull(info.userPoolId)
+	m.CognitoUserPoolArn = stringValueOrNull(info.userPoolArn)
+	m.CognitoIdentitySourceId = stringValueOrNull(info.identitySourceId)
+	if info.userPoolId == "" {
+		m.CognitoUserPoolClientIDs = types.ListNull(types.StringType)
+		return
+	}
+	ids := make([]attr.Value, 0, len(info.clientIds))
+	for _, id := range info.clientIds {
+		ids = append(ids, types.StringValue(id))
+	}
+	m.CognitoUserPoolClientIDs = types.ListValueMust(types.StringType, ids)
+}
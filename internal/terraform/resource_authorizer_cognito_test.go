@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestSesConfigEqual(t *testing.T) {
+	a := &CognitoSesBlock{SourceArn: types.StringValue("arn"), From: types.StringValue("a@example.com")}
+	b := &CognitoSesBlock{SourceArn: types.StringValue("arn"), From: types.StringValue("a@example.com")}
+	if !sesConfigEqual(a, b) || !sesConfigEqual(nil, nil) {
+		t.Fatalf("expected equal SES configs")
+	}
+	b.ReplyToEmail = types.StringValue("reply@example.com")
+	if sesConfigEqual(a, b) || sesConfigEqual(a, nil) {
+		t.Fatalf("expected SES configs to differ")
+	}
+}
+
+func TestSetCognitoState(t *testing.T) {
+	var m authorizerModel
+	setCognitoState(&m, cognitoInfo{userPoolId: "pool", userPoolArn: "arn", clientIds: []string{"c1"}, identitySourceId: "src"})
+	if m.CognitoUserPoolId.ValueString() != "pool" || m.CognitoIdentitySourceId.ValueString() != "src" || len(m.CognitoUserPoolClientIDs.Elements()) != 1 {
+		t.Fatalf("unexpected state: %+v", m)
+	}
+	setCognitoState(&m, cognitoInfo{})
+	if !m.CognitoUserPoolId.IsNull() || !m.CognitoUserPoolClientIDs.IsNull() || !m.CognitoIdentitySourceId.IsNull() {
+		t.Fatalf("expected Cognito state to be cleared: %+v", m)
+	}
+}
+
+func TestCognitoFailureState(t *testing.T) {
+	state := authorizerModel{Description: types.StringValue("before")}
+	setCognitoState(&state, cognitoInfo{})
+	if hasCognitoState(&state) {
+		t.Fatalf("empty state should not track Cognito resources")
+	}
+
+	// A creation that failed after the user pool was created.
+	plan := authorizerModel{Description: types.StringValue("after"), Cognito: &CognitoBlock{}}
+	setCognitoState(&plan, cognitoInfo{userPoolId: "pool", userPoolArn: "arn"})
+	failed := cognitoFailureState(state, &plan)
+	if failed.Cognito != nil || failed.Description.ValueString() != "before" {
+		t.Fatalf("prior configuration must be kept so the change is retried: %+v", failed)
+	}
+	if failed.CognitoUserPoolId.ValueString() != "pool" || !failed.CognitoIdentitySourceId.IsNull() || !hasCognitoState(&failed) {
+		t.Fatalf("partially created Cognito resources must be tracked: %+v", failed)
+	}
+}
+
+func TestSignInAliases(t *testing.T) {
+	if got := signInAliases(&CognitoBlock{SignInAliases: types.ListNull(types.StringType)}); len(got) != 1 || got[0] != "email" {
+		t.Fatalf("default sign-in aliases = %v", got)
+	}
+	cfg := &CognitoBlock{SignInAliases: types.ListValueMust(types.StringType, []attr.Value{types.StringValue("phone"), types.StringValue("preferredUsername")})}
+	if got := signInAliases(cfg); len(got) != 2 || got[1] != "preferredUsername" {
+		t.Fatalf("sign-in aliases = %v", got)
+	}
+}
@@ -0,0 +1,283 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+)
+
+// lambdaWaitTimeout bounds how long we wait for the function to become active or finish an update.
+const lambdaWaitTimeout = 5 * time.Minute
+
+// resolveLambdaSettings applies the shared defaults and validation to the lambda block.
+func resolveLambdaSettings(plan *LambdaBlock) (sharedavp.LambdaSettings, error) {
+	if plan == nil {
+		return sharedavp.ResolveLambdaSettings(sharedavp.LambdaOptions{})
+	}
+	return sharedavp.ResolveLambdaSettings(sharedavp.LambdaOptions{
+		MemorySize:             intPointer(plan.MemorySize),
+		ReservedConcurrency:    intPointer(plan.ReservedConcurrency),
+		ProvisionedConcurrency: intPointer(plan.ProvisionedConcurrency),
+		Timeout:                intPointer(plan.Timeout),
+		LogRetentionDays:       intPointer(plan.LogRetentionDays),
+		LogLevel:               plan.LogLevel.ValueStringPointer(),
+		LogKmsKeyArn:           plan.LogKmsKeyArn.ValueStringPointer(),
+		Environment:            lambdaEnvironment(plan),
+	})
+}
+
+// lambdaEnvironment returns the configured environment variables. It returns nil while the map or any
+// value is unknown (e.g. set from another resource's attribute at plan time), which skips validation.
+func lambdaEnvironment(plan *LambdaBlock) map[string]string {
+	if plan.Environment.IsNull() || plan.Environment.IsUnknown() {
+		return nil
+	}
+	out := make(map[string]string, len(plan.Environment.Elements()))
+	for k, v := range plan.Environment.Elements() {
+		s, ok := v.(types.String)
+		if !ok || s.IsUnknown() {
+			return nil
+		}
+		out[k] = s.ValueString()
+	}
+	return out
+}
+
+func intPointer(v types.Int64) *int {
+	if v.IsNull() || v.IsUnknown() {
+		return nil
+	}
+	i := int(v.ValueInt64())
+	return &i
+}
+
+// createLambdaFunction creates the authorizer's log group, then the function and its concurrency settings.
+// mergedJSON is bundled as schema.merged.json when non-empty. It returns the ARN to invoke: the "live"
+// alias ARN when provisioned concurrency is configured, otherwise the function ARN. Once the function
+// exists a non-empty ARN is returned even on error so the caller can track it for cleanup.
+func createLambdaFunction(ctx context.Context, clients *awsClients, fnName string, roleArn string, policyStoreId string, settings sharedavp.LambdaSettings, mergedJSON string) (functionArn string, err error) {
+	zbuf, err := buildLambdaZip(mergedJSON)
+	if err != nil {
+		return "", err
+	}
+	// Create the log group first so Lambda cannot auto-create it without retention or the KMS key.
+	if err := ensureLogGroup(ctx, clients.logs, fnName, settings); err != nil {
+		return "", err
+	}
+	out, err := clients.lambda.CreateFunction(ctx, &lambda.CreateFunctionInput{
+		FunctionName:  &fnName,
+		Role:          &roleArn,
+		Runtime:       lambdatypes.RuntimeNodejs20x,
+		Handler:       awsString("index.handler"),
+		Code:          &lambdatypes.FunctionCode{ZipFile: zbuf},
+		Architectures: []lambdatypes.Architecture{lambdatypes.ArchitectureArm64},
+		Timeout:       awsInt32(int32(settings.Timeout)),
+		MemorySize:    awsInt32(int32(settings.MemorySize)),
+		Publish:       settings.ProvisionedConcurrency > 0,
+		Environment:   &lambdatypes.Environment{Variables: settings.EnvironmentVariables(policyStoreId)},
+		LoggingConfig: loggingConfig(fnName, settings),
+	})
+	if err != nil {
+		return "", err
+	}
+	fnArn := awsStringValue(out.FunctionArn)
+	if err := lambda.NewFunctionActiveV2Waiter(clients.lambda).Wait(ctx, &lambda.GetFunctionInput{FunctionName: &fnName}, lambdaWaitTimeout); err != nil {
+		return fnArn, fmt.Errorf("waiting for function %s to become active: %w", fnName, err)
+	}
+	if err := putReservedConcurrency(ctx, clients.lambda, fnName, settings.ReservedConcurrency); err != nil {
+		return fnArn, err
+	}
+	if settings.ProvisionedConcurrency == 0 {
+		return fnArn, nil
+	}
+	aliasArn, err := syncProvisionedConcurrency(ctx, clients.lambda, fnName, awsStringValue(out.Version), settings.ProvisionedConcurrency)
+	if aliasArn == "" {
+		// The alias was not created; keep the function ARN so the function is still tracked.
+		aliasArn = fnArn
+	}
+	return aliasArn, err
+}
+
+// updateLambda applies lambda block changes in place, and deploys code when it is non-nil (the merged
+// schema changed). Configuration or code changes are followed by a new published version for the alias
+// when provisioned concurrency is enabled; disabling provisioned concurrency removes the alias. It
+// returns the ARN to invoke.
+func updateLambda(ctx context.Context, clients *awsClients, fnName string, policyStoreId string, currentArn string, prev sharedavp.LambdaSettings, next sharedavp.LambdaSettings, code []byte) (string, error) {
+	fnArn := unqualifiedFunctionArn(currentArn)
+	if code != nil {
+		if _, err := clients.lambda.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{FunctionName: &fnName, ZipFile: code}); err != nil {
+			return currentArn, fmt.Errorf("update function code failed for %s: %w", fnName, err)
+		}
+		if err := lambda.NewFunctionUpdatedV2Waiter(clients.lambda).Wait(ctx, &lambda.GetFunctionInput{FunctionName: &fnName}, lambdaWaitTimeout); err != nil {
+			return currentArn, fmt.Errorf("waiting for function %s code update: %w", fnName, err)
+		}
+	}
+	configChanged := prev.MemorySize != next.MemorySize || prev.Timeout != next.Timeout || prev.LogLevel != next.LogLevel ||
+		!maps.Equal(prev.Environment, next.Environment)
+	if configChanged {
+		if _, err := clients.lambda.UpdateFunctionConfiguration(ctx, &lambda.UpdateFunctionConfigurationInput{
+			FunctionName:  &fnName,
+			MemorySize:    awsInt32(int32(next.MemorySize)),
+			Timeout:       awsInt32(int32(next.Timeout)),
+			Environment:   &lambdatypes.Environment{Variables: next.EnvironmentVariables(policyStoreId)},
+			LoggingConfig: loggingConfig(fnName, next),
+		}); err != nil {
+			return currentArn, fmt.Errorf("update function configuration failed for %s: %w", fnName, err)
+		}
+		if err := lambda.NewFunctionUpdatedV2Waiter(clients.lambda).Wait(ctx, &lambda.GetFunctionInput{FunctionName: &fnName}, lambdaWaitTimeout); err != nil {
+			return currentArn, fmt.Errorf("waiting for function %s update: %w", fnName, err)
+		}
+	}
+	if prev.LogRetentionDays != next.LogRetentionDays {
+		if err := ensureLogGroup(ctx, clients.logs, fnName, next); err != nil {
+			return currentArn, err
+		}
+	}
+	if prev.LogKmsKeyArn != next.LogKmsKeyArn {
+		if err := syncLogGroupKmsKey(ctx, clients.logs, fnName, next.LogKmsKeyArn); err != nil {
+			return currentArn, err
+		}
+	}
+	if prev.ReservedConcurrency != next.ReservedConcurrency {
+		if err := putReservedConcurrency(ctx, clients.lambda, fnName, next.ReservedConcurrency); err != nil {
+			return currentArn, err
+		}
+	}
+
+	switch {
+	case next.ProvisionedConcurrency > 0 && (configChanged || code != nil || prev.ProvisionedConcurrency != next.ProvisionedConcurrency):
+		version, err := clients.lambda.PublishVersion(ctx, &lambda.PublishVersionInput{FunctionName: &fnName})
+		if err != nil {
+			return currentArn, fmt.Errorf("publish version failed for %s: %w", fnName, err)
+		}
+		return syncProvisionedConcurrency(ctx, clients.lambda, fnName, awsStringValue(version.Version), next.ProvisionedConcurrency)
+	case next.ProvisionedConcurrency == 0 && prev.ProvisionedConcurrency > 0:
+		if err := deleteLambdaAlias(ctx, clients.lambda, fnName); err != nil {
+			return currentArn, err
+		}
+		return fnArn, nil
+	}
+	return currentArn, nil
+}
+
+// syncProvisionedConcurrency points the "live" alias at version and sets its provisioned concurrency.
+func syncProvisionedConcurrency(ctx context.Context, client *lambda.Client, fnName string, version string, provisioned int) (string, error) {
+	alias := sharedavp.LambdaAliasName
+	var aliasArn string
+	created, err := client.CreateAlias(ctx, &lambda.CreateAliasInput{FunctionName: &fnName, Name: &alias, FunctionVersion: &version})
+	switch {
+	case err == nil:
+		aliasArn = awsStringValue(created.AliasArn)
+	case isResourceConflict(err):
+		updated, err := client.UpdateAlias(ctx, &lambda.UpdateAliasInput{FunctionName: &fnName, Name: &alias, FunctionVersion: &version})
+		if err != nil {
+			return "", fmt.Errorf("update alias failed for %s:%s: %w", fnName, alias, err)
+		}
+		aliasArn = awsStringValue(updated.AliasArn)
+	default:
+		return "", fmt.Errorf("create alias failed for %s:%s: %w", fnName, alias, err)
+	}
+	if _, err := client.PutProvisionedConcurrencyConfig(ctx, &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    &fnName,
+		Qualifier:                       &alias,
+		ProvisionedConcurrentExecutions: awsInt32(int32(provisioned)),
+	}); err != nil {
+		return aliasArn, fmt.Errorf("put provisioned concurrency failed for %s:%s: %w", fnName, alias, err)
+	}
+	return aliasArn, nil
+}
+
+// deleteLambdaAlias removes the "live" alias, which also removes its provisioned concurrency.
+func deleteLambdaAlias(ctx context.Context, client *lambda.Client, fnName string) error {
+	alias := sharedavp.LambdaAliasName
+	if _, err := client.DeleteAlias(ctx, &lambda.DeleteAliasInput{FunctionName: &fnName, Name: &alias}); err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete alias failed for %s:%s: %w", fnName, alias, err)
+	}
+	return nil
+}
+
+func putReservedConcurrency(ctx context.Context, client *lambda.Client, fnName string, reserved int) error {
+	if _, err := client.PutFunctionConcurrency(ctx, &lambda.PutFunctionConcurrencyInput{
+		FunctionName:                 &fnName,
+		ReservedConcurrentExecutions: awsInt32(int32(reserved)),
+	}); err != nil {
+		return fmt.Errorf("put reserved concurrency failed for %s: %w", fnName, err)
+	}
+	return nil
+}
+
+// loggingConfig sends JSON structured logs at the configured application log level to the function's
+// provider-managed log group.
+func loggingConfig(fnName string, settings sharedavp.LambdaSettings) *lambdatypes.LoggingConfig {
+	return &lambdatypes.LoggingConfig{
+		LogGroup:            awsString(sharedavp.LogGroupName(fnName)),
+		LogFormat:           lambdatypes.LogFormat(sharedavp.LambdaLogFormat),
+		ApplicationLogLevel: lambdatypes.ApplicationLogLevel(settings.LogLevel),
+	}
+}
+
+// ensureLogGroup creates the function's log group (encrypted with the configured KMS key) when missing
+// and sets its retention. An existing group, e.g. one retained by a previous stack, is adopted.
+func ensureLogGroup(ctx context.Context, client *cloudwatchlogs.Client, fnName string, settings sharedavp.LambdaSettings) error {
+	name := sharedavp.LogGroupName(fnName)
+	in := &cloudwatchlogs.CreateLogGroupInput{LogGroupName: &name}
+	if settings.LogKmsKeyArn != "" {
+		in.KmsKeyId = &settings.LogKmsKeyArn
+	}
+	_, err := client.CreateLogGroup(ctx, in)
+	if isAlreadyExists(err) {
+		err = syncLogGroupKmsKey(ctx, client, fnName, settings.LogKmsKeyArn)
+	}
+	if err != nil {
+		return fmt.Errorf("create log group failed for %s: %w", name, err)
+	}
+	if _, err := client.PutRetentionPolicy(ctx, &cloudwatchlogs.PutRetentionPolicyInput{
+		LogGroupName:    &name,
+		RetentionInDays: awsInt32(int32(settings.LogRetentionDays)),
+	}); err != nil {
+		return fmt.Errorf("put retention policy failed for %s: %w", name, err)
+	}
+	return nil
+}
+
+// syncLogGroupKmsKey associates the KMS key with the log group, or disassociates it when kmsKeyArn is empty.
+func syncLogGroupKmsKey(ctx context.Context, client *cloudwatchlogs.Client, fnName string, kmsKeyArn string) error {
+	name := sharedavp.LogGroupName(fnName)
+	if kmsKeyArn == "" {
+		if _, err := client.DisassociateKmsKey(ctx, &cloudwatchlogs.DisassociateKmsKeyInput{LogGroupName: &name}); err != nil && !isNotFound(err) {
+			return fmt.Errorf("disassociate KMS key failed for %s: %w", name, err)
+		}
+		return nil
+	}
+	if _, err := client.AssociateKmsKey(ctx, &cloudwatchlogs.AssociateKmsKeyInput{LogGroupName: &name, KmsKeyId: &kmsKeyArn}); err != nil {
+		return fmt.Errorf("associate KMS key failed for %s: %w", name, err)
+	}
+	return nil
+}
+
+func deleteLogGroup(ctx context.Context, client *cloudwatchlogs.Client, fnName string) error {
+	name := sharedavp.LogGroupName(fnName)
+	if _, err := client.DeleteLogGroup(ctx, &cloudwatchlogs.DeleteLogGroupInput{LogGroupName: &name}); err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete log group failed for %s: %w", name, err)
+	}
+	return nil
+}
+
+// unqualifiedFunctionArn strips an alias or version qualifier from a function ARN.
+func unqualifiedFunctionArn(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) > 7 && parts[5] == "function" {
+		return strings.Join(parts[:7], ":")
+	}
+	return arn
+}
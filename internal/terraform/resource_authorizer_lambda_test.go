@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/smithy-go/middleware"
+
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+)
+
+// recordedCalls records the AWS operations made through clients built by stubAPIOptions, in call order.
+type recordedCalls struct {
+	ops    []string
+	inputs map[string]any
+}
+
+// stubAPIOptions short-circuits every operation before it is sent, recording it and returning the
+// canned output for its name.
+func (r *recordedCalls) stubAPIOptions(outputs map[string]any) []func(*middleware.Stack) error {
+	return []func(*middleware.Stack) error{func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("stub", func(ctx context.Context, in middleware.InitializeInput, _ middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			op := awsmiddleware.GetOperationName(ctx)
+			r.ops = append(r.ops, op)
+			r.inputs[op] = in.Parameters
+			out, ok := outputs[op]
+			if !ok {
+				return middleware.InitializeOutput{}, middleware.Metadata{}, fmt.Errorf("unexpected call to %s", op)
+			}
+			return middleware.InitializeOutput{Result: out}, middleware.Metadata{}, nil
+		}), middleware.After)
+	}}
+}
+
+func TestCreateLambdaFunctionCreatesLogGroupFirst(t *testing.T) {
+	calls := &recordedCalls{inputs: map[string]any{}}
+	opts := calls.stubAPIOptions(map[string]any{
+		"CreateLogGroup":         &cloudwatchlogs.CreateLogGroupOutput{},
+		"PutRetentionPolicy":     &cloudwatchlogs.PutRetentionPolicyOutput{},
+		"CreateFunction":         &lambda.CreateFunctionOutput{FunctionArn: aws.String("fn-arn")},
+		"GetFunction":            &lambda.GetFunctionOutput{Configuration: &lambdatypes.FunctionConfiguration{State: lambdatypes.StateActive}},
+		"PutFunctionConcurrency": &lambda.PutFunctionConcurrencyOutput{},
+	})
+	clients := &awsClients{
+		lambda: lambda.New(lambda.Options{Region: "us-east-1", APIOptions: opts}),
+		logs:   cloudwatchlogs.New(cloudwatchlogs.Options{Region: "us-east-1", APIOptions: opts}),
+	}
+	settings, err := resolveLambdaSettings(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnArn, err := createLambdaFunction(context.Background(), clients, "vpa-test-authorizer", "role-arn", "ps", settings, "")
+	if err != nil || fnArn != "fn-arn" {
+		t.Fatalf("createLambdaFunction = %q, %v", fnArn, err)
+	}
+	if i, j := slices.Index(calls.ops, "PutRetentionPolicy"), slices.Index(calls.ops, "CreateFunction"); i < 0 || j < i {
+		t.Fatalf("log group must be created with retention before the function: %v", calls.ops)
+	}
+	in := calls.inputs["CreateFunction"].(*lambda.CreateFunctionInput)
+	if got := aws.ToString(in.LoggingConfig.LogGroup); got != sharedavp.LogGroupName("vpa-test-authorizer") {
+		t.Fatalf("function must log to the managed log group, got %q", got)
+	}
+}
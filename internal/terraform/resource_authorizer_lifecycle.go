@@ -0,0 +1,655 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awscfg "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/verifiedpermissions"
+	vptypes "github.com/aws/aws-sdk-go-v2/service/verifiedpermissions/types"
+	"github.com/aws/smithy-go"
+
+	awserrors "github.com/mikecbrant/verified-permissions-authorizer/internal/awssdk/errors"
+	sharedavp "github.com/mikecbrant/verified-permissions-authorizer/internal/common"
+)
+
+// awsClients bundles the service clients used across the resource lifecycle.
+type awsClients struct {
+	region  string
+	vp      *verifiedpermissions.Client
+	ddb     *dynamodb.Client
+	iam     *iam.Client
+	lambda  *lambda.Client
+	logs    *cloudwatchlogs.Client
+	cognito *cognitoidentityprovider.Client
+}
+
+func newAWSClients(ctx context.Context) (*awsClients, error) {
+	cfg, err := awscfg.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &awsClients{
+		region:  cfg.Region,
+		vp:      verifiedpermissions.NewFromConfig(cfg),
+		ddb:     dynamodb.NewFromConfig(cfg),
+		iam:     iam.NewFromConfig(cfg),
+		lambda:  lambda.NewFromConfig(cfg),
+		logs:    cloudwatchlogs.NewFromConfig(cfg),
+		cognito: cognitoidentityprovider.NewFromConfig(cfg),
+	}, nil
+}
+
+func (r *authorizerResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state authorizerModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	clients, err := newAWSClients(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("AWS config error", err.Error())
+		return
+	}
+
+	store, err := clients.vp.GetPolicyStore(ctx, &verifiedpermissions.GetPolicyStoreInput{PolicyStoreId: awsString(state.PolicyStoreId.ValueString())})
+	if isNotFound(err) {
+		resp.Diagnostics.AddWarning("Policy store not found", fmt.Sprintf("policy store %s no longer exists; removing the authorizer from state", state.PolicyStoreId.ValueString()))
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Read policy store failed", err.Error())
+		return
+	}
+	state.PolicyStoreArn = types.StringValue(awsStringValue(store.Arn))
+	if desc := awsStringValue(store.Description); desc != "" || !state.Description.IsNull() {
+		state.Description = types.StringValue(desc)
+	}
+
+	for _, refresh := range []func(context.Context, *awsClients, *authorizerModel) (string, error){
+		refreshDynamoTable,
+		refreshLambdaRole,
+		refreshLambdaFunction,
+		refreshCognitoUserPool,
+	} {
+		missing, err := refresh(ctx, clients, &state)
+		if err != nil {
+			resp.Diagnostics.AddError("Read authorizer failed", err.Error())
+			return
+		}
+		if missing != "" {
+			resp.Diagnostics.AddWarning("Authorizer drift detected", fmt.Sprintf("%s was deleted outside of Terraform; the authorizer will be replaced on the next apply to recreate it", missing))
+		}
+	}
+
+	nullUnknownComputed(&state)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// refreshDynamoTable updates table-derived attributes; it returns a description of the table when it no longer exists.
+func refreshDynamoTable(ctx context.Context, clients *awsClients, state *authorizerModel) (string, error) {
+	name := stateTableName(state)
+	if name == "" {
+		return "", nil
+	}
+	table, err := describeDynamoTable(ctx, clients.ddb, name)
+	if isNotFound(err) {
+		state.DynamoTableArn = types.StringNull()
+		state.DynamoStreamArn = types.StringNull()
+		return fmt.Sprintf("DynamoDB table %s", name), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	state.DynamoTableName = types.StringValue(table.name)
+	state.DynamoTableArn = types.StringValue(table.arn)
+	state.DynamoStreamArn = stringValueOrNull(table.streamArn)
+	if state.Dynamo != nil && !state.Dynamo.EnableDynamoDbStream.IsNull() {
+		state.Dynamo.EnableDynamoDbStream = types.BoolValue(table.streamArn != "")
+	}
+	return "", nil
+}
+
+// refreshLambdaRole updates the role ARN; it returns a description of the role when it no longer exists.
+func refreshLambdaRole(ctx context.Context, clients *awsClients, state *authorizerModel) (string, error) {
+	name := stateRoleName(state)
+	if name == "" {
+		return "", nil
+	}
+	out, err := clients.iam.GetRole(ctx, &iam.GetRoleInput{RoleName: &name})
+	if isNotFound(err) {
+		state.LambdaRoleArn = types.StringNull()
+		return fmt.Sprintf("IAM role %s", name), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get role failed for %s: %w", name, err)
+	}
+	state.LambdaRoleName = types.StringValue(name)
+	state.LambdaRoleArn = types.StringValue(awsStringValue(out.Role.Arn))
+	return "", nil
+}
+
+// refreshLambdaFunction updates function-derived attributes; it returns a description of the function when it no longer exists.
+func refreshLambdaFunction(ctx context.Context, clients *awsClients, state *authorizerModel) (string, error) {
+	name := stateFunctionName(state)
+	if name == "" {
+		return "", nil
+	}
+	out, err := clients.lambda.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: &name})
+	if isNotFound(err) {
+		state.LambdaAuthorizerArn = types.StringNull()
+		return fmt.Sprintf("Lambda function %s", name), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get function failed for %s: %w", name, err)
+	}
+	if out.Configuration == nil {
+		return "", nil
+	}
+	state.LambdaFunctionName = types.StringValue(name)
+	// Keep the alias qualifier when provisioned concurrency routes invocations through the alias.
+	fnArn := awsStringValue(out.Configuration.FunctionArn)
+	if strings.HasSuffix(state.LambdaAuthorizerArn.ValueString(), ":"+sharedavp.LambdaAliasName) {
+		fnArn += ":" + sharedavp.LambdaAliasName
+	}
+	state.LambdaAuthorizerArn = types.StringValue(fnArn)
+	if state.Lambda != nil && !state.Lambda.MemorySize.IsNull() && out.Configuration.MemorySize != nil {
+		state.Lambda.MemorySize = types.Int64Value(int64(*out.Configuration.MemorySize))
+	}
+	if state.Lambda != nil && !state.Lambda.Timeout.IsNull() && out.Configuration.Timeout != nil {
+		state.Lambda.Timeout = types.Int64Value(int64(*out.Configuration.Timeout))
+	}
+	return "", nil
+}
+
+// ModifyPlan plans merged_schema_hash from the schema file on disk. Terraform cannot see edits to the
+// file in configuration, so the hash is what plans an update that redeploys the bundled schema.
+func (r *authorizerResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan authorizerModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !req.State.Raw.IsNull() {
+		var state authorizerModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// Owned children deleted outside Terraform cannot be recreated in place; replace the authorizer.
+		for _, attr := range missingChildArns(&state) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(attr), types.StringUnknown())...)
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root(attr))
+		}
+	}
+	if vp := plan.VerifiedPermissions; vp != nil && (vp.SchemaFile.IsUnknown() || vp.PolicyDir.IsUnknown()) {
+		return
+	}
+	vpSchema, err := loadSchema(plan.VerifiedPermissions)
+	if err != nil {
+		resp.Diagnostics.AddError("Verified permissions config failed", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("merged_schema_hash"), mergedSchemaHash(vpSchema))...)
+}
+
+func (r *authorizerResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state authorizerModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	settings, err := resolveLambdaSettings(plan.Lambda)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid lambda settings", err.Error())
+		return
+	}
+	if err := validateAdditionalPolicyStatements(plan.Lambda); err != nil {
+		resp.Diagnostics.AddError("Invalid lambda additional_policy_statements", err.Error())
+		return
+	}
+	vpSchema, err := loadSchema(plan.VerifiedPermissions)
+	if err != nil {
+		resp.Diagnostics.AddError("Verified permissions config failed", err.Error())
+		return
+	}
+	clients, err := newAWSClients(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("AWS config error", err.Error())
+		return
+	}
+	carryComputed(&plan, &state)
+	psId := state.PolicyStoreId.ValueString()
+
+	if !plan.Description.Equal(state.Description) {
+		if err := updatePolicyStore(ctx, clients.vp, psId, plan.Description.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Update policy store failed", err.Error())
+			return
+		}
+	}
+
+	before, after := resolveTableSettings(&state), resolveTableSettings(&plan)
+	if before != after {
+		table, err := updateDynamoTable(ctx, clients.ddb, stateTableName(&state), before, after)
+		if err != nil {
+			resp.Diagnostics.AddError("Update DynamoDB table failed", err.Error())
+			return
+		}
+		plan.DynamoTableArn = types.StringValue(table.arn)
+		plan.DynamoStreamArn = stringValueOrNull(table.streamArn)
+	}
+
+	// The inline policy is rewritten on every update so additional statement changes (and stacks created
+	// before the policy existed) converge.
+	if name := stateRoleName(&state); name != "" {
+		if err := putLambdaRolePolicy(ctx, clients.iam, name, plan.PolicyStoreArn.ValueString(), plan.DynamoTableArn.ValueString(), plan.Lambda); err != nil {
+			resp.Diagnostics.AddError("Put IAM role policy failed", err.Error())
+			return
+		}
+	}
+
+	if name := stateFunctionName(&state); name != "" {
+		// Settings in state were validated when applied; fall back to defaults if that ever changes.
+		prev, err := resolveLambdaSettings(state.Lambda)
+		if err != nil {
+			prev, _ = resolveLambdaSettings(nil)
+		}
+		// Redeploy the code when the bundled merged schema changed.
+		var code []byte
+		if hash := mergedSchemaHash(vpSchema); !hash.Equal(state.MergedSchemaHash) {
+			if code, err = buildLambdaZip(mergedSchemaJSON(vpSchema)); err != nil {
+				resp.Diagnostics.AddError("Build Lambda code failed", err.Error())
+				return
+			}
+		}
+		fnArn, err := updateLambda(ctx, clients, name, psId, state.LambdaAuthorizerArn.ValueString(), prev, settings, code)
+		plan.LambdaAuthorizerArn = stringValueOrNull(fnArn)
+		if err != nil {
+			resp.Diagnostics.AddError("Update Lambda failed", err.Error())
+			return
+		}
+	}
+
+	if plan.Cognito != nil || hasCognitoState(&state) {
+		if err := updateCognito(ctx, clients, namesForPrefix(plan.NamePrefix.ValueString()), &plan, &state, schemaNamespace(vpSchema)); err != nil {
+			resp.Diagnostics.AddError("Update Cognito failed", err.Error())
+			failed := cognitoFailureState(state, &plan)
+			resp.Diagnostics.Append(resp.State.Set(ctx, &failed)...)
+			return
+		}
+	}
+
+	var tracked managedPolicies
+	var diags diag.Diagnostics
+	tracked.policies, diags = trackedPolicyIDs(ctx, state.PolicyIDs)
+	resp.Diagnostics.Append(diags...)
+	tracked.guardrails, diags = trackedPolicyIDs(ctx, state.GuardrailPolicyIDs)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	managed, warns, err := applyVerifiedPermissions(ctx, clients, psId, plan.VerifiedPermissions, vpSchema, tracked)
+	if err != nil {
+		addAWSError(&resp.Diagnostics, "Verified permissions config failed", err)
+		return
+	}
+	for _, w := range warns {
+		resp.Diagnostics.AddWarning("AVP", w)
+	}
+	plan.PolicyIDs = policyIDsValue(managed.policies)
+	plan.GuardrailPolicyIDs = policyIDsValue(managed.guardrails)
+	plan.MergedSchemaHash = mergedSchemaHash(vpSchema)
+
+	nullUnknownComputed(&plan)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+
+	if plan.VerifiedPermissions != nil && !resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(runCanaries(ctx, clients, psId, plan.VerifiedPermissions)...)
+	}
+}
+
+func updatePolicyStore(ctx context.Context, client *verifiedpermissions.Client, policyStoreId string, description string) error {
+	_, err := client.UpdatePolicyStore(ctx, &verifiedpermissions.UpdatePolicyStoreInput{
+		PolicyStoreId:      &policyStoreId,
+		ValidationSettings: &vptypes.ValidationSettings{Mode: vptypes.ValidationModeStrict},
+		Description:        awsString(description),
+	})
+	return err
+}
+
+func updateDynamoTable(ctx context.Context, client *dynamodb.Client, tableName string, before tableSettings, after tableSettings) (tableInfo, error) {
+	if before.enableStream != after.enableStream {
+		spec := &dynamodbtypes.StreamSpecification{StreamEnabled: awsBool(after.enableStream)}
+		if after.enableStream {
+			spec.StreamViewType = dynamodbtypes.StreamViewTypeNewAndOldImages
+		}
+		if _, err := client.UpdateTable(ctx, &dynamodb.UpdateTableInput{TableName: &tableName, StreamSpecification: spec}); err != nil {
+			return tableInfo{}, fmt.Errorf("update stream settings failed for %s: %w", tableName, err)
+		}
+		if err := dynamodb.NewTableExistsWaiter(client).Wait(ctx, &dynamodb.DescribeTableInput{TableName: &tableName}, tableWaitTimeout); err != nil {
+			return tableInfo{}, fmt.Errorf("waiting for table %s to become active: %w", tableName, err)
+		}
+	}
+	if before.retainOnDelete != after.retainOnDelete {
+		if _, err := client.UpdateTable(ctx, &dynamodb.UpdateTableInput{TableName: &tableName, DeletionProtectionEnabled: awsBool(after.retainOnDelete)}); err != nil {
+			return tableInfo{}, fmt.Errorf("update deletion protection failed for %s: %w", tableName, err)
+		}
+		if err := setPointInTimeRecovery(ctx, client, tableName, after.retainOnDelete); err != nil {
+			return tableInfo{}, err
+		}
+	}
+	return describeDynamoTable(ctx, client, tableName)
+}
+
+func (r *authorizerResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state authorizerModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	clients, err := newAWSClients(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("AWS config error", err.Error())
+		return
+	}
+
+	// Tear down in reverse creation order: Cognito, Lambda, role, table, policy store.
+	// Cognito resources are never retained, matching the Pulumi component.
+	if state.Cognito != nil || !state.CognitoUserPoolId.IsNull() {
+		if err := deleteCognito(ctx, clients, namesForPrefix(state.NamePrefix.ValueString()), &state); err != nil {
+			resp.Diagnostics.AddError("Delete Cognito failed", err.Error())
+			return
+		}
+	}
+	if name := stateFunctionName(&state); name != "" {
+		if err := deleteLambdaFunction(ctx, clients.lambda, name); err != nil {
+			resp.Diagnostics.AddError("Delete Lambda failed", err.Error())
+			return
+		}
+		if !state.RetainOnDelete.ValueBool() {
+			if err := deleteLogGroup(ctx, clients.logs, name); err != nil {
+				resp.Diagnostics.AddError("Delete log group failed", err.Error())
+				return
+			}
+		}
+	}
+	if name := stateRoleName(&state); name != "" {
+		if err := deleteLambdaRole(ctx, clients.iam, name); err != nil {
+			resp.Diagnostics.AddError("Delete IAM role failed", err.Error())
+			return
+		}
+	}
+
+	// Mirrors the Pulumi component: the table, policy store and log group are the retained resources.
+	if state.RetainOnDelete.ValueBool() {
+		resp.Diagnostics.AddWarning("Resources retained", "retain_on_delete is true; the DynamoDB table, policy store and Lambda log group were left in place")
+		return
+	}
+	if name := stateTableName(&state); name != "" {
+		if _, err := clients.ddb.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: &name}); err != nil && !isNotFound(err) {
+			resp.Diagnostics.AddError("Delete DynamoDB table failed", err.Error())
+			return
+		}
+	}
+	if psId := state.PolicyStoreId.ValueString(); psId != "" {
+		if _, err := clients.vp.DeletePolicyStore(ctx, &verifiedpermissions.DeletePolicyStoreInput{PolicyStoreId: &psId}); err != nil && !isNotFound(err) {
+			resp.Diagnostics.AddError("Delete policy store failed", err.Error())
+			return
+		}
+	}
+}
+
+func deleteLambdaFunction(ctx context.Context, client *lambda.Client, name string) error {
+	if _, err := client.DeleteFunction(ctx, &lambda.DeleteFunctionInput{FunctionName: &name}); err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete function failed for %s: %w", name, err)
+	}
+	return nil
+}
+
+// deleteLambdaRole detaches managed policies and removes inline policies before deleting the role.
+func deleteLambdaRole(ctx context.Context, client *iam.Client, name string) error {
+	attached, err := client.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{RoleName: &name})
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("list attached role policies failed for %s: %w", name, err)
+	}
+	for _, p := range attached.AttachedPolicies {
+		if _, err := client.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{RoleName: &name, PolicyArn: p.PolicyArn}); err != nil && !isNotFound(err) {
+			return fmt.Errorf("detach role policy failed (policy=%s role=%s): %w", awsStringValue(p.PolicyArn), name, err)
+		}
+	}
+	inline, err := client.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{RoleName: &name})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("list role policies failed for %s: %w", name, err)
+	}
+	if inline != nil {
+		for _, p := range inline.PolicyNames {
+			policyName := p
+			if _, err := client.DeleteRolePolicy(ctx, &iam.DeleteRolePolicyInput{RoleName: &name, PolicyName: &policyName}); err != nil && !isNotFound(err) {
+				return fmt.Errorf("delete role policy failed (policy=%s role=%s): %w", policyName, name, err)
+			}
+		}
+	}
+	if _, err := client.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: &name}); err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete role failed for %s: %w", name, err)
+	}
+	return nil
+}
+
+// ImportState adopts an existing stack from a composite ID: <policy_store_id>/<name_prefix>.
+// Child resource names are derived from the prefix; Read then refreshes every other attribute.
+func (r *authorizerResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	id, err := parseImportID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid import ID", err.Error())
+		return
+	}
+	names := namesForPrefix(id.namePrefix)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), types.StringValue(id.policyStoreId))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("policy_store_id"), types.StringValue(id.policyStoreId))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name_prefix"), types.StringValue(names.prefix))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("dynamo_table_name"), types.StringValue(names.table))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("lambda_role_name"), types.StringValue(names.role))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("lambda_function_name"), types.StringValue(names.function))...)
+}
+
+// importID is the parsed form of the composite import identifier.
+type importID struct {
+	policyStoreId string
+	namePrefix    string
+}
+
+func parseImportID(raw string) (importID, error) {
+	parts := strings.Split(strings.TrimSpace(raw), "/")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return importID{}, fmt.Errorf("expected <policy_store_id>/<name_prefix>, got %q", raw)
+	}
+	if err := validateNamePrefix(parts[1]); err != nil {
+		return importID{}, err
+	}
+	return importID{policyStoreId: parts[0], namePrefix: parts[1]}, nil
+}
+
+// carryComputed copies computed attributes that are unknown in the plan from prior state.
+func carryComputed(plan *authorizerModel, state *authorizerModel) {
+	plan.ID = state.ID
+	plan.PolicyStoreId = state.PolicyStoreId
+	plan.PolicyStoreArn = state.PolicyStoreArn
+	plan.LambdaAuthorizerArn = state.LambdaAuthorizerArn
+	plan.LambdaRoleArn = state.LambdaRoleArn
+	plan.DynamoTableArn = state.DynamoTableArn
+	plan.DynamoStreamArn = state.DynamoStreamArn
+	plan.Parameters = state.Parameters
+	plan.CognitoUserPoolId = state.CognitoUserPoolId
+	plan.CognitoUserPoolArn = state.CognitoUserPoolArn
+	plan.CognitoUserPoolClientIDs = state.CognitoUserPoolClientIDs
+	plan.CognitoIdentitySourceId = state.CognitoIdentitySourceId
+	plan.NamePrefix = state.NamePrefix
+	plan.DynamoTableName = state.DynamoTableName
+	plan.LambdaRoleName = state.LambdaRoleName
+	plan.LambdaFunctionName = state.LambdaFunctionName
+	plan.PolicyIDs = state.PolicyIDs
+	plan.GuardrailPolicyIDs = state.GuardrailPolicyIDs
+}
+
+// nullUnknownComputed replaces unknown computed values with nulls so state is always fully known.
+func nullUnknownComputed(m *authorizerModel) {
+	for _, s := range []*types.String{
+		&m.PolicyStoreArn, &m.LambdaAuthorizerArn, &m.LambdaRoleArn, &m.DynamoTableArn,
+		&m.DynamoStreamArn, &m.CognitoUserPoolId, &m.CognitoUserPoolArn, &m.CognitoIdentitySourceId,
+		&m.NamePrefix, &m.DynamoTableName, &m.LambdaRoleName, &m.LambdaFunctionName, &m.MergedSchemaHash,
+	} {
+		if s.IsUnknown() {
+			*s = types.StringNull()
+		}
+	}
+	if m.Parameters.IsUnknown() {
+		m.Parameters = types.MapNull(types.StringType)
+	}
+	if m.CognitoUserPoolClientIDs.IsUnknown() {
+		m.CognitoUserPoolClientIDs = types.ListNull(types.StringType)
+	}
+	if m.PolicyIDs.IsUnknown() {
+		m.PolicyIDs = types.MapNull(types.StringType)
+	}
+	if m.GuardrailPolicyIDs.IsUnknown() {
+		m.GuardrailPolicyIDs = types.MapNull(types.StringType)
+	}
+}
+
+// stateTableName returns the owned table name, falling back to the ARN for state written
+// before child names were tracked.
+func stateTableName(m *authorizerModel) string {
+	if n := m.DynamoTableName.ValueString(); n != "" {
+		return n
+	}
+	return tableNameFromArn(m.DynamoTableArn.ValueString())
+}
+
+// missingChildArns returns the ARN attributes Read cleared because the owned child they identify was
+// deleted outside Terraform while its name or ID is still recorded.
+func missingChildArns(m *authorizerModel) []string {
+	var out []string
+	if m.DynamoTableName.ValueString() != "" && m.DynamoTableArn.IsNull() {
+		out = append(out, "dynamo_table_arn")
+	}
+	if m.LambdaRoleName.ValueString() != "" && m.LambdaRoleArn.IsNull() {
+		out = append(out, "lambda_role_arn")
+	}
+	if m.LambdaFunctionName.ValueString() != "" && m.LambdaAuthorizerArn.IsNull() {
+		out = append(out, "lambda_authorizer_arn")
+	}
+	if m.CognitoUserPoolId.ValueString() != "" && m.CognitoUserPoolArn.IsNull() {
+		out = append(out, "cognito_user_pool_arn")
+	}
+	return out
+}
+
+// stateRoleName returns the owned role name, falling back to the ARN.
+func stateRoleName(m *authorizerModel) string {
+	if n := m.LambdaRoleName.ValueString(); n != "" {
+		return n
+	}
+	return roleNameFromArn(m.LambdaRoleArn.ValueString())
+}
+
+// stateFunctionName returns the owned function name, falling back to the ARN.
+func stateFunctionName(m *authorizerModel) string {
+	if n := m.LambdaFunctionName.ValueString(); n != "" {
+		return n
+	}
+	return functionNameFromArn(m.LambdaAuthorizerArn.ValueString())
+}
+
+func stringValueOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(s)
+}
+
+// isNotFound reports whether err is an AWS "resource does not exist" error.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	var missing *awserrors.NotFoundError
+	return errors.As(awserrors.Classify(err), &missing)
+}
+
+// addAWSError adds an error diagnostic for a failed AWS call. The detail leads with the error
+// category (validation, quota, access denied, ...) so users can tell what to fix.
+func addAWSError(diags *diag.Diagnostics, summary string, err error) {
+	category, detail := awserrors.Describe(err)
+	diags.AddError(summary, category+": "+detail)
+}
+
+func isAlreadyExists(err error) bool {
+	var api smithy.APIError
+	if !errors.As(err, &api) {
+		return false
+	}
+	switch api.ErrorCode() {
+	case "AlreadyExistsException", "ResourceAlreadyExistsException":
+		return true
+	}
+	return false
+}
+
+// isResourceConflict matches Lambda's ResourceConflictException (e.g., an alias that already exists).
+func isResourceConflict(err error) bool {
+	var api smithy.APIError
+	return errors.As(err, &api) && api.ErrorCode() == "ResourceConflictException"
+}
+
+// functionNameFromArn extracts the function name from arn:aws:lambda:<region>:<account>:function:<name>[:qualifier].
+func functionNameFromArn(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 7 || parts[5] != "function" {
+		return ""
+	}
+	return parts[6]
+}
+
+// roleNameFromArn extracts the role name from arn:aws:iam::<account>:role/<path/><name>.
+func roleNameFromArn(arn string) string {
+	i := strings.Index(arn, ":role/")
+	if i < 0 {
+		return ""
+	}
+	rest := arn[i+len(":role/"):]
+	return rest[strings.LastIndex(rest, "/")+1:]
+}
+
+// tableNameFromArn extracts the table name from arn:aws:dynamodb:<region>:<account>:table/<name>.
+func tableNameFromArn(arn string) string {
+	i := strings.Index(arn, ":table/")
+	if i < 0 {
+		return ""
+	}
+	rest := arn[i+len(":table/"):]
+	if j := strings.Index(rest, "/"); j >= 0 {
+		rest = rest[:j]
+	}
+	return rest
+}
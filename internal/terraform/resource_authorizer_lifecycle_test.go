@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestParseImportID(t *testing.T) {
+	id, err := parseImportID("ps-123/vpa-1a2b3c4d")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id.policyStoreId != "ps-123" || id.namePrefix != "vpa-1a2b3c4d" {
+		t.Fatalf("unexpected parsed id: %+v", id)
+	}
+	for _, bad := range []string{"", "ps-123", "ps-123/", "/vpa", "ps-123/vpa/extra", "ps-123/bad prefix"} {
+		if _, err := parseImportID(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestNamesFromArns(t *testing.T) {
+	if got := functionNameFromArn("arn:aws:lambda:us-east-1:123456789012:function:vpa-authorizer-1"); got != "vpa-authorizer-1" {
+		t.Fatalf("functionNameFromArn = %q", got)
+	}
+	if got := functionNameFromArn("arn:aws:lambda:us-east-1:123456789012:function:fn:live"); got != "fn" {
+		t.Fatalf("functionNameFromArn (qualified) = %q", got)
+	}
+	if got := roleNameFromArn("arn:aws:iam::123456789012:role/service/vpa-role-1"); got != "vpa-role-1" {
+		t.Fatalf("roleNameFromArn = %q", got)
+	}
+	if got := tableNameFromArn("arn:aws:dynamodb:us-east-1:123456789012:table/vpa-tenant-1"); got != "vpa-tenant-1" {
+		t.Fatalf("tableNameFromArn = %q", got)
+	}
+	if functionNameFromArn("") != "" || roleNameFromArn("") != "" || tableNameFromArn("") != "" {
+		t.Fatalf("expected empty names for empty ARNs")
+	}
+}
+
+func TestUnqualifiedFunctionArn(t *testing.T) {
+	if got := unqualifiedFunctionArn("arn:aws:lambda:us-east-1:123456789012:function:fn:live"); got != "arn:aws:lambda:us-east-1:123456789012:function:fn" {
+		t.Fatalf("unqualifiedFunctionArn = %q", got)
+	}
+	if got := unqualifiedFunctionArn("arn:aws:lambda:us-east-1:123456789012:function:fn"); got != "arn:aws:lambda:us-east-1:123456789012:function:fn" {
+		t.Fatalf("unqualifiedFunctionArn (unqualified) = %q", got)
+	}
+}
+
+func TestMissingChildArns(t *testing.T) {
+	m := authorizerModel{
+		DynamoTableName:     types.StringValue("vpa-tenant-1"),
+		DynamoTableArn:      types.StringValue("arn:aws:dynamodb:us-east-1:123456789012:table/vpa-tenant-1"),
+		LambdaRoleName:      types.StringValue("vpa-role-1"),
+		LambdaRoleArn:       types.StringNull(),
+		LambdaFunctionName:  types.StringValue("vpa-authorizer-1"),
+		LambdaAuthorizerArn: types.StringNull(),
+		CognitoUserPoolId:   types.StringNull(),
+		CognitoUserPoolArn:  types.StringNull(),
+	}
+	if got, want := missingChildArns(&m), []string{"lambda_role_arn", "lambda_authorizer_arn"}; !slices.Equal(got, want) {
+		t.Fatalf("missingChildArns = %v, want %v", got, want)
+	}
+	m.CognitoUserPoolId = types.StringValue("pool")
+	if got := missingChildArns(&m); !slices.Contains(got, "cognito_user_pool_arn") {
+		t.Fatalf("missing user pool not reported: %v", got)
+	}
+}
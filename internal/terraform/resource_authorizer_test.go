@@ -1,11 +1,20 @@
 package provider
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
 	"os"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	tftest "github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
@@ -34,3 +43,141 @@ resource "vpauthorizer_authorizer" "test" {
 		}},
 	})
 }
+
+func TestBuildLambdaZip(t *testing.T) {
+	merged := `{"app":{"entityTypes":{}}}`
+	a, err := buildLambdaZip(merged)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := buildLambdaZip(merged)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("archive is not deterministic")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(a), int64(len(a)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "index.mjs" || zr.File[1].Name != "schema.merged.json" {
+		t.Fatalf("unexpected archive entries: %v", zr.File)
+	}
+	rc, _ := zr.File[1].Open()
+	got, _ := io.ReadAll(rc)
+	if string(got) != merged {
+		t.Fatalf("schema.merged.json = %q", got)
+	}
+	withoutSchema, _ := buildLambdaZip("")
+	if zr, _ := zip.NewReader(bytes.NewReader(withoutSchema), int64(len(withoutSchema))); len(zr.File) != 1 {
+		t.Fatalf("expected only index.mjs without a schema")
+	}
+}
+
+func TestSetCreatedState(t *testing.T) {
+	names := namesForPrefix("vpa-test")
+	var m authorizerModel
+
+	// The role was created but the function was not.
+	c := createdResources{policyStoreId: "ps", policyStoreArn: "ps-arn", table: tableInfo{name: names.table, arn: "table-arn"}, roleArn: "role-arn"}
+	setCreatedState(&m, names, c)
+	if m.PolicyStoreId.ValueString() != "ps" || m.DynamoTableName.ValueString() != names.table || m.LambdaRoleName.ValueString() != names.role {
+		t.Fatalf("created children must be tracked: %+v", m)
+	}
+	if !m.LambdaFunctionName.IsNull() || !m.LambdaAuthorizerArn.IsNull() || !m.CognitoUserPoolId.IsNull() {
+		t.Fatalf("children that were not created must be null: %+v", m)
+	}
+	if stateFunctionName(&m) != "" || stateRoleName(&m) != names.role {
+		t.Fatalf("Delete must clean up exactly the created children: %+v", m)
+	}
+
+	// The function exists even though a later step of its creation failed.
+	c.fnArn = "fn-arn"
+	setCreatedState(&m, names, c)
+	if m.LambdaFunctionName.ValueString() != names.function || m.LambdaAuthorizerArn.ValueString() != "fn-arn" {
+		t.Fatalf("created function must be tracked: %+v", m)
+	}
+}
+
+func TestResolveLambdaSettingsEnvironment(t *testing.T) {
+	env := func(v types.String) *LambdaBlock {
+		return &LambdaBlock{Environment: types.MapValueMust(types.StringType, map[string]attr.Value{"POLICY_STORE_ID": v})}
+	}
+	if _, err := resolveLambdaSettings(env(types.StringValue("x"))); err == nil {
+		t.Fatalf("expected reserved environment variable to be rejected")
+	}
+	// Values from other resources are unknown at plan time; validation waits until they are known.
+	for _, cfg := range []*LambdaBlock{env(types.StringUnknown()), {Environment: types.MapUnknown(types.StringType)}} {
+		s, err := resolveLambdaSettings(cfg)
+		if err != nil || s.Environment != nil {
+			t.Fatalf("unknown environment: settings=%+v err=%v", s, err)
+		}
+	}
+
+	cfg := &LambdaBlock{Environment: types.MapValueMust(types.StringType, map[string]attr.Value{"TABLE": types.StringValue("t")})}
+	if s, err := resolveLambdaSettings(cfg); err != nil || s.Environment["TABLE"] != "t" {
+		t.Fatalf("environment: settings=%+v err=%v", s, err)
+	}
+}
+
+// policyStatementAttrTypes is the object type of an additional_policy_statements element.
+var policyStatementAttrTypes = map[string]attr.Type{
+	"effect":    types.StringType,
+	"actions":   types.ListType{ElemType: types.StringType},
+	"resources": types.ListType{ElemType: types.StringType},
+}
+
+func TestAdditionalPolicyStatements(t *testing.T) {
+	strs := func(vs ...attr.Value) types.List { return types.ListValueMust(types.StringType, vs) }
+	block := func(effect types.String, resources types.List) *LambdaBlock {
+		stmt := types.ObjectValueMust(policyStatementAttrTypes, map[string]attr.Value{
+			"effect": effect, "actions": strs(types.StringValue("s3:GetObject")), "resources": resources,
+		})
+		return &LambdaBlock{AdditionalPolicyStatements: types.ListValueMust(types.ObjectType{AttrTypes: policyStatementAttrTypes}, []attr.Value{stmt})}
+	}
+
+	stmts, known := additionalPolicyStatements(block(types.StringNull(), strs(types.StringValue("arn:aws:s3:::b/*"))))
+	if !known || len(stmts) != 1 || stmts[0].Action[0] != "s3:GetObject" || stmts[0].Resource[0] != "arn:aws:s3:::b/*" {
+		t.Fatalf("statements = %+v known=%v", stmts, known)
+	}
+	if err := validateAdditionalPolicyStatements(block(types.StringValue("Maybe"), strs(types.StringValue("*")))); err == nil {
+		t.Fatalf("expected invalid effect to be rejected")
+	}
+
+	// Resource ARNs from other resources are unknown at plan time; validation waits until they are known.
+	for _, cfg := range []*LambdaBlock{
+		block(types.StringValue("Maybe"), strs(types.StringUnknown())),
+		block(types.StringValue("Maybe"), types.ListUnknown(types.StringType)),
+		{AdditionalPolicyStatements: types.ListUnknown(types.ObjectType{AttrTypes: policyStatementAttrTypes})},
+	} {
+		if _, known := additionalPolicyStatements(cfg); known {
+			t.Fatalf("expected unknown statements for %+v", cfg)
+		}
+		if err := validateAdditionalPolicyStatements(cfg); err != nil {
+			t.Fatalf("unknown statements must not be validated: %v", err)
+		}
+	}
+}
+
+func TestPlanDecodesUnknownLambdaValues(t *testing.T) {
+	ctx := context.Background()
+	var schemaResp resource.SchemaResponse
+	(&authorizerResource{}).Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+	diags := plan.Set(ctx, &authorizerModel{
+		Parameters:               types.MapNull(types.StringType),
+		PolicyIDs:                types.MapNull(types.StringType),
+		GuardrailPolicyIDs:       types.MapNull(types.StringType),
+		CognitoUserPoolClientIDs: types.ListNull(types.StringType),
+		Lambda: &LambdaBlock{
+			Environment:                types.MapUnknown(types.StringType),
+			AdditionalPolicyStatements: types.ListUnknown(types.ObjectType{AttrTypes: policyStatementAttrTypes}),
+		},
+	})
+	var m authorizerModel
+	diags.Append(plan.Get(ctx, &m)...)
+	if diags.HasError() {
+		t.Fatalf("unknown lambda values must decode: %v", diags)
+	}
+	if !m.Lambda.Environment.IsUnknown() || !m.Lambda.AdditionalPolicyStatements.IsUnknown() {
+		t.Fatalf("unexpected lambda block: %+v", m.Lambda)
+	}
+}
@@ -60,6 +60,9 @@ class AuthorizerWithPolicyStore extends pulumi.ComponentResource {
         }
         return o.roleArn as string;
       }),
+      mergedSchemaHash: lambda.apply(
+        (o) => o?.mergedSchemaHash as string | undefined,
+      ),
     };
 
     // dynamo group
@@ -45,6 +45,7 @@ export type AuthorizerWithPolicyStoreArgs = {
 export type AuthorizerLambdaOutputs = {
   authorizerFunctionArn: pulumi.Output<string>;
   roleArn: pulumi.Output<string>;
+  mergedSchemaHash: pulumi.Output<string | undefined>;
 };
 
 export type AuthorizerDynamoOutputs = {